@@ -56,6 +56,7 @@ func main() {
 		"db-user": func(ctx context.Context) error {
 			return db.Close()
 		},
+		"background-jobs": bootstrap.Stop,
 	})
 
 	<-wait
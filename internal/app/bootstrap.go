@@ -1,15 +1,27 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	httpHandler "github.com/federicodosantos/socialize/internal/delivery/http"
 	"github.com/federicodosantos/socialize/internal/middleware"
 	"github.com/federicodosantos/socialize/internal/repository"
 	"github.com/federicodosantos/socialize/internal/usecase"
 	"github.com/federicodosantos/socialize/pkg/jwt"
+	"github.com/federicodosantos/socialize/pkg/mailer"
+	"github.com/federicodosantos/socialize/pkg/oidc"
+	"github.com/federicodosantos/socialize/pkg/password"
+	"github.com/federicodosantos/socialize/pkg/ranking"
+	"github.com/federicodosantos/socialize/pkg/reaction"
+	"github.com/federicodosantos/socialize/pkg/realtime"
 	"github.com/federicodosantos/socialize/pkg/supabase"
+	"github.com/federicodosantos/socialize/pkg/token"
 	"github.com/federicodosantos/socialize/pkg/util"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -23,23 +35,197 @@ type Bootstrap struct {
 	db     *sqlx.DB
 	router *chi.Mux
 	logger *zap.SugaredLogger
+
+	// ctx is cancelled on shutdown to stop background jobs
+	ctx    context.Context
+	cancel context.CancelFunc
+	// closers release the resources opened by InitApp on shutdown
+	closers []func() error
 }
 
 func NewBootstrap(db *sqlx.DB, router *chi.Mux, logger *zap.SugaredLogger) *Bootstrap {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Bootstrap{
 		db:     db,
 		router: router,
 		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
 func (b *Bootstrap) InitApp() {
 	// initialize jwt service
-	jwtService, err := jwt.NewJwt(os.Getenv("JWT_SECRET_KEY"), os.Getenv("JWT_EXPIRED"))
+	jwtKeys, err := jwt.NewKeySetFromConfig(jwt.KeyConfig{
+		Algorithm:   os.Getenv("JWT_ALGORITHM"),
+		SecretKey:   os.Getenv("JWT_SECRET_KEY"),
+		PrivateKeys: os.Getenv("JWT_PRIVATE_KEYS"),
+	})
+	if err != nil {
+		log.Fatalf("cannot load jwt keys due to %s", err.Error())
+	}
+
+	jwtService, err := jwt.NewJwt(jwtKeys, os.Getenv("JWT_EXPIRED"),
+		os.Getenv("JWT_ISSUER"), os.Getenv("JWT_AUDIENCE"))
+	if err != nil {
+		log.Fatalf("cannot initialize jwt service due to %s", err.Error())
+	}
+
+	if interval := os.Getenv("JWT_KEY_ROTATION_INTERVAL"); interval != "" {
+		rotationInterval, err := time.ParseDuration(interval)
+		if err != nil {
+			log.Fatalf("invalid duration format for JWT_KEY_ROTATION_INTERVAL: %s", err.Error())
+		}
+
+		jwtKeys.StartRotation(b.ctx, rotationInterval, jwtService.ExpiresIn())
+	}
+
+	// initialize password hasher
+	passwordHasher, err := password.NewHasher(password.Config{
+		Algorithm:         os.Getenv("PASSWORD_HASH_ALGORITHM"),
+		Argon2Memory:      os.Getenv("ARGON2_MEMORY"),
+		Argon2Iterations:  os.Getenv("ARGON2_ITERATIONS"),
+		Argon2Parallelism: os.Getenv("ARGON2_PARALLELISM"),
+		BcryptCost:        os.Getenv("BCRYPT_COST"),
+	})
+	if err != nil {
+		log.Fatalf("cannot initialize password hasher due to %s", err.Error())
+	}
+
+	refreshTokenTTL, err := time.ParseDuration(os.Getenv("JWT_REFRESH_EXPIRED"))
+	if err != nil {
+		log.Fatalf("invalid duration format for JWT_REFRESH_EXPIRED: %s", err.Error())
+	}
+
+	// initialize mailer and signer for the tokens sent by email
+	mailService, err := mailer.NewMailer(mailer.Config{
+		Driver:   os.Getenv("MAIL_DRIVER"),
+		From:     os.Getenv("MAIL_FROM"),
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		DropDir:  os.Getenv("MAIL_DROP_DIR"),
+	})
+	if err != nil {
+		log.Fatalf("cannot initialize mailer due to %s", err.Error())
+	}
+
+	tokenSigner, err := token.NewSigner(os.Getenv("TOKEN_SIGNING_SECRET"))
+	if err != nil {
+		log.Fatalf("cannot initialize token signer due to %s", err.Error())
+	}
+
+	verificationTokenTTL, err := time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_EXPIRED"))
+	if err != nil {
+		log.Fatalf("invalid duration format for EMAIL_VERIFICATION_EXPIRED: %s", err.Error())
+	}
+
+	// EMAIL_VERIFICATION_RESEND_INTERVAL is the name used before the
+	// interval also applied to password reset mails
+	mailResendIntervalEnv := "MAIL_RESEND_INTERVAL"
+	if os.Getenv(mailResendIntervalEnv) == "" {
+		mailResendIntervalEnv = "EMAIL_VERIFICATION_RESEND_INTERVAL"
+	}
+
+	mailResendInterval, err := time.ParseDuration(os.Getenv(mailResendIntervalEnv))
+	if err != nil {
+		log.Fatalf("invalid duration format for %s: %s", mailResendIntervalEnv, err.Error())
+	}
+
+	passwordResetTokenTTL, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_EXPIRED"))
+	if err != nil {
+		log.Fatalf("invalid duration format for PASSWORD_RESET_EXPIRED: %s", err.Error())
+	}
+
+	emailChangeTokenTTL, err := time.ParseDuration(os.Getenv("EMAIL_CHANGE_EXPIRED"))
+	if err != nil {
+		log.Fatalf("invalid duration format for EMAIL_CHANGE_EXPIRED: %s", err.Error())
+	}
+
+	twoFactorChallengeTTL, err := time.ParseDuration(os.Getenv("TWO_FACTOR_CHALLENGE_EXPIRED"))
 	if err != nil {
-		log.Printf("cannot initialize jwt service due to %s", err.Error())
+		log.Fatalf("invalid duration format for TWO_FACTOR_CHALLENGE_EXPIRED: %s", err.Error())
 	}
 
+	oauthStateTTL, err := time.ParseDuration(os.Getenv("OAUTH_STATE_EXPIRED"))
+	if err != nil {
+		log.Fatalf("invalid duration format for OAUTH_STATE_EXPIRED: %s", err.Error())
+	}
+
+	// initialize the OpenID Connect providers listed in OAUTH_PROVIDERS, each
+	// configured through OAUTH_<NAME>_* variables
+	var oauthProviders []*oidc.Provider
+	for _, name := range strings.Split(os.Getenv("OAUTH_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+
+		var scopes []string
+		if s := os.Getenv(prefix + "SCOPES"); s != "" {
+			scopes = strings.Fields(s)
+		}
+
+		provider, err := oidc.NewProvider(oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("APP_URL") + "/auth/oauth/" + name + "/callback",
+			Scopes:       scopes,
+		}, nil)
+		if err != nil {
+			log.Fatalf("cannot initialize oauth provider due to %s", err.Error())
+		}
+
+		oauthProviders = append(oauthProviders, provider)
+	}
+
+	// initialize the ranking of the posts
+	scorer, err := ranking.NewScorer(os.Getenv("RANKING_ALGORITHM"))
+	if err != nil {
+		log.Fatalf("cannot initialize ranking due to %s", err.Error())
+	}
+
+	rankingInterval, err := time.ParseDuration(os.Getenv("RANKING_RECOMPUTE_INTERVAL"))
+	if err != nil {
+		log.Fatalf("invalid duration format for RANKING_RECOMPUTE_INTERVAL: %s", err.Error())
+	}
+
+	rankingWindow, err := time.ParseDuration(os.Getenv("RANKING_RECOMPUTE_WINDOW"))
+	if err != nil {
+		log.Fatalf("invalid duration format for RANKING_RECOMPUTE_WINDOW: %s", err.Error())
+	}
+
+	// the emoji users can react with
+	reactionSet, err := reaction.Parse(os.Getenv("REACTIONS"))
+	if err != nil {
+		log.Fatalf("invalid REACTIONS: %s", err.Error())
+	}
+
+	// initialize the realtime hub, only the in-process one for now
+	var realtimeHistorySize int
+	if s := os.Getenv("REALTIME_HISTORY_SIZE"); s != "" {
+		realtimeHistorySize, err = strconv.Atoi(s)
+		if err != nil {
+			log.Fatalf("invalid REALTIME_HISTORY_SIZE: %s", err.Error())
+		}
+	}
+
+	realtimeHub, err := realtime.NewHub(realtime.Config{
+		Driver:      os.Getenv("REALTIME_DRIVER"),
+		HistorySize: realtimeHistorySize,
+	})
+	if err != nil {
+		log.Fatalf("cannot initialize realtime hub due to %s", err.Error())
+	}
+
+	b.closers = append(b.closers, realtimeHub.Close)
+
 	// initialize supabase
 	client := supabaseStorage.NewClient(os.Getenv("SUPABASE_URL"), os.Getenv("SUPABASE_KEY"),
 		map[string]string{
@@ -50,27 +236,94 @@ func (b *Bootstrap) InitApp() {
 
 	// initialize repository
 	userRepo := repository.NewUserRepo(b.db)
-	postRepo := repository.NewPostRepo(b.db)
+	postRepo := repository.NewPostRepo(b.db, scorer)
 	commentRepo := repository.NewCommentRepo(b.db)
+	tokenRepo := repository.NewTokenRepo(b.db)
+	searchSourceRepo := repository.NewSearchSourceRepo(b.db)
+	reactionRepo := repository.NewReactionRepo(b.db)
+	followRepo := repository.NewFollowRepo(b.db)
+	feedSource := repository.NewFanOutOnReadFeed(b.db)
+	blockRepo := repository.NewBlockRepo(b.db)
+	bookmarkRepo := repository.NewBookmarkRepo(b.db)
+	notificationRepo := repository.NewNotificationRepo(b.db)
+
+	// initialize the search index, mysql searches the tables through their
+	// FULLTEXT indexes and bleve keeps an embedded index on disk
+	var searchIndex repository.SearchIndex
+	switch driver := os.Getenv("SEARCH_DRIVER"); driver {
+	case "", "mysql":
+		searchIndex = repository.NewMySQLSearchIndex(b.db)
+	case "bleve":
+		bleveIndex, err := repository.NewBleveSearchIndex(os.Getenv("SEARCH_INDEX_PATH"))
+		if err != nil {
+			log.Fatalf("cannot initialize search index due to %s", err.Error())
+		}
+
+		b.closers = append(b.closers, bleveIndex.Close)
+		searchIndex = bleveIndex
+	default:
+		log.Fatalf("unknown SEARCH_DRIVER %q", driver)
+	}
 
 	// initialize usecase
+	searchUsecase := usecase.NewSearchUsecase(searchIndex, searchSourceRepo, blockRepo)
+	go func() {
+		if _, err := searchUsecase.Reindex(b.ctx); err != nil {
+			log.Printf("cannot fill search index: %s", err)
+		}
+	}()
+
 	fileUsecase := usecase.NewFileUsecase(supabase)
-	userUsecase := usecase.NewUserUsecase(userRepo, jwtService)
-	postUsecase := usecase.NewPostUsecase(postRepo, commentRepo)
+	userUsecase := usecase.NewUserUsecase(userRepo, tokenRepo, jwtService, passwordHasher,
+		mailService, tokenSigner, oauthProviders, searchUsecase, usecase.UserUsecaseConfig{
+			RefreshTokenTTL:          refreshTokenTTL,
+			RequireEmailVerification: os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true",
+			VerificationTokenTTL:     verificationTokenTTL,
+			MailResendInterval:       mailResendInterval,
+			PasswordResetTokenTTL:    passwordResetTokenTTL,
+			EmailChangeTokenTTL:      emailChangeTokenTTL,
+			TwoFactorChallengeTTL:    twoFactorChallengeTTL,
+			TOTPIssuer:               os.Getenv("TOTP_ISSUER"),
+			OAuthStateTTL:            oauthStateTTL,
+			AppURL:                   os.Getenv("APP_URL"),
+		})
+	userUsecase.StartTokenPurge(b.ctx, time.Hour)
+	rankingUsecase := usecase.NewRankingUsecase(postRepo, scorer)
+	rankingUsecase.StartRecomputation(b.ctx, rankingInterval, rankingWindow)
+	reactionUsecase := usecase.NewReactionUsecase(reactionRepo, postRepo, commentRepo, blockRepo, reactionSet)
+	realtimeUsecase := usecase.NewRealtimeUsecase(realtimeHub, postRepo, blockRepo)
+	notificationUsecase := usecase.NewNotificationUsecase(notificationRepo, userRepo, blockRepo, realtimeUsecase)
+	postUsecase := usecase.NewPostUsecase(postRepo, commentRepo, rankingUsecase, searchUsecase,
+		reactionUsecase, feedSource, blockRepo, notificationUsecase, realtimeUsecase)
+	adminUsecase := usecase.NewAdminUsecase(userRepo, tokenRepo, commentRepo, postUsecase)
+	profileUsecase := usecase.NewProfileUsecase(userRepo, postRepo, commentRepo, followRepo, blockRepo, reactionUsecase)
+	followUsecase := usecase.NewFollowUsecase(followRepo, userRepo, feedSource, blockRepo, reactionUsecase,
+		notificationUsecase)
+	blockUsecase := usecase.NewBlockUsecase(blockRepo, userRepo)
+	bookmarkUsecase := usecase.NewBookmarkUsecase(bookmarkRepo, postRepo, blockRepo, reactionUsecase)
 
 	// init handler
 	fileHandler := httpHandler.NewFileHandler(fileUsecase)
 	userHandler := httpHandler.NewUserHandler(userUsecase)
-	postHandler := httpHandler.NewPostHandler(postUsecase)
+	postHandler := httpHandler.NewPostHandler(postUsecase, reactionUsecase)
+	adminHandler := httpHandler.NewAdminHandler(adminUsecase)
+	searchHandler := httpHandler.NewSearchHandler(searchUsecase)
+	profileHandler := httpHandler.NewProfileHandler(profileUsecase)
+	followHandler := httpHandler.NewFollowHandler(followUsecase)
+	blockHandler := httpHandler.NewBlockHandler(blockUsecase)
+	bookmarkHandler := httpHandler.NewBookmarkHandler(bookmarkUsecase)
+	notificationHandler := httpHandler.NewNotificationHandler(notificationUsecase)
+	realtimeHandler := httpHandler.NewRealtimeHandler(realtimeUsecase)
+	wellKnownHandler := httpHandler.NewWellKnownHandler(jwtService)
 
 	// initialize middleware
-	middleware := middleware.NewMiddleware(jwtService, b.logger)
+	middleware := middleware.NewMiddleware(jwtService, tokenRepo, b.logger)
 
 	b.router.Use(middleware.LoggingMiddleware)
 
 	b.router.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"https://*", "http://*"},
-		AllowedMethods: []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type"},
 	}))
 
@@ -78,7 +331,31 @@ func (b *Bootstrap) InitApp() {
 	httpHandler.FileRoutes(b.router, fileHandler, middleware)
 	httpHandler.UserRoutes(b.router, userHandler, middleware)
 	httpHandler.PostRoutes(b.router, postHandler, middleware)
+	httpHandler.AdminRoutes(b.router, adminHandler, middleware)
+	httpHandler.SearchRoutes(b.router, searchHandler, middleware)
+	httpHandler.ProfileRoutes(b.router, profileHandler, middleware)
+	httpHandler.FollowRoutes(b.router, followHandler, middleware)
+	httpHandler.BlockRoutes(b.router, blockHandler, middleware)
+	httpHandler.BookmarkRoutes(b.router, bookmarkHandler, middleware)
+	httpHandler.NotificationRoutes(b.router, notificationHandler, middleware)
+	httpHandler.RealtimeRoutes(b.router, realtimeHandler, middleware)
+	httpHandler.WellKnownRoutes(b.router, wellKnownHandler)
 
 	//health check
 	util.HealthCheck(b.router, b.db)
 }
+
+// Stop cancels the background jobs started by InitApp and releases what it
+// opened.
+func (b *Bootstrap) Stop(ctx context.Context) error {
+	b.cancel()
+
+	var errs []error
+	for _, closer := range b.closers {
+		if err := closer(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,207 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/federicodosantos/socialize/internal/middleware"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/usecase"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/rbac"
+	response "github.com/federicodosantos/socialize/pkg/response"
+	"github.com/federicodosantos/socialize/pkg/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type AdminHandler struct {
+	adminUC usecase.AdminUsecaseItf
+}
+
+func NewAdminHandler(adminUC usecase.AdminUsecaseItf) *AdminHandler {
+	return &AdminHandler{adminUC: adminUC}
+}
+
+func AdminRoutes(router *chi.Mux, adminHandle *AdminHandler, middleware middleware.MiddlewareItf) {
+	// private routes
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.JwtAuthMiddleware)
+		r.Route("/admin", func(r chi.Router) {
+			r.With(middleware.RequirePermission(rbac.PermManageUsers)).Get("/users", adminHandle.GetAllUsers)
+			r.With(middleware.RequirePermission(rbac.PermManageUsers)).Patch("/users/{userID}/role", adminHandle.UpdateUserRole)
+			r.With(middleware.RequirePermission(rbac.PermManageUsers)).Post("/users/{userID}/ban", adminHandle.BanUser)
+			r.With(middleware.RequirePermission(rbac.PermManageUsers)).Delete("/users/{userID}/ban", adminHandle.UnbanUser)
+
+			r.With(middleware.RequirePermission(rbac.PermRemoveAnyPost)).Delete("/posts/{postID}", adminHandle.RemovePost)
+			r.With(middleware.RequirePermission(rbac.PermRemoveAnyComment)).Delete("/comments/{commentID}", adminHandle.RemoveComment)
+		})
+	})
+}
+
+func (h *AdminHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	filter := model.UserFilter{}
+
+	filter.Limit, err = util.ParseLimit(r)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if offset := r.URL.Query().Get("offset"); offset != "" {
+		filter.Offset, err = strconv.Atoi(offset)
+		if err != nil || filter.Offset < 0 {
+			response.FailedResponse(w, http.StatusBadRequest, "Invalid offset")
+			return
+		}
+	}
+
+	reqCtx := r.Context()
+
+	users, err := h.adminUC.GetAllUsers(reqCtx, actor, filter)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully get all users", users)
+}
+
+func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	var req *model.UpdateRoleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	user, err := h.adminUC.UpdateUserRole(reqCtx, actor, userID, req)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully update user role", user)
+}
+
+func (h *AdminHandler) BanUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	user, err := h.adminUC.BanUser(reqCtx, actor, userID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully ban user", user)
+}
+
+func (h *AdminHandler) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	user, err := h.adminUC.UnbanUser(reqCtx, actor, userID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully unban user", user)
+}
+
+func (h *AdminHandler) RemovePost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	if err := h.adminUC.RemovePost(reqCtx, actor, postID); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Post removed successfully", nil)
+}
+
+func (h *AdminHandler) RemoveComment(w http.ResponseWriter, r *http.Request) {
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	if err := h.adminUC.RemoveComment(reqCtx, actor, commentID); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Comment removed successfully", nil)
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, customError.ErrPermissionDenied):
+		response.FailedResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, customError.ErrInvalidRole):
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, customError.ErrUserNotFound), errors.Is(err, customError.ErrNotFound):
+		response.FailedResponse(w, http.StatusNotFound, err.Error())
+	default:
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/federicodosantos/socialize/internal/middleware"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/usecase"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	response "github.com/federicodosantos/socialize/pkg/response"
+	"github.com/federicodosantos/socialize/pkg/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type BlockHandler struct {
+	blockUC usecase.BlockUsecaseItf
+}
+
+func NewBlockHandler(blockUC usecase.BlockUsecaseItf) *BlockHandler {
+	return &BlockHandler{blockUC: blockUC}
+}
+
+func BlockRoutes(router *chi.Mux, blockHandle *BlockHandler, middleware middleware.MiddlewareItf) {
+	// private routes
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.JwtAuthMiddleware)
+		r.Get("/blocks", blockHandle.GetBlockedUsers)
+		r.Get("/mutes", blockHandle.GetMutedUsers)
+		r.Post("/users/{userID}/block", blockHandle.Block)
+		r.Delete("/users/{userID}/block", blockHandle.Unblock)
+		r.Post("/users/{userID}/mute", blockHandle.Mute)
+		r.Delete("/users/{userID}/mute", blockHandle.Unmute)
+	})
+}
+
+func (h *BlockHandler) Block(w http.ResponseWriter, r *http.Request) {
+	h.changeRestriction(w, r, h.blockUC.Block, "Block user successfully")
+}
+
+func (h *BlockHandler) Unblock(w http.ResponseWriter, r *http.Request) {
+	h.changeRestriction(w, r, h.blockUC.Unblock, "Unblock user successfully")
+}
+
+func (h *BlockHandler) Mute(w http.ResponseWriter, r *http.Request) {
+	h.changeRestriction(w, r, h.blockUC.Mute, "Mute user successfully")
+}
+
+func (h *BlockHandler) Unmute(w http.ResponseWriter, r *http.Request) {
+	h.changeRestriction(w, r, h.blockUC.Unmute, "Unmute user successfully")
+}
+
+func (h *BlockHandler) changeRestriction(w http.ResponseWriter, r *http.Request,
+	change func(ctx context.Context, userID int64, actorID int64) error, message string) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	actorID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	if err := change(reqCtx, userID, actorID); err != nil {
+		writeBlockError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, message, nil)
+}
+
+func (h *BlockHandler) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	h.listRestrictions(w, r, h.blockUC.GetBlockedUsers, "Get blocked users successfully")
+}
+
+func (h *BlockHandler) GetMutedUsers(w http.ResponseWriter, r *http.Request) {
+	h.listRestrictions(w, r, h.blockUC.GetMutedUsers, "Get muted users successfully")
+}
+
+func (h *BlockHandler) listRestrictions(w http.ResponseWriter, r *http.Request,
+	list func(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUserResponse, string, error), message string) {
+	var filter model.RestrictionFilter
+	if err := util.ParseRestrictionFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	users, nextCursor, err := list(reqCtx, userID, filter)
+	if err != nil {
+		writeBlockError(w, err)
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, message, users, nextCursor)
+}
+
+func writeBlockError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, customError.ErrUserNotFound):
+		response.FailedResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, customError.ErrRestrictSelf):
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
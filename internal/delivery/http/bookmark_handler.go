@@ -0,0 +1,139 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/federicodosantos/socialize/internal/middleware"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/usecase"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	response "github.com/federicodosantos/socialize/pkg/response"
+	"github.com/federicodosantos/socialize/pkg/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type BookmarkHandler struct {
+	bookmarkUC usecase.BookmarkUsecaseItf
+}
+
+func NewBookmarkHandler(bookmarkUC usecase.BookmarkUsecaseItf) *BookmarkHandler {
+	return &BookmarkHandler{bookmarkUC: bookmarkUC}
+}
+
+func BookmarkRoutes(router *chi.Mux, bookmarkHandle *BookmarkHandler, middleware middleware.MiddlewareItf) {
+	// private routes
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.JwtAuthMiddleware)
+		r.Get("/bookmarks", bookmarkHandle.GetBookmarks)
+		r.Get("/bookmarks/collections", bookmarkHandle.GetCollections)
+		r.Post("/post/{postID}/bookmark", bookmarkHandle.Bookmark)
+		r.Delete("/post/{postID}/bookmark", bookmarkHandle.RemoveBookmark)
+	})
+}
+
+func (h *BookmarkHandler) Bookmark(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	// the body is optional, a post saved without one is in no collection
+	var req *model.BookmarkRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	if err := h.bookmarkUC.Bookmark(reqCtx, postID, req, userID); err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Bookmark saved successfully", nil)
+}
+
+func (h *BookmarkHandler) RemoveBookmark(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	if err := h.bookmarkUC.RemoveBookmark(reqCtx, postID, userID); err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Bookmark removed successfully", nil)
+}
+
+func (h *BookmarkHandler) GetBookmarks(w http.ResponseWriter, r *http.Request) {
+	var filter model.BookmarkFilter
+	if err := util.ParseBookmarkFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	bookmarks, nextCursor, err := h.bookmarkUC.GetBookmarks(reqCtx, userID, filter)
+	if err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, "Get bookmarks successfully", bookmarks, nextCursor)
+}
+
+func (h *BookmarkHandler) GetCollections(w http.ResponseWriter, r *http.Request) {
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	collections, err := h.bookmarkUC.GetCollections(reqCtx, userID)
+	if err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Get bookmark collections successfully", collections)
+}
+
+func writeBookmarkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, customError.ErrNotFound):
+		response.FailedResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, customError.ErrInvalidCollection):
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
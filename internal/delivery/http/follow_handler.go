@@ -0,0 +1,142 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/federicodosantos/socialize/internal/middleware"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/usecase"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	response "github.com/federicodosantos/socialize/pkg/response"
+	"github.com/federicodosantos/socialize/pkg/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type FollowHandler struct {
+	followUC usecase.FollowUsecaseItf
+}
+
+func NewFollowHandler(followUC usecase.FollowUsecaseItf) *FollowHandler {
+	return &FollowHandler{followUC: followUC}
+}
+
+func FollowRoutes(router *chi.Mux, followHandle *FollowHandler, middleware middleware.MiddlewareItf) {
+	// private routes
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.JwtAuthMiddleware)
+		r.Get("/feed", followHandle.GetFeed)
+		r.Post("/users/{userID}/follow", followHandle.Follow)
+		r.Delete("/users/{userID}/follow", followHandle.Unfollow)
+		r.Get("/users/{userID}/followers", followHandle.GetFollowers)
+		r.Get("/users/{userID}/following", followHandle.GetFollowing)
+	})
+}
+
+func (h *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	h.changeFollow(w, r, h.followUC.Follow, "Follow user successfully")
+}
+
+func (h *FollowHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	h.changeFollow(w, r, h.followUC.Unfollow, "Unfollow user successfully")
+}
+
+func (h *FollowHandler) changeFollow(w http.ResponseWriter, r *http.Request,
+	change func(ctx context.Context, userID int64, followerID int64) (*model.FollowStatus, error), message string) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	followerID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	status, err := change(reqCtx, userID, followerID)
+	if err != nil {
+		writeFollowError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, message, status)
+}
+
+func (h *FollowHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	h.listFollows(w, r, h.followUC.GetFollowers, "Get followers successfully")
+}
+
+func (h *FollowHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	h.listFollows(w, r, h.followUC.GetFollowing, "Get following successfully")
+}
+
+func (h *FollowHandler) listFollows(w http.ResponseWriter, r *http.Request,
+	list func(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUserResponse, string, error), message string) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var filter model.FollowFilter
+	if err := util.ParseFollowFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter.ViewerID, err = util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	users, nextCursor, err := list(reqCtx, userID, filter)
+	if err != nil {
+		writeFollowError(w, err)
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, message, users, nextCursor)
+}
+
+func (h *FollowHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	var filter model.PostFilter
+	if err := util.ParsePostFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	posts, nextCursor, err := h.followUC.GetFeed(reqCtx, userID, filter)
+	if err != nil {
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, "Get feed successfully", posts, nextCursor)
+}
+
+func writeFollowError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, customError.ErrUserNotFound):
+		response.FailedResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, customError.ErrFollowSelf):
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, customError.ErrBlocked):
+		response.FailedResponse(w, http.StatusForbidden, err.Error())
+	default:
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
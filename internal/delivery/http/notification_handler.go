@@ -0,0 +1,171 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/federicodosantos/socialize/internal/middleware"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/usecase"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	response "github.com/federicodosantos/socialize/pkg/response"
+	"github.com/federicodosantos/socialize/pkg/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type NotificationHandler struct {
+	notificationUC usecase.NotificationUsecaseItf
+}
+
+func NewNotificationHandler(notificationUC usecase.NotificationUsecaseItf) *NotificationHandler {
+	return &NotificationHandler{notificationUC: notificationUC}
+}
+
+func NotificationRoutes(router *chi.Mux, notificationHandle *NotificationHandler, middleware middleware.MiddlewareItf) {
+	// private routes
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.JwtAuthMiddleware)
+		r.Route("/notifications", func(r chi.Router) {
+			r.Get("/", notificationHandle.GetNotifications)
+			r.Get("/unread-count", notificationHandle.GetUnreadCount)
+			r.Post("/read-all", notificationHandle.MarkAllRead)
+			r.Get("/preferences", notificationHandle.GetPreferences)
+			r.Put("/preferences", notificationHandle.UpdatePreferences)
+			r.Post("/{notificationID}/read", notificationHandle.MarkRead)
+		})
+	})
+}
+
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	var filter model.NotificationFilter
+	if err := util.ParseNotificationFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	page, nextCursor, err := h.notificationUC.GetNotifications(reqCtx, userID, filter)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, "Get notifications successfully", page, nextCursor)
+}
+
+func (h *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	unread, err := h.notificationUC.GetUnreadCount(reqCtx, userID)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Get unread count successfully", unread)
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := strconv.ParseInt(chi.URLParam(r, "notificationID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	unread, err := h.notificationUC.MarkRead(reqCtx, notificationID, userID)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Mark notification as read successfully", unread)
+}
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	unread, err := h.notificationUC.MarkAllRead(reqCtx, userID)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Mark all notifications as read successfully", unread)
+}
+
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	prefs, err := h.notificationUC.GetPreferences(reqCtx, userID)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Get notification preferences successfully", prefs)
+}
+
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var req map[string]bool
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	prefs, err := h.notificationUC.UpdatePreferences(reqCtx, userID, req)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Update notification preferences successfully", prefs)
+}
+
+func writeNotificationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, customError.ErrNotFound):
+		response.FailedResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, customError.ErrUnknownNotificationType):
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -1,43 +1,62 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/federicodosantos/socialize/internal/middleware"
 	"github.com/federicodosantos/socialize/internal/model"
 	"github.com/federicodosantos/socialize/internal/usecase"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
 	response "github.com/federicodosantos/socialize/pkg/response"
 	"github.com/federicodosantos/socialize/pkg/util"
 	"github.com/go-chi/chi/v5"
 )
 
 type PostHandler struct {
-	postUsecase usecase.PostUsecaseItf
+	postUsecase     usecase.PostUsecaseItf
+	reactionUsecase usecase.ReactionUsecaseItf
 }
 
-func NewPostHandler(postUsecase usecase.PostUsecaseItf) *PostHandler {
-	return &PostHandler{postUsecase: postUsecase}
+func NewPostHandler(postUsecase usecase.PostUsecaseItf, reactionUsecase usecase.ReactionUsecaseItf) *PostHandler {
+	return &PostHandler{postUsecase: postUsecase, reactionUsecase: reactionUsecase}
 }
 
 func PostRoutes(router *chi.Mux, postHandle *PostHandler, middleware middleware.MiddlewareItf) {
 	// private routes
 	router.Group(func(r chi.Router) {
 		r.Use(middleware.JwtAuthMiddleware)
+		r.Get("/reactions", postHandle.GetReactionSet)
 		r.Route("/post", func(r chi.Router) {
 			r.Post("/", postHandle.CreatePost)
 			r.Get("/", postHandle.GetAllPost)
 			r.Get("/{postID}", postHandle.GetPostByID)
+			r.Patch("/{postID}", postHandle.UpdatePost)
+			r.Get("/{postID}/history", postHandle.GetPostHistory)
 			r.Delete("/{postID}", postHandle.DeletePost)
 			r.Post("/{postID}/up-vote", postHandle.UpVote)
 			r.Post("/{postID}/down-vote", postHandle.DownVote)
-		
-		
+			r.Delete("/{postID}/vote", postHandle.RetractVote)
+			r.Put("/{postID}/reaction", postHandle.ReactToPost)
+			r.Delete("/{postID}/reaction", postHandle.RemovePostReaction)
+
+			r.Get("/{postID}/comments", postHandle.GetComments)
 			r.Post("/{postID}/comment", postHandle.CreateComment)
+			r.Get("/{postID}/comment/{commentID}/replies", postHandle.GetReplies)
+			r.Post("/{postID}/comment/{commentID}/reply", postHandle.ReplyComment)
+			r.Patch("/{postID}/comment/{commentID}", postHandle.UpdateComment)
+			r.Get("/{postID}/comment/{commentID}/history", postHandle.GetCommentHistory)
 			r.Delete("/{postID}/comment/{commentID}", postHandle.DeleteComment)
+			r.Post("/{postID}/comment/{commentID}/up-vote", postHandle.UpVoteComment)
+			r.Post("/{postID}/comment/{commentID}/down-vote", postHandle.DownVoteComment)
+			r.Delete("/{postID}/comment/{commentID}/vote", postHandle.RetractCommentVote)
+			r.Put("/{postID}/comment/{commentID}/reaction", postHandle.ReactToComment)
+			r.Delete("/{postID}/comment/{commentID}/reaction", postHandle.RemoveCommentReaction)
 		})
-		
+
 	})
 }
 
@@ -74,13 +93,19 @@ func (h *PostHandler) GetAllPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	posts, err := h.postUsecase.GetAllPost(reqCtx, filter)
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+	filter.ViewerID = userID
+
+	posts, nextCursor, err := h.postUsecase.GetAllPost(reqCtx, filter)
 	if err != nil {
 		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	response.SuccessResponse(w, http.StatusOK, "Get all post successfully", posts)
+	response.PaginatedResponse(w, http.StatusOK, "Get all post successfully", posts, nextCursor)
 }
 
 func (h *PostHandler) GetPostByID(w http.ResponseWriter, r *http.Request) {
@@ -93,15 +118,75 @@ func (h *PostHandler) GetPostByID(w http.ResponseWriter, r *http.Request) {
 
 	reqCtx := r.Context()
 
-	post, err := h.postUsecase.GetPostByID(reqCtx, postID)
+	userID, err := util.GetUserIdFromContext(w, r)
 	if err != nil {
-		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	post, err := h.postUsecase.GetPostByID(reqCtx, postID, userID)
+	if err != nil {
+		writePostError(w, err)
 		return
 	}
 
 	response.SuccessResponse(w, http.StatusOK, "Get post by ID successfully", post)
 }
 
+func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	postIDStr := chi.URLParam(r, "postID")
+	postID, err := strconv.ParseInt(postIDStr, 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var req *model.PostUpdate
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	post, err := h.postUsecase.UpdatePost(reqCtx, postID, req, actor)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Post updated successfully", post)
+}
+
+func (h *PostHandler) GetPostHistory(w http.ResponseWriter, r *http.Request) {
+	postIDStr := chi.URLParam(r, "postID")
+	postID, err := strconv.ParseInt(postIDStr, 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	history, err := h.postUsecase.GetPostHistory(reqCtx, postID, userID)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Get post history successfully", history)
+}
+
 func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	postIDStr := chi.URLParam(r, "postID")
 	postID, err := strconv.ParseInt(postIDStr, 10, 64)
@@ -110,11 +195,16 @@ func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
 	reqCtx := r.Context()
 
-	err = h.postUsecase.DeletePost(reqCtx, postID)
+	err = h.postUsecase.DeletePost(reqCtx, postID, actor)
 	if err != nil {
-		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		writePostError(w, err)
 		return
 	}
 
@@ -123,12 +213,12 @@ func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 
 func (h *PostHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	postIDStr := chi.URLParam(r, "postID")
-    postID, err := strconv.ParseInt(postIDStr, 10, 64)
-    if err != nil {
-        response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
-        return
-    }
-	
+	postID, err := strconv.ParseInt(postIDStr, 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
 	var model *model.CommentCreate
 
 	if err := json.NewDecoder(r.Body).Decode(&model); err != nil {
@@ -145,16 +235,195 @@ func (h *PostHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postUsecase.CreateComment(reqCtx, model, userID)
+	comment, err := h.postUsecase.CreateComment(reqCtx, model, userID)
 	if err != nil {
-		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
-		return 
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusCreated, "successfully create a new comment", comment)
+}
+
+func (h *PostHandler) ReplyComment(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	var req *model.CommentCreate
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req.PostID = postID
+	req.ParentID = commentID
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	comment, err := h.postUsecase.CreateComment(reqCtx, req, userID)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusCreated, "successfully reply to the comment", comment)
+}
+
+func (h *PostHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var filter model.CommentTreeFilter
+	if err := util.ParseCommentTreeFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter.ViewerID, err = util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	comments, nextCursor, err := h.postUsecase.GetComments(reqCtx, postID, filter)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, "Get comments successfully", comments, nextCursor)
+}
+
+func (h *PostHandler) GetReplies(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	filter := model.CommentTreeFilter{ParentID: commentID}
+	if err := util.ParseCommentTreeFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter.ViewerID, err = util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	replies, nextCursor, err := h.postUsecase.GetComments(reqCtx, postID, filter)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, "Get replies successfully", replies, nextCursor)
+}
+
+func (h *PostHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	postIDStr := chi.URLParam(r, "postID")
+	postID, err := strconv.ParseInt(postIDStr, 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	commentIDStr := chi.URLParam(r, "commentID")
+	commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	var req *model.CommentUpdate
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	comment, err := h.postUsecase.UpdateComment(reqCtx, postID, commentID, req, actor)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Comment updated successfully", comment)
+}
+
+func (h *PostHandler) GetCommentHistory(w http.ResponseWriter, r *http.Request) {
+	postIDStr := chi.URLParam(r, "postID")
+	postID, err := strconv.ParseInt(postIDStr, 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	commentIDStr := chi.URLParam(r, "commentID")
+	commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid comment ID")
+		return
 	}
 
-	response.SuccessResponse(w, http.StatusCreated, "successfully create a new comment", nil)
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	history, err := h.postUsecase.GetCommentHistory(reqCtx, postID, commentID, userID)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Get comment history successfully", history)
 }
 
 func (h *PostHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	postIDStr := chi.URLParam(r, "postID")
+	postID, err := strconv.ParseInt(postIDStr, 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
 	commentIDStr := chi.URLParam(r, "commentID")
 	commentID, err := strconv.ParseInt(commentIDStr, 10, 64)
 	if err != nil {
@@ -162,11 +431,16 @@ func (h *PostHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor, err := util.GetActorFromContext(w, r)
+	if err != nil {
+		return
+	}
+
 	reqCtx := r.Context()
 
-	err = h.postUsecase.DeleteComment(reqCtx, commentID)
+	err = h.postUsecase.DeleteComment(reqCtx, postID, commentID, actor)
 	if err != nil {
-		response.FailedResponse(w, http.StatusInternalServerError, "Failed to delete comment")
+		writePostError(w, err)
 		return
 	}
 
@@ -188,13 +462,13 @@ func (h *PostHandler) UpVote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postUsecase.CreateUpVote(reqCtx, postID, userID)
+	vote, err := h.postUsecase.CreateUpVote(reqCtx, postID, userID)
 	if err != nil {
-		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		writePostError(w, err)
 		return
 	}
 
-	response.SuccessResponse(w, http.StatusOK, "Upvote successfully", nil)
+	response.SuccessResponse(w, http.StatusOK, "Upvote successfully", vote)
 }
 
 func (h *PostHandler) DownVote(w http.ResponseWriter, r *http.Request) {
@@ -212,11 +486,207 @@ func (h *PostHandler) DownVote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postUsecase.CreateDownVote(reqCtx, postID, userID)
+	vote, err := h.postUsecase.CreateDownVote(reqCtx, postID, userID)
 	if err != nil {
-		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Downvote successfully", vote)
+}
+
+func (h *PostHandler) RetractVote(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	vote, err := h.postUsecase.RetractVote(reqCtx, postID, userID)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Vote retracted successfully", vote)
+}
+
+func (h *PostHandler) RetractCommentVote(w http.ResponseWriter, r *http.Request) {
+	h.voteComment(w, r, h.postUsecase.RetractCommentVote, "Comment vote retracted successfully")
+}
+
+func (h *PostHandler) UpVoteComment(w http.ResponseWriter, r *http.Request) {
+	h.voteComment(w, r, h.postUsecase.CreateCommentUpVote, "Upvote comment successfully")
+}
+
+func (h *PostHandler) DownVoteComment(w http.ResponseWriter, r *http.Request) {
+	h.voteComment(w, r, h.postUsecase.CreateCommentDownVote, "Downvote comment successfully")
+}
+
+func (h *PostHandler) voteComment(w http.ResponseWriter, r *http.Request,
+	vote func(ctx context.Context, postID, commentID int64, userID int64) (*model.VoteResponse, error), message string) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	result, err := vote(reqCtx, postID, commentID, userID)
+	if err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, message, result)
+}
+
+func (h *PostHandler) GetReactionSet(w http.ResponseWriter, r *http.Request) {
+	response.SuccessResponse(w, http.StatusOK, "Get reactions successfully", h.reactionUsecase.GetReactionSet())
+}
+
+func (h *PostHandler) ReactToPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var req *model.ReactionRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	if err := h.reactionUsecase.ReactToPost(reqCtx, postID, req, userID); err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Reaction saved successfully", nil)
+}
+
+func (h *PostHandler) RemovePostReaction(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	if err := h.reactionUsecase.RemovePostReaction(reqCtx, postID, userID); err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Reaction removed successfully", nil)
+}
+
+func (h *PostHandler) ReactToComment(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	var req *model.ReactionRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	if err := h.reactionUsecase.ReactToComment(reqCtx, postID, commentID, req, userID); err != nil {
+		writePostError(w, err)
 		return
 	}
 
-	response.SuccessResponse(w, http.StatusOK, "Downvote successfully", nil)
+	response.SuccessResponse(w, http.StatusOK, "Reaction saved successfully", nil)
+}
+
+func (h *PostHandler) RemoveCommentReaction(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	if err := h.reactionUsecase.RemoveCommentReaction(reqCtx, postID, commentID, userID); err != nil {
+		writePostError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Reaction removed successfully", nil)
+}
+
+func writePostError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, customError.ErrPermissionDenied), errors.Is(err, customError.ErrBlocked):
+		response.FailedResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, customError.ErrNotFound):
+		response.FailedResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, customError.ErrEmptyComment), errors.Is(err, customError.ErrUnknownReaction):
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+	}
 }
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/federicodosantos/socialize/internal/middleware"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/usecase"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	response "github.com/federicodosantos/socialize/pkg/response"
+	"github.com/federicodosantos/socialize/pkg/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type ProfileHandler struct {
+	profileUC usecase.ProfileUsecaseItf
+}
+
+func NewProfileHandler(profileUC usecase.ProfileUsecaseItf) *ProfileHandler {
+	return &ProfileHandler{profileUC: profileUC}
+}
+
+func ProfileRoutes(router *chi.Mux, profileHandle *ProfileHandler, middleware middleware.MiddlewareItf) {
+	// private routes
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.JwtAuthMiddleware)
+		r.Route("/users/{userID}", func(r chi.Router) {
+			r.Get("/", profileHandle.GetProfile)
+			r.Get("/posts", profileHandle.GetUserPosts)
+			r.Get("/comments", profileHandle.GetUserComments)
+		})
+	})
+}
+
+func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	viewerID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	profile, err := h.profileUC.GetProfile(reqCtx, userID, viewerID)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Get user profile successfully", profile)
+}
+
+func (h *ProfileHandler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var filter model.PostFilter
+	if err := util.ParsePostFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter.ViewerID, err = util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	posts, nextCursor, err := h.profileUC.GetUserPosts(reqCtx, userID, filter)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, "Get user posts successfully", posts, nextCursor)
+}
+
+func (h *ProfileHandler) GetUserComments(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var filter model.CommentFilter
+	if err := util.ParseCommentFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter.ViewerID, err = util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	comments, nextCursor, err := h.profileUC.GetUserComments(reqCtx, userID, filter)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, "Get user comments successfully", comments, nextCursor)
+}
+
+func writeProfileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, customError.ErrUserNotFound):
+		response.FailedResponse(w, http.StatusNotFound, err.Error())
+	default:
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,231 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/federicodosantos/socialize/internal/middleware"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/usecase"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/realtime"
+	response "github.com/federicodosantos/socialize/pkg/response"
+	"github.com/federicodosantos/socialize/pkg/util"
+	"github.com/federicodosantos/socialize/pkg/websocket"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// heartbeatInterval keeps idle connections open through proxies and
+	// tells the websocket clients that went away.
+	heartbeatInterval = 25 * time.Second
+	// writeWait is how long writing an event, or answering a ping, may take
+	// before the client is given up on.
+	writeWait = 10 * time.Second
+	// retryDelay is how long EventSource waits before reconnecting.
+	retryDelay = 3 * time.Second
+)
+
+type RealtimeHandler struct {
+	realtimeUC usecase.RealtimeUsecaseItf
+}
+
+func NewRealtimeHandler(realtimeUC usecase.RealtimeUsecaseItf) *RealtimeHandler {
+	return &RealtimeHandler{realtimeUC: realtimeUC}
+}
+
+func RealtimeRoutes(router *chi.Mux, realtimeHandle *RealtimeHandler, middleware middleware.MiddlewareItf) {
+	// private routes, browsers cannot set headers on these connections so
+	// the token may also come in the query
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.QueryTokenMiddleware)
+		r.Use(middleware.JwtAuthMiddleware)
+		r.Route("/realtime", func(r chi.Router) {
+			r.Get("/events", realtimeHandle.StreamEvents)
+			r.Get("/ws", realtimeHandle.ServeWebSocket)
+		})
+	})
+}
+
+// subscribe opens the stream of the request user, on failure the error
+// response is already written.
+func (h *RealtimeHandler) subscribe(w http.ResponseWriter, r *http.Request) (*usecase.RealtimeStream, bool) {
+	var filter model.RealtimeFilter
+	if err := util.ParseRealtimeFilter(r, &filter); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	userID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return nil, false
+	}
+
+	stream, err := h.realtimeUC.Subscribe(r.Context(), userID, filter)
+	if err != nil {
+		writeRealtimeError(w, err)
+		return nil, false
+	}
+
+	return stream, true
+}
+
+// StreamEvents sends the events as Server-Sent Events. EventSource
+// reconnects on its own and sends the id of the last event it got in the
+// Last-Event-ID header.
+func (h *RealtimeHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	stream, ok := h.subscribe(w, r)
+	if !ok {
+		return
+	}
+	defer stream.Close()
+
+	rc := http.NewResponseController(w)
+
+	// the stream outlives the write timeout of the server
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("cannot clear the write deadline of an event stream: %s", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", retryDelay.Milliseconds()); err != nil {
+		return
+	}
+
+	if err := rc.Flush(); err != nil {
+		log.Printf("cannot flush an event stream: %s", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-stream.Done():
+			// the client reconnects and resumes from its last event
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event := <-stream.Events():
+			if !stream.Visible(event) {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("cannot encode event %s: %s", event.ID, err)
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// ServeWebSocket sends the events as websocket text messages. The client
+// resumes after a reconnect with the last_event_id parameter.
+func (h *RealtimeHandler) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsUpgrade(r) {
+		response.FailedResponse(w, http.StatusBadRequest, "websocket upgrade required")
+		return
+	}
+
+	// subscribing first lets the errors be answered as plain responses
+	stream, ok := h.subscribe(w, r)
+	if !ok {
+		return
+	}
+	defer stream.Close()
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		log.Printf("cannot upgrade to websocket: %s", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	// the client sends nothing but pongs and the close, reading them is
+	// what notices a client that went away
+	ctx := conn.CloseRead(r.Context())
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stream.Done():
+			code := websocket.CloseGoingAway
+			if errors.Is(stream.Err(), realtime.ErrSlowConsumer) {
+				code = websocket.CloseTryAgainLater
+			}
+
+			conn.Close(code, stream.Err().Error())
+			return
+		case <-heartbeat.C:
+			if err := ping(ctx, conn); err != nil {
+				return
+			}
+		case event := <-stream.Events():
+			if !stream.Visible(event) {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("cannot encode event %s: %s", event.ID, err)
+				continue
+			}
+
+			if err := writeText(ctx, conn, data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func ping(ctx context.Context, conn *websocket.Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, writeWait)
+	defer cancel()
+
+	return conn.Ping(ctx)
+}
+
+func writeText(ctx context.Context, conn *websocket.Conn, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, writeWait)
+	defer cancel()
+
+	return conn.WriteText(ctx, data)
+}
+
+func writeRealtimeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, customError.ErrNotFound):
+		response.FailedResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, realtime.ErrHubClosed):
+		response.FailedResponse(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/federicodosantos/socialize/internal/middleware"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/usecase"
+	response "github.com/federicodosantos/socialize/pkg/response"
+	"github.com/federicodosantos/socialize/pkg/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type SearchHandler struct {
+	searchUC usecase.SearchUsecaseItf
+}
+
+func NewSearchHandler(searchUC usecase.SearchUsecaseItf) *SearchHandler {
+	return &SearchHandler{searchUC: searchUC}
+}
+
+func SearchRoutes(router *chi.Mux, searchHandle *SearchHandler, middleware middleware.MiddlewareItf) {
+	// private routes
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.JwtAuthMiddleware)
+		r.Get("/search", searchHandle.Search)
+	})
+}
+
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var query model.SearchQuery
+	if err := util.ParseSearchQuery(r, &query); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	viewerID, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+	query.ViewerID = viewerID
+
+	reqCtx := r.Context()
+
+	hits, nextCursor, err := h.searchUC.Search(reqCtx, query)
+	if err != nil {
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.PaginatedResponse(w, http.StatusOK, "Search successfully", hits, nextCursor)
+}
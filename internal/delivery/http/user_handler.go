@@ -16,6 +16,10 @@ import (
 
 const maxUploadSize = 2 * 1024 * 1024
 
+// oauthSessionCookie keeps the state of an OAuth login between the redirect
+// to the provider and its callback.
+const oauthSessionCookie = "oauth_session"
+
 type UserHandler struct {
 	userUC usecase.UserUsecaseItf
 }
@@ -28,13 +32,31 @@ func UserRoutes(router *chi.Mux, userHandle *UserHandler, middleware middleware.
 	// public routes
 	router.Post("/auth/register", userHandle.Register)
 	router.Post("/auth/login", userHandle.Login)
+	router.Post("/auth/login/2fa", userHandle.LoginTwoFactor)
+	router.Get("/auth/oauth/{provider}/start", userHandle.StartOAuth)
+	router.Get("/auth/oauth/{provider}/callback", userHandle.OAuthCallback)
+	router.Post("/auth/refresh", userHandle.RefreshToken)
+	router.Get("/auth/verify-email", userHandle.VerifyEmail)
+	router.Post("/auth/verify-email", userHandle.VerifyEmail)
+	router.Post("/auth/verify-email/resend", userHandle.ResendVerificationEmail)
+	router.Post("/auth/forgot-password", userHandle.ForgotPassword)
+	router.Post("/auth/reset-password", userHandle.ResetPassword)
+	router.Get("/auth/confirm-email-change", userHandle.ConfirmEmailChange)
+	router.Post("/auth/confirm-email-change", userHandle.ConfirmEmailChange)
 
 	// private routes
 	router.Group(func(r chi.Router) {
 		r.Use(middleware.JwtAuthMiddleware)
 		r.Get("/auth/current-user", userHandle.GetCurrentUser)
+		r.Post("/auth/logout", userHandle.Logout)
 		r.Patch("/auth/update-photo", userHandle.UpdateUserPhoto)
 		r.Patch("/auth/update-data", userHandle.UpdateUserData)
+		r.Post("/auth/change-password", userHandle.ChangePassword)
+		r.Post("/auth/change-email", userHandle.ChangeEmail)
+		r.Post("/auth/2fa/enroll", userHandle.EnrollTwoFactor)
+		r.Post("/auth/2fa/confirm", userHandle.ConfirmTwoFactor)
+		r.Post("/auth/2fa/disable", userHandle.DisableTwoFactor)
+		r.Post("/auth/2fa/recovery-codes", userHandle.RegenerateRecoveryCodes)
 	})
 }
 
@@ -81,15 +103,288 @@ func (uh *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, customError.ErrIncorrectPassword):
 			response.FailedResponse(w, http.StatusUnauthorized, err.Error())
 			return
+		case errors.Is(err, customError.ErrNotVerified),
+			errors.Is(err, customError.ErrUserBanned):
+			response.FailedResponse(w, http.StatusForbidden, err.Error())
+			return
 		default:
 			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 
+	if token.TwoFactorRequired {
+		response.SuccessResponse(w, http.StatusOK, "two-factor code required", token)
+		return
+	}
+
 	response.SuccessResponse(w, http.StatusOK, "successfully login to account", token)
 }
 
+func (uh *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req *model.TwoFactorLoginRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqCtx := r.Context()
+
+	token, err := uh.userUC.LoginTwoFactor(reqCtx, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrInvalidToken),
+			errors.Is(err, customError.ErrInvalidTwoFactorCode):
+			response.FailedResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		case errors.Is(err, customError.ErrUserBanned):
+			response.FailedResponse(w, http.StatusForbidden, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully login to account", token)
+}
+
+// StartOAuth redirects the browser to the provider login page.
+func (uh *UserHandler) StartOAuth(w http.ResponseWriter, r *http.Request) {
+	reqCtx := r.Context()
+
+	authorization, err := uh.userUC.StartOAuth(reqCtx, chi.URLParam(r, "provider"))
+	if err != nil {
+		if errors.Is(err, customError.ErrUnknownOAuthProvider) {
+			response.FailedResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Lax is required for the cookie to come back on the top level redirect
+	// from the provider
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthSessionCookie,
+		Value:    authorization.Session,
+		Path:     "/auth/oauth/",
+		MaxAge:   int(authorization.ExpiresIn.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authorization.AuthURL, http.StatusFound)
+}
+
+func (uh *UserHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	req := &model.OAuthCallbackRequest{
+		Provider: chi.URLParam(r, "provider"),
+		Code:     r.URL.Query().Get("code"),
+		State:    r.URL.Query().Get("state"),
+		Error:    r.URL.Query().Get("error"),
+	}
+
+	if cookie, err := r.Cookie(oauthSessionCookie); err == nil {
+		req.Session = cookie.Value
+	}
+
+	// the session is single use whatever the outcome
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthSessionCookie,
+		Path:     "/auth/oauth/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	reqCtx := r.Context()
+
+	token, err := uh.userUC.OAuthCallback(reqCtx, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrUnknownOAuthProvider):
+			response.FailedResponse(w, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, customError.ErrInvalidOAuthState):
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, customError.ErrOAuthDenied),
+			errors.Is(err, customError.ErrOAuthEmailNotVerified),
+			errors.Is(err, customError.ErrUserBanned):
+			response.FailedResponse(w, http.StatusForbidden, err.Error())
+			return
+		case errors.Is(err, customError.ErrEmailExist):
+			response.FailedResponse(w, http.StatusConflict, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if token.TwoFactorRequired {
+		response.SuccessResponse(w, http.StatusOK, "two-factor code required", token)
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully login to account", token)
+}
+
+func (uh *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req *model.RefreshTokenRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqCtx := r.Context()
+
+	token, err := uh.userUC.RefreshToken(reqCtx, req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrInvalidRefreshToken),
+			errors.Is(err, customError.ErrRefreshTokenReused):
+			response.FailedResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		case errors.Is(err, customError.ErrUserBanned):
+			response.FailedResponse(w, http.StatusForbidden, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully refresh token", token)
+}
+
+func (uh *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	// the refresh token is optional, without it only the access token is revoked
+	var req model.RefreshTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	jti, expiresAt, err := util.GetTokenFromContext(r)
+	if err != nil {
+		response.FailedResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	userId, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	err = uh.userUC.Logout(reqCtx, jti, expiresAt, req.RefreshToken, userId)
+	if err != nil {
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully logout", nil)
+}
+
+// VerifyEmail accepts the token either from the link query string or from a
+// JSON body.
+func (uh *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req model.VerifyEmailRequest
+
+	req.Token = r.URL.Query().Get("token")
+	if req.Token == "" && r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	reqCtx := r.Context()
+
+	err := uh.userUC.VerifyEmail(reqCtx, req.Token)
+	if err != nil {
+		if errors.Is(err, customError.ErrInvalidToken) {
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully verify email", nil)
+}
+
+func (uh *UserHandler) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	var req *model.ResendVerificationRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqCtx := r.Context()
+
+	err := uh.userUC.ResendVerificationEmail(reqCtx, req.Email)
+	if err != nil {
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "if the account exists and is not verified yet, a verification email has been sent", nil)
+}
+
+func (uh *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req *model.ForgotPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqCtx := r.Context()
+
+	err := uh.userUC.ForgotPassword(reqCtx, req.Email)
+	if err != nil {
+		response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "if the account exists, a password reset email has been sent", nil)
+}
+
+func (uh *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req *model.ResetPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqCtx := r.Context()
+
+	err := uh.userUC.ResetPassword(reqCtx, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrInvalidToken),
+			errors.Is(err, customError.ErrPasswordNotMatch):
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully reset password", nil)
+}
+
 func (uh *UserHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	userId, err := util.GetUserIdFromContext(w,r)
 	if err != nil {
@@ -157,4 +452,235 @@ func (uh *UserHandler) UpdateUserData(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response.SuccessResponse(w, http.StatusOK, "Successfully update user Data", updatedUser)
-}
\ No newline at end of file
+}
+
+func (uh *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req *model.ChangePasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	token, err := uh.userUC.ChangePassword(reqCtx, req, userId)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrPasswordNotMatch):
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, customError.ErrIncorrectPassword):
+			response.FailedResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully change password", token)
+}
+
+func (uh *UserHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var req *model.ChangeEmailRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	user, err := uh.userUC.ChangeEmail(reqCtx, req, userId)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrIncorrectPassword):
+			response.FailedResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		case errors.Is(err, customError.ErrEmailExist):
+			response.FailedResponse(w, http.StatusConflict, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusAccepted, "a confirmation link has been sent to the new email", user)
+}
+
+func (uh *UserHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req model.ConfirmEmailChangeRequest
+
+	req.Token = r.URL.Query().Get("token")
+	if req.Token == "" && r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	reqCtx := r.Context()
+
+	user, err := uh.userUC.ConfirmEmailChange(reqCtx, req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrInvalidToken):
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, customError.ErrEmailExist):
+			response.FailedResponse(w, http.StatusConflict, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully change email", user)
+}
+
+func (uh *UserHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req *model.EnrollTwoFactorRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	enrollment, err := uh.userUC.EnrollTwoFactor(reqCtx, req, userId)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrIncorrectPassword):
+			response.FailedResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		case errors.Is(err, customError.ErrTwoFactorAlreadyEnabled):
+			response.FailedResponse(w, http.StatusConflict, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "scan the qr code and confirm with a code from your authenticator app", enrollment)
+}
+
+func (uh *UserHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req *model.ConfirmTwoFactorRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	recoveryCodes, err := uh.userUC.ConfirmTwoFactor(reqCtx, req, userId)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrInvalidTwoFactorCode),
+			errors.Is(err, customError.ErrTwoFactorNotEnrolled):
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, customError.ErrTwoFactorAlreadyEnabled):
+			response.FailedResponse(w, http.StatusConflict, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully enable two-factor authentication", recoveryCodes)
+}
+
+func (uh *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req *model.DisableTwoFactorRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	err = uh.userUC.DisableTwoFactor(reqCtx, req, userId)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrIncorrectPassword),
+			errors.Is(err, customError.ErrInvalidTwoFactorCode):
+			response.FailedResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		case errors.Is(err, customError.ErrTwoFactorNotEnabled):
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully disable two-factor authentication", nil)
+}
+
+func (uh *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req *model.TwoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.FailedResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId, err := util.GetUserIdFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	reqCtx := r.Context()
+
+	recoveryCodes, err := uh.userUC.RegenerateRecoveryCodes(reqCtx, req, userId)
+	if err != nil {
+		switch {
+		case errors.Is(err, customError.ErrInvalidTwoFactorCode):
+			response.FailedResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		case errors.Is(err, customError.ErrTwoFactorNotEnabled):
+			response.FailedResponse(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			response.FailedResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "successfully regenerate recovery codes", recoveryCodes)
+}
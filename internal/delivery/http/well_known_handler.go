@@ -0,0 +1,32 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/federicodosantos/socialize/pkg/jwt"
+	"github.com/go-chi/chi/v5"
+)
+
+type WellKnownHandler struct {
+	jwt jwt.JWTItf
+}
+
+func NewWellKnownHandler(jwt jwt.JWTItf) *WellKnownHandler {
+	return &WellKnownHandler{jwt: jwt}
+}
+
+func WellKnownRoutes(router *chi.Mux, wellKnownHandler *WellKnownHandler) {
+	// public routes
+	router.Get("/.well-known/jwks.json", wellKnownHandler.JWKS)
+}
+
+// JWKS is consumed by other services to verify our tokens, so it is written
+// as a plain JWK Set instead of the usual response envelope.
+func (h *WellKnownHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(h.jwt.JWKS())
+}
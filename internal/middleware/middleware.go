@@ -3,62 +3,128 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/federicodosantos/socialize/internal/repository"
 	customContext "github.com/federicodosantos/socialize/pkg/context"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
 	"github.com/federicodosantos/socialize/pkg/jwt"
+	"github.com/federicodosantos/socialize/pkg/rbac"
 	response "github.com/federicodosantos/socialize/pkg/response"
 	"go.uber.org/zap"
 )
 
 type MiddlewareItf interface {
 	JwtAuthMiddleware(next http.Handler) http.Handler
+	QueryTokenMiddleware(next http.Handler) http.Handler
+	RequirePermission(perms ...rbac.Permission) func(next http.Handler) http.Handler
 	LoggingMiddleware(next http.Handler) http.Handler
 }
 
 type Middleware struct {
-	jwt    jwt.JWTItf
-	logger *zap.SugaredLogger
+	jwt       jwt.JWTItf
+	tokenRepo repository.TokenRepoItf
+	logger    *zap.SugaredLogger
 }
 
-func NewMiddleware(jwt jwt.JWTItf, logger *zap.SugaredLogger) MiddlewareItf {
-	return &Middleware{jwt: jwt, logger: logger}
+func NewMiddleware(jwt jwt.JWTItf, tokenRepo repository.TokenRepoItf, logger *zap.SugaredLogger) MiddlewareItf {
+	return &Middleware{jwt: jwt, tokenRepo: tokenRepo, logger: logger}
 }
 
 func (m *Middleware) JwtAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		
 		bearerToken := r.Header.Get("Authorization")
 
-		if bearerToken == "" {
+		token, found := strings.CutPrefix(bearerToken, "Bearer ")
+		if !found || token == "" {
 			response.FailedResponse(w, http.StatusUnauthorized, "Authorization token is required")
 			return
 		}
 
-		token := strings.Split(bearerToken, " ")[1]
-
-		userID, err := m.jwt.VerifyToken(token)
+		claims, err := m.jwt.VerifyToken(token)
 		if err != nil {
 			response.FailedResponse(w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
-		// Set userID in context
-		ctx := context.WithValue(r.Context(), customContext.UserIDKey, userID)
+		// tokens without an id or expiry cannot be revoked, so they are refused
+		if claims.ID == "" || claims.ExpiresAt == nil || claims.IssuedAt == nil {
+			response.FailedResponse(w, http.StatusUnauthorized, "token is missing required claims")
+			return
+		}
+
+		revoked, err := m.tokenRepo.IsAccessTokenRevoked(r.Context(), claims.ID, claims.UserID, claims.IssuedAt.Time)
+		if err != nil {
+			m.logger.Errorw("cannot check token revocation", "error", err)
+			response.FailedResponse(w, http.StatusInternalServerError, "cannot verify token")
+			return
+		}
+
+		if revoked {
+			response.FailedResponse(w, http.StatusUnauthorized, customError.ErrTokenRevoked.Error())
+			return
+		}
+
+		// Set userID and token identity in context
+		// tokens issued before roles existed carry no role
+		role := rbac.Role(claims.Role)
+		if role == "" {
+			role = rbac.RoleUser
+		}
+
+		ctx := context.WithValue(r.Context(), customContext.UserIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, customContext.UserRoleKey, role)
+		ctx = context.WithValue(ctx, customContext.TokenIDKey, claims.ID)
+		ctx = context.WithValue(ctx, customContext.TokenExpiresAtKey, claims.ExpiresAt.Time)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// QueryTokenMiddleware lets the access token come in the access_token query
+// parameter, for clients such as EventSource and browser websockets that
+// cannot set headers. A token in the Authorization header wins. It must run
+// before JwtAuthMiddleware.
+func (m *Middleware) QueryTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("access_token")
+
+		if token != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequirePermission refuses requests whose token role lacks any of perms. It
+// must run after JwtAuthMiddleware.
+func (m *Middleware) RequirePermission(perms ...rbac.Permission) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := r.Context().Value(customContext.UserRoleKey).(rbac.Role)
+			if !ok || !role.Can(perms...) {
+				response.FailedResponse(w, http.StatusForbidden, customError.ErrPermissionDenied.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (m *Middleware) LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		requestURL := redactURL(r.URL)
 
 		// Log informasi request
 		m.logger.Infow("Incoming request",
 			"method", r.Method,
-			"url", r.URL.String(),
+			"url", requestURL,
 			"remote_addr", r.RemoteAddr,
 		)
 
@@ -71,13 +137,29 @@ func (m *Middleware) LoggingMiddleware(next http.Handler) http.Handler {
 		duration := time.Since(start)
 		m.logger.Infow("Request processed",
 			"method", r.Method,
-			"url", r.URL.String(),
+			"url", requestURL,
 			"duration", duration,
 			"status", rr.statusCode,
 		)
 	})
 }
 
+// redactURL hides the access token a URL may carry so it does not end up in
+// the logs.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	if !query.Has("access_token") {
+		return u.String()
+	}
+
+	query.Set("access_token", "REDACTED")
+
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
+
 type responseRecorder struct {
 	http.ResponseWriter
 	statusCode int
@@ -87,3 +169,9 @@ func (rr *responseRecorder) WriteHeader(code int) {
 	rr.statusCode = code
 	rr.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap gives http.ResponseController access to the wrapped writer, which
+// streaming responses need to flush and websockets to hijack.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
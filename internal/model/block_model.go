@@ -0,0 +1,32 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RestrictedUser is a user blocked or muted by another one.
+type RestrictedUser struct {
+	EntryID int64          `db:"entry_id"`
+	ID      int64          `db:"id"`
+	Name    string         `db:"name"`
+	Photo   sql.NullString `db:"photo"`
+	Since   time.Time      `db:"since"`
+}
+
+type RestrictedUserResponse struct {
+	ID    int64     `json:"id"`
+	Name  string    `json:"name"`
+	Photo string    `json:"photo"`
+	Since time.Time `json:"since"`
+}
+
+type RestrictionFilter struct {
+	Limit  int
+	Cursor *RestrictionCursor
+}
+
+// RestrictionCursor is the position of the last block or mute of a page.
+type RestrictionCursor struct {
+	ID int64 `json:"id"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// MaxCollectionLength is the longest name a bookmark collection can have.
+const MaxCollectionLength = 100
+
+// BookmarkedPost is a post saved by a user, with where and when it was saved.
+type BookmarkedPost struct {
+	Post
+	BookmarkID   int64     `db:"bookmark_id"`
+	Collection   string    `db:"collection"`
+	BookmarkedAt time.Time `db:"bookmarked_at"`
+}
+
+type BookmarkResponse struct {
+	Collection   string        `json:"collection,omitempty"`
+	BookmarkedAt time.Time     `json:"bookmarked_at"`
+	Post         *PostResponse `json:"post"`
+}
+
+// BookmarkRequest is the optional body of a bookmark, a post saved without a
+// collection stays out of every collection.
+type BookmarkRequest struct {
+	Collection string `json:"collection"`
+}
+
+// BookmarkCollection is a collection of a user and how many posts it holds.
+type BookmarkCollection struct {
+	Name  string `json:"name" db:"name"`
+	Count int64  `json:"count" db:"count"`
+}
+
+type BookmarkFilter struct {
+	Limit  int
+	Cursor *BookmarkCursor
+	// Collection limits the list to one collection when set
+	Collection string
+}
+
+// BookmarkCursor is the position of the last bookmark of a page.
+type BookmarkCursor struct {
+	ID int64 `json:"id"`
+}
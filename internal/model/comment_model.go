@@ -6,26 +6,96 @@ import (
 )
 
 type Comment struct {
-	ID        int64     		`db:"id"`
-	PostID    int64     		`db:"post_id"`
-	UserID    int64     		`db:"user_id"`
-	Comment   string    		`db:"comment"`
-	UserName  string    		`db:"user_name"`   
-	UserPhoto sql.NullString    `db:"user_photo"`  
-	CreatedAt time.Time 		`db:"created_at"`
+	ID         int64          `db:"id"`
+	PostID     int64          `db:"post_id"`
+	UserID     int64          `db:"user_id"`
+	Comment    string         `db:"comment"`
+	UserName   string         `db:"user_name"`
+	UserPhoto  sql.NullString `db:"user_photo"`
+	CreatedAt  time.Time      `db:"created_at"`
+	EditedAt   sql.NullTime   `db:"edited_at"`
+	ParentID   sql.NullInt64  `db:"parent_id"`
+	ReplyCount int64          `db:"reply_count"`
+	// DeletedAt is set on the tombstone of a deleted comment that had replies
+	DeletedAt sql.NullTime `db:"deleted_at"`
+	UpVote    int64        `db:"up_vote"`
+	DownVote  int64        `db:"down_vote"`
 }
 
 type CommentCreate struct {
-	PostID  int64  `json:"post_id"`
+	PostID int64 `json:"post_id"`
+	// ParentID is the comment replied to, zero for a top level comment
+	ParentID int64  `json:"parent_id,omitempty"`
+	Comment  string `json:"comment"`
+}
+
+// CommentUpdate holds the new text of an edited comment.
+type CommentUpdate struct {
 	Comment string `json:"comment"`
 }
 
 type CommentResponse struct {
-	ID        int64     `json:"id"`
-	PostID    int64     `json:"post_id"`
-	UserID    int64     `json:"user_id"`
-	Comment   string    `json:"comment"`
-	UserName  string    `json:"user_name"`   
-	UserPhoto string    `json:"user_photo"`  
-	CreatedAt time.Time `json:"created_at"`
-}
\ No newline at end of file
+	ID         int64              `json:"id"`
+	PostID     int64              `json:"post_id"`
+	UserID     int64              `json:"user_id"`
+	Comment    string             `json:"comment"`
+	UserName   string             `json:"user_name"`
+	UserPhoto  string             `json:"user_photo"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Edited     bool               `json:"edited"`
+	ParentID   int64              `json:"parent_id,omitempty"`
+	Deleted    bool               `json:"deleted,omitempty"`
+	ReplyCount int64              `json:"reply_count"`
+	Replies    []*CommentResponse `json:"replies,omitempty"`
+	// RepliesCursor loads the replies after the last one in Replies, it is
+	// only set when some of the loaded replies were left out
+	RepliesCursor string           `json:"replies_cursor,omitempty"`
+	UpVote        int64            `json:"up_vote"`
+	DownVote      int64            `json:"down_vote"`
+	Reactions     map[string]int64 `json:"reactions,omitempty"`
+	// MyReaction is the reaction of the caller to the comment
+	MyReaction string `json:"my_reaction,omitempty"`
+}
+
+type CommentFilter struct {
+	Limit  int
+	Cursor *CommentCursor
+	// ViewerID is the user the listing is shown to
+	ViewerID int64
+}
+
+// CommentCursor is the position after the last comment of a page.
+type CommentCursor struct {
+	ID int64 `json:"id"`
+}
+
+// CommentTreeFilter selects a page of the comments of one level of a thread
+// and how much of the replies below them is loaded along.
+type CommentTreeFilter struct {
+	// ParentID is the comment whose replies are listed, zero for the top
+	// level comments of the post
+	ParentID int64
+	Limit    int
+	Cursor   *CommentCursor
+	// Depth is how many levels are loaded, counting the listed level
+	Depth int
+	// ReplyLimit is how many replies are loaded below each comment
+	ReplyLimit int
+	// ViewerID is the user the thread is shown to
+	ViewerID int64
+}
+
+type CommentRevision struct {
+	ID         int64     `db:"id"`
+	CommentID  int64     `db:"comment_id"`
+	Comment    string    `db:"comment"`
+	CreatedAt  time.Time `db:"created_at"`
+	ReplacedAt time.Time `db:"replaced_at"`
+}
+
+type CommentRevisionResponse struct {
+	ID         int64     `json:"id"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
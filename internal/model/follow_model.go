@@ -0,0 +1,48 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FollowUser is a user listed among the followers or the followings of
+// another one. IsFollowing and FollowsYou are relative to the viewer of the
+// list.
+type FollowUser struct {
+	FollowID    int64          `db:"follow_id"`
+	ID          int64          `db:"id"`
+	Name        string         `db:"name"`
+	Photo       sql.NullString `db:"photo"`
+	FollowedAt  time.Time      `db:"followed_at"`
+	IsFollowing bool           `db:"is_following"`
+	FollowsYou  bool           `db:"follows_you"`
+}
+
+type FollowUserResponse struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Photo      string    `json:"photo"`
+	FollowedAt time.Time `json:"followed_at"`
+	FollowStatus
+}
+
+// FollowStatus is the relationship between the viewer and another user.
+type FollowStatus struct {
+	// IsFollowing tells whether the viewer follows the user
+	IsFollowing bool `json:"is_following" db:"is_following"`
+	// FollowsYou tells whether the user follows the viewer
+	FollowsYou bool `json:"follows_you" db:"follows_you"`
+	Mutual     bool `json:"mutual" db:"-"`
+}
+
+type FollowFilter struct {
+	Limit  int
+	Cursor *FollowCursor
+	// ViewerID is the user the list is shown to
+	ViewerID int64
+}
+
+// FollowCursor is the position of the last follow of a page.
+type FollowCursor struct {
+	ID int64 `json:"id"`
+}
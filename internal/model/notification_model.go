@@ -0,0 +1,118 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Notification types, they are also the categories a user can turn off.
+const (
+	// NotificationComment is a comment on a post of the user
+	NotificationComment = "comment"
+	// NotificationReply is a reply to a comment of the user
+	NotificationReply = "reply"
+	// NotificationVote is an up vote on a post or a comment of the user
+	NotificationVote = "vote"
+	// NotificationFollow is a new follower of the user
+	NotificationFollow = "follow"
+	// NotificationMention is a mention of the user in a post or a comment
+	NotificationMention = "mention"
+)
+
+// NotificationTypes lists every notification type.
+var NotificationTypes = []string{
+	NotificationComment,
+	NotificationReply,
+	NotificationVote,
+	NotificationFollow,
+	NotificationMention,
+}
+
+// Notification targets, the kinds of rows a notification is about.
+const (
+	NotificationTargetPost    = "post"
+	NotificationTargetComment = "comment"
+	NotificationTargetUser    = "user"
+)
+
+// NotificationEvent is something that happened to a user. The events of the
+// same type on the same target are gathered in one notification until it is
+// read.
+type NotificationEvent struct {
+	UserID     int64
+	ActorID    int64
+	Type       string
+	TargetType string
+	TargetID   int64
+	// PostID is the post the target belongs to, it is zero for follows
+	PostID int64
+}
+
+type Notification struct {
+	ID         int64          `db:"id"`
+	Type       string         `db:"type"`
+	TargetType string         `db:"target_type"`
+	TargetID   int64          `db:"target_id"`
+	PostID     sql.NullInt64  `db:"post_id"`
+	ActorCount int64          `db:"actor_count"`
+	ActorID    int64          `db:"actor_id"`
+	ActorName  string         `db:"actor_name"`
+	ActorPhoto sql.NullString `db:"actor_photo"`
+	ReadAt     sql.NullTime   `db:"read_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at"`
+}
+
+type NotificationActor struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Photo string `json:"photo"`
+}
+
+type NotificationResponse struct {
+	ID         int64  `json:"id"`
+	Type       string `json:"type"`
+	TargetType string `json:"target_type"`
+	TargetID   int64  `json:"target_id"`
+	PostID     int64  `json:"post_id,omitempty"`
+	// Actor is the last user behind the notification, ActorCount counts them
+	// all
+	Actor      NotificationActor `json:"actor"`
+	ActorCount int64             `json:"actor_count"`
+	Message    string            `json:"message"`
+	Read       bool              `json:"read"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// NotificationPage is a page of notifications with the unread count of the
+// user.
+type NotificationPage struct {
+	UnreadCount   int64                   `json:"unread_count"`
+	Notifications []*NotificationResponse `json:"notifications"`
+}
+
+type UnreadCount struct {
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// NotificationPreference tells whether a user gets the notifications of a
+// type.
+type NotificationPreference struct {
+	Type    string `db:"type"`
+	Enabled bool   `db:"enabled"`
+}
+
+type NotificationFilter struct {
+	Limit  int
+	Cursor *NotificationCursor
+	// UnreadOnly leaves out the notifications already read
+	UnreadOnly bool
+}
+
+// NotificationCursor is the position of the last notification of a page.
+// Notifications come by their last event so the cursor holds its time.
+type NotificationCursor struct {
+	UpdatedAt int64 `json:"t"`
+	ID        int64 `json:"id"`
+}
@@ -6,17 +6,42 @@ import (
 )
 
 type Post struct {
-	ID        int64          `db:"id"`
-	Title     string         `db:"title"`
-	Content   string         `db:"content"`
-	UserID    int64          `db:"user_id"`
-	UserName  string	 	 `db:"user_name"`
-	UserPhoto sql.NullString `db:"user_photo"`
-	Image     sql.NullString `db:"image"`
-	CreatedAt time.Time      `db:"created_at"`
-	UpdatedAt time.Time      `db:"updated_at"`
-	UpVote    int64          `db:"up_vote"`
-	DownVote  int64          `db:"down_vote"`
+	ID           int64          `db:"id"`
+	Title        string         `db:"title"`
+	Content      string         `db:"content"`
+	UserID       int64          `db:"user_id"`
+	UserName     string         `db:"user_name"`
+	UserPhoto    sql.NullString `db:"user_photo"`
+	Image        sql.NullString `db:"image"`
+	CreatedAt    time.Time      `db:"created_at"`
+	UpdatedAt    time.Time      `db:"updated_at"`
+	EditedAt     sql.NullTime   `db:"edited_at"`
+	UpVote       int64          `db:"up_vote"`
+	DownVote     int64          `db:"down_vote"`
+	CommentCount int64          `db:"comment_count"`
+	Score        float64        `db:"score"`
+	// MyVote, IsOwner and Bookmarked are relative to the user the post is
+	// shown to
+	MyVote     int64 `db:"my_vote"`
+	IsOwner    bool  `db:"is_owner"`
+	Bookmarked bool  `db:"bookmarked"`
+	// SortKey is the value the listing was ordered by, it is only set by
+	// GetAllPost
+	SortKey float64 `db:"sort_key"`
+}
+
+// PostStats are the counters the ranking score of a post is computed from.
+type PostStats struct {
+	ID           int64     `db:"id"`
+	UpVote       int64     `db:"up_vote"`
+	DownVote     int64     `db:"down_vote"`
+	CommentCount int64     `db:"comment_count"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+type PostScore struct {
+	ID    int64   `db:"id"`
+	Score float64 `db:"score"`
 }
 
 type PostCreate struct {
@@ -26,20 +51,93 @@ type PostCreate struct {
 }
 
 type PostResponse struct {
-	ID        int64     		 `json:"id"`
-	Title     string    		 `json:"title"`
-	Content   string    		 `json:"content"`
-	UserID    int64     		 `json:"user_id"`
-	UserName  string			 `json:"user_name"`
-	UserPhoto string	 		 `json:"user_photo"`
-	Image     string    		 `json:"image"`
+	ID        int64              `json:"id"`
+	Title     string             `json:"title"`
+	Content   string             `json:"content"`
+	UserID    int64              `json:"user_id"`
+	UserName  string             `json:"user_name"`
+	UserPhoto string             `json:"user_photo"`
+	Image     string             `json:"image"`
 	Comment   []*CommentResponse `json:"comment,omitempty"`
-	UpVote    int64     		 `json:"up_vote"`
-	DownVote  int64     		 `json:"down_vote"`
-	CreatedAt time.Time 		 `json:"created_at"`
-	UpdatedAt time.Time 		 `json:"updated_at"`
+	// CommentsCursor loads the top level comments after the ones in Comment
+	CommentsCursor string `json:"comments_cursor,omitempty"`
+	UpVote         int64  `json:"up_vote"`
+	DownVote       int64  `json:"down_vote"`
+	CommentCount   int64  `json:"comment_count"`
+	// MyVote is the vote of the caller on the post, 1, -1 or 0 if none
+	MyVote  int64 `json:"my_vote"`
+	IsOwner bool  `json:"is_owner"`
+	// Bookmarked tells whether the caller saved the post
+	Bookmarked bool             `json:"bookmarked"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	Edited     bool             `json:"edited"`
+	Reactions  map[string]int64 `json:"reactions,omitempty"`
+	// MyReaction is the reaction of the caller to the post
+	MyReaction string `json:"my_reaction,omitempty"`
+}
+
+// PostUpdate holds the fields of a post edit, empty fields are left unchanged.
+type PostUpdate struct {
+	Title   string `json:"title,omitempty"`
+	Content string `json:"content,omitempty"`
+	Image   string `json:"image,omitempty"`
+	// RemoveImage clears the image, Image is then ignored
+	RemoveImage bool `json:"remove_image,omitempty"`
+}
+
+type PostRevision struct {
+	ID         int64          `db:"id"`
+	PostID     int64          `db:"post_id"`
+	Title      string         `db:"title"`
+	Content    string         `db:"content"`
+	Image      sql.NullString `db:"image"`
+	CreatedAt  time.Time      `db:"created_at"`
+	ReplacedAt time.Time      `db:"replaced_at"`
+}
+
+type PostRevisionResponse struct {
+	ID         int64     `json:"id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Image      string    `json:"image"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
 }
 
+const (
+	PostSortNew = "new"
+	PostSortTop = "top"
+	PostSortHot = "hot"
+)
+
 type PostFilter struct {
 	Keyword string `json:"keyword"`
+	Sort    string
+	// Since limits the listing to posts created after it, it is set from
+	// Window for the first page and carried by the cursor after that so the
+	// window does not move while paging.
+	Window time.Duration
+	Since  time.Time
+	Limit  int
+	Cursor *PostCursor
+	// ViewerID is the user the listing is shown to, the posts of users
+	// blocked by them or who blocked them are left out
+	ViewerID int64
+	// ExcludeMuted leaves out the posts of the users muted by the viewer
+	ExcludeMuted bool
+	// RankedAt is the time a hot listing with a time dependent score is
+	// ranked as of, it is carried by the cursor like Since so the posts do
+	// not move between pages.
+	RankedAt time.Time
+}
+
+// PostCursor is the position of the last post of a page.
+type PostCursor struct {
+	Sort  string  `json:"s"`
+	Score float64 `json:"sc,omitempty"`
+	ID    int64   `json:"id"`
+	Since int64   `json:"t,omitempty"`
+	// RankedAt is the Unix time of PostFilter.RankedAt
+	RankedAt int64 `json:"r,omitempty"`
 }
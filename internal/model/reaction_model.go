@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Reaction targets, the kinds of rows a reaction can be left on.
+const (
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+)
+
+type Reaction struct {
+	UserID     int64     `db:"user_id"`
+	TargetType string    `db:"target_type"`
+	TargetID   int64     `db:"target_id"`
+	Reaction   string    `db:"reaction"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// ReactionCount is how many times one reaction was left on a target.
+type ReactionCount struct {
+	TargetID int64  `db:"target_id"`
+	Reaction string `db:"reaction"`
+	Count    int64  `db:"count"`
+}
+
+type ReactionRequest struct {
+	Reaction string `json:"reaction"`
+}
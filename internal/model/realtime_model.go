@@ -0,0 +1,29 @@
+package model
+
+// RealtimeFilter is what a client subscribes to, the events of the user
+// themselves are always included.
+type RealtimeFilter struct {
+	PostIDs []int64
+	// LastEventID resumes a subscription after the last event the client got
+	LastEventID string
+}
+
+// VoteUpdate carries the vote counts of a post, or of one of its comments
+// when CommentID is set, after a vote changed them.
+type VoteUpdate struct {
+	PostID    int64 `json:"post_id"`
+	CommentID int64 `json:"comment_id,omitempty"`
+	UpVote    int64 `json:"up_vote"`
+	DownVote  int64 `json:"down_vote"`
+}
+
+// NotificationUpdate tells a user a notification was added or gathered a new
+// event, with their unread count after it.
+type NotificationUpdate struct {
+	Type        string `json:"type"`
+	TargetType  string `json:"target_type"`
+	TargetID    int64  `json:"target_id"`
+	PostID      int64  `json:"post_id,omitempty"`
+	ActorID     int64  `json:"actor_id"`
+	UnreadCount int64  `json:"unread_count"`
+}
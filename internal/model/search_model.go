@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+const (
+	SearchTypePost    = "post"
+	SearchTypeComment = "comment"
+	SearchTypeUser    = "user"
+)
+
+// SearchDocument is what a search index stores of a post, comment or user.
+// Users keep their name in Title.
+type SearchDocument struct {
+	Type       string    `db:"type"`
+	ID         int64     `db:"id"`
+	PostID     int64     `db:"post_id"`
+	AuthorID   int64     `db:"author_id"`
+	AuthorName string    `db:"author_name"`
+	Title      string    `db:"title"`
+	Body       string    `db:"body"`
+	HasImage   bool      `db:"has_image"`
+	Score      int64     `db:"score"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// SearchQuery holds the text and filters of a search. HasImage and MinScore
+// only apply to posts, the score of a post being its net votes.
+type SearchQuery struct {
+	Text     string
+	Type     string
+	AuthorID int64
+	From     time.Time
+	To       time.Time
+	HasImage *bool
+	MinScore *int64
+	Limit    int
+	Offset   int
+	// ViewerID is the user searching, ExcludeAuthorIDs is filled from it
+	// with the users blocked either way whose documents are left out
+	ViewerID         int64
+	ExcludeAuthorIDs []int64
+}
+
+// SearchCursor is the position of the next page of a search. Results are
+// ordered by relevance which is not stable enough for a keyset, so the cursor
+// carries an offset.
+type SearchCursor struct {
+	Offset int `json:"o"`
+}
+
+type SearchHit struct {
+	Document  *SearchDocument
+	Relevance float64
+}
+
+type SearchHitResponse struct {
+	Type       string    `json:"type"`
+	ID         int64     `json:"id"`
+	PostID     int64     `json:"post_id,omitempty"`
+	AuthorID   int64     `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	Title      string    `json:"title,omitempty"`
+	Snippet    string    `json:"snippet"`
+	Relevance  float64   `json:"relevance"`
+	CreatedAt  time.Time `json:"created_at"`
+}
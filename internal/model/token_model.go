@@ -0,0 +1,67 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RefreshToken struct {
+	ID        int64        `db:"id"`
+	UserID    int64        `db:"user_id"`
+	FamilyID  string       `db:"family_id"`
+	TokenHash string       `db:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	RevokedAt sql.NullTime `db:"revoked_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailChange       = "email_change"
+	TokenPurposeTwoFactorLogin    = "two_factor_login"
+	TokenPurposeOAuthState        = "oauth_state"
+)
+
+// UserToken is a single use token mailed to a user, such as an email
+// verification link. Only the hash of the token is stored.
+type UserToken struct {
+	ID        int64        `db:"id"`
+	UserID    int64        `db:"user_id"`
+	Purpose   string       `db:"purpose"`
+	TokenHash string       `db:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the user
+// lost their authenticator. Only the hash of the code is stored.
+type RecoveryCode struct {
+	ID        int64        `db:"id"`
+	UserID    int64        `db:"user_id"`
+	CodeHash  string       `db:"code_hash"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// LoginResponse holds either the token pair or, for accounts with two-factor
+// authentication, the challenge to exchange at /auth/login/2fa.
+type LoginResponse struct {
+	*TokenResponse
+	TwoFactorRequired  bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken     string `json:"challenge_token,omitempty"`
+	ChallengeExpiresIn int64  `json:"challenge_expires_in,omitempty"`
+}
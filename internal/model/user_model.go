@@ -3,16 +3,26 @@ package model
 import (
 	"database/sql"
 	"time"
+
+	"github.com/federicodosantos/socialize/pkg/rbac"
 )
 
 type User struct {
-	ID        int64          `db:"id"`
-	Name      string         `db:"name"`
-	Email     string         `db:"email"`
-	Password  string         `db:"password"`
-	Photo     sql.NullString `db:"photo"`
-	CreatedAt time.Time      `db:"created_at"`
-	UpdatedAt time.Time      `db:"updated_at"`
+	ID                int64          `db:"id"`
+	Name              string         `db:"name"`
+	Email             string         `db:"email"`
+	Password          string         `db:"password"`
+	Photo             sql.NullString `db:"photo"`
+	PendingEmail      sql.NullString `db:"pending_email"`
+	VerifiedAt        sql.NullTime   `db:"verified_at"`
+	SessionsRevokedAt sql.NullTime   `db:"sessions_revoked_at"`
+	TOTPSecret        sql.NullString `db:"totp_secret"`
+	TOTPEnabledAt     sql.NullTime   `db:"totp_enabled_at"`
+	TOTPLastStep      sql.NullInt64  `db:"totp_last_step"`
+	Role              string         `db:"role"`
+	BannedAt          sql.NullTime   `db:"banned_at"`
+	CreatedAt         time.Time      `db:"created_at"`
+	UpdatedAt         time.Time      `db:"updated_at"`
 }
 
 type UserRegister struct {
@@ -29,9 +39,22 @@ type UserLogin struct {
 }
 
 type UserUpdateData struct {
-	Name     string `json:"name,omitempty"`
-	Email    string `json:"email,omitempty"`
-	Password string `json:"password,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+type ChangeEmailRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewEmail        string `json:"new_email"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token"`
 }
 
 type UserUpdatePhoto struct {
@@ -39,10 +62,158 @@ type UserUpdatePhoto struct {
 }
 
 type UserResponse struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Photo     string    `json:"photo"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	PendingEmail string    `json:"pending_email,omitempty"`
+	Photo        string    `json:"photo"`
+	Verified     bool      `json:"verified"`
+	TwoFactor    bool      `json:"two_factor_enabled"`
+	Role         string    `json:"role"`
+	Banned       bool      `json:"banned,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// UserProfile is the public view of a user with their activity totals.
+type UserProfile struct {
+	ID           int64          `db:"id"`
+	Name         string         `db:"name"`
+	Photo        sql.NullString `db:"photo"`
+	Role         string         `db:"role"`
+	BannedAt     sql.NullTime   `db:"banned_at"`
+	CreatedAt    time.Time      `db:"created_at"`
+	PostCount    int64          `db:"post_count"`
+	CommentCount int64          `db:"comment_count"`
+	// Karma is the sum of the net votes of the posts of the user.
+	Karma          int64 `db:"karma"`
+	FollowerCount  int64 `db:"follower_count"`
+	FollowingCount int64 `db:"following_count"`
+}
+
+// UserProfileResponse is what anyone can see of a user, it leaves out the
+// email and the account security settings.
+type UserProfileResponse struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`
+	Photo          string    `json:"photo"`
+	Role           string    `json:"role"`
+	Banned         bool      `json:"banned,omitempty"`
+	PostCount      int64     `json:"post_count"`
+	CommentCount   int64     `json:"comment_count"`
+	Karma          int64     `json:"karma"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+	JoinedAt       time.Time `json:"joined_at"`
+	// Follow is the relationship of the viewer with the user, it is left out
+	// on the profile of the viewer
+	Follow *FollowStatus `json:"follow,omitempty"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token           string `json:"token"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+type EnrollTwoFactorRequest struct {
+	CurrentPassword string `json:"current_password"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	// QRCode is a data URI of a PNG image encoding OTPAuthURI.
+	QRCode string `json:"qr_code"`
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorCodeRequest carries the second factor, either a TOTP code from the
+// authenticator app or one of the recovery codes.
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type DisableTwoFactorRequest struct {
+	CurrentPassword string `json:"current_password"`
+	TwoFactorCodeRequest
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	TwoFactorCodeRequest
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// UserIdentity links a user to their account at an external OpenID Connect
+// provider.
+type UserIdentity struct {
+	ID        int64          `db:"id"`
+	UserID    int64          `db:"user_id"`
+	Provider  string         `db:"provider"`
+	Subject   string         `db:"subject"`
+	Email     sql.NullString `db:"email"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+// OAuthSession is what the start of an OAuth login needs to remember until
+// the provider redirects back. It travels signed in a cookie.
+type OAuthSession struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
+type OAuthAuthorization struct {
+	AuthURL string
+	// Session is the signed OAuthSession to hand back on the callback.
+	Session   string
+	ExpiresIn time.Duration
+}
+
+type OAuthCallbackRequest struct {
+	Provider string
+	Code     string
+	State    string
+	Session  string
+	// Error is set when the provider redirects back without a code, for
+	// example because the user denied access.
+	Error string
+}
+
+// Actor is the authenticated user performing a request, as carried by their
+// access token.
+type Actor struct {
+	UserID int64
+	Role   rbac.Role
+}
+
+type UserFilter struct {
+	Limit  int
+	Offset int
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
 }
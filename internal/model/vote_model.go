@@ -0,0 +1,16 @@
+package model
+
+// VoteCount is the vote counters of a post or a comment.
+type VoteCount struct {
+	UpVote   int64 `db:"up_vote"`
+	DownVote int64 `db:"down_vote"`
+}
+
+// VoteResponse is the outcome of a vote change.
+type VoteResponse struct {
+	UpVote   int64 `json:"up_vote"`
+	DownVote int64 `json:"down_vote"`
+	// MyVote is the vote of the caller after the change, 1, -1 or 0 when
+	// they have no vote.
+	MyVote int64 `json:"my_vote"`
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/federicodosantos/socialize/internal/model"
+)
+
+// deleteBatchSize is how many comments are removed at once when their post is
+// deleted.
+const deleteBatchSize = 500
+
+// BleveSearchIndex keeps an embedded Bleve index on disk next to the
+// database. It has to be filled once from the database and then kept in sync
+// through Index and Delete.
+type BleveSearchIndex struct {
+	index bleve.Index
+}
+
+// NewBleveSearchIndex opens the index at path, creating it when it does not
+// exist yet.
+func NewBleveSearchIndex(path string) (*BleveSearchIndex, error) {
+	index, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(path, newSearchMapping())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot open search index: %w", err)
+	}
+
+	return &BleveSearchIndex{index: index}, nil
+}
+
+func newSearchMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = en.AnalyzerName
+
+	keyword := bleve.NewKeywordFieldMapping()
+	numeric := bleve.NewNumericFieldMapping()
+	boolean := bleve.NewBooleanFieldMapping()
+	date := bleve.NewDateTimeFieldMapping()
+
+	document := bleve.NewDocumentStaticMapping()
+	document.AddFieldMappingsAt("type", keyword)
+	document.AddFieldMappingsAt("id", numeric)
+	document.AddFieldMappingsAt("post_id", numeric)
+	document.AddFieldMappingsAt("author_id", numeric)
+	document.AddFieldMappingsAt("author_name", text)
+	document.AddFieldMappingsAt("title", text)
+	document.AddFieldMappingsAt("body", text)
+	document.AddFieldMappingsAt("has_image", boolean)
+	document.AddFieldMappingsAt("score", numeric)
+	document.AddFieldMappingsAt("created_at", date)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = document
+
+	return indexMapping
+}
+
+func searchDocumentID(docType string, id int64) string {
+	return fmt.Sprintf("%s:%d", docType, id)
+}
+
+func (s *BleveSearchIndex) Index(ctx context.Context, docs ...*model.SearchDocument) error {
+	batch := s.index.NewBatch()
+
+	for _, doc := range docs {
+		err := batch.Index(searchDocumentID(doc.Type, doc.ID), map[string]any{
+			"type":        doc.Type,
+			"id":          float64(doc.ID),
+			"post_id":     float64(doc.PostID),
+			"author_id":   float64(doc.AuthorID),
+			"author_name": doc.AuthorName,
+			"title":       doc.Title,
+			"body":        doc.Body,
+			"has_image":   doc.HasImage,
+			"score":       float64(doc.Score),
+			"created_at":  doc.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.index.Batch(batch)
+}
+
+func (s *BleveSearchIndex) Delete(ctx context.Context, docType string, id int64) error {
+	if err := s.index.Delete(searchDocumentID(docType, id)); err != nil {
+		return err
+	}
+
+	if docType != model.SearchTypePost {
+		return nil
+	}
+
+	// the comments of the post went with it
+	postID := float64(id)
+	inclusive := true
+
+	byPost := bleve.NewNumericRangeInclusiveQuery(&postID, &postID, &inclusive, &inclusive)
+	byPost.SetField("post_id")
+
+	comments := bleve.NewConjunctionQuery(termQuery("type", model.SearchTypeComment), byPost)
+
+	for {
+		result, err := s.index.SearchInContext(ctx, bleve.NewSearchRequestOptions(comments, deleteBatchSize, 0, false))
+		if err != nil {
+			return err
+		}
+
+		if len(result.Hits) == 0 {
+			return nil
+		}
+
+		batch := s.index.NewBatch()
+		for _, hit := range result.Hits {
+			batch.Delete(hit.ID)
+		}
+
+		if err := s.index.Batch(batch); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *BleveSearchIndex) Search(ctx context.Context, q model.SearchQuery) ([]*model.SearchHit, error) {
+	title := bleve.NewMatchQuery(q.Text)
+	title.SetField("title")
+
+	body := bleve.NewMatchQuery(q.Text)
+	body.SetField("body")
+
+	conjuncts := []query.Query{
+		bleve.NewDisjunctionQuery(title, body),
+		termQuery("type", q.Type),
+	}
+
+	inclusive := true
+
+	if q.AuthorID != 0 {
+		authorID := float64(q.AuthorID)
+
+		author := bleve.NewNumericRangeInclusiveQuery(&authorID, &authorID, &inclusive, &inclusive)
+		author.SetField("author_id")
+		conjuncts = append(conjuncts, author)
+	}
+
+	if !q.From.IsZero() || !q.To.IsZero() {
+		exclusive := false
+
+		createdAt := bleve.NewDateRangeInclusiveQuery(q.From, q.To, &inclusive, &exclusive)
+		createdAt.SetField("created_at")
+		conjuncts = append(conjuncts, createdAt)
+	}
+
+	if q.Type == model.SearchTypePost {
+		if q.HasImage != nil {
+			hasImage := bleve.NewBoolFieldQuery(*q.HasImage)
+			hasImage.SetField("has_image")
+			conjuncts = append(conjuncts, hasImage)
+		}
+
+		if q.MinScore != nil {
+			minScore := float64(*q.MinScore)
+
+			score := bleve.NewNumericRangeInclusiveQuery(&minScore, nil, &inclusive, nil)
+			score.SetField("score")
+			conjuncts = append(conjuncts, score)
+		}
+	}
+
+	searchQuery := bleve.NewBooleanQuery()
+	searchQuery.AddMust(conjuncts...)
+
+	for _, id := range q.ExcludeAuthorIDs {
+		authorID := float64(id)
+
+		author := bleve.NewNumericRangeInclusiveQuery(&authorID, &authorID, &inclusive, &inclusive)
+		author.SetField("author_id")
+		searchQuery.AddMustNot(author)
+	}
+
+	req := bleve.NewSearchRequestOptions(searchQuery, q.Limit, q.Offset, false)
+	req.Fields = []string{"*"}
+	req.SortBy([]string{"-_score", "-_id"})
+
+	result, err := s.index.SearchInContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]*model.SearchHit, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		hits = append(hits, &model.SearchHit{
+			Document:  documentFromFields(hit.Fields),
+			Relevance: hit.Score,
+		})
+	}
+
+	return hits, nil
+}
+
+func (s *BleveSearchIndex) SyncRequired() bool {
+	return true
+}
+
+// Close releases the files of the index.
+func (s *BleveSearchIndex) Close() error {
+	return s.index.Close()
+}
+
+func termQuery(field, term string) query.Query {
+	q := bleve.NewTermQuery(term)
+	q.SetField(field)
+
+	return q
+}
+
+func documentFromFields(fields map[string]any) *model.SearchDocument {
+	number := func(name string) int64 {
+		value, _ := fields[name].(float64)
+		return int64(value)
+	}
+
+	text := func(name string) string {
+		value, _ := fields[name].(string)
+		return value
+	}
+
+	hasImage, _ := fields["has_image"].(bool)
+	createdAt, _ := time.Parse(time.RFC3339, text("created_at"))
+
+	return &model.SearchDocument{
+		Type:       text("type"),
+		ID:         number("id"),
+		PostID:     number("post_id"),
+		AuthorID:   number("author_id"),
+		AuthorName: text("author_name"),
+		Title:      text("title"),
+		Body:       text("body"),
+		HasImage:   hasImage,
+		Score:      number("score"),
+		CreatedAt:  createdAt,
+	}
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+type BlockRepoItf interface {
+	Block(ctx context.Context, blockerID int64, blockedID int64) error
+	Unblock(ctx context.Context, blockerID int64, blockedID int64) error
+	Mute(ctx context.Context, muterID int64, mutedID int64) error
+	Unmute(ctx context.Context, muterID int64, mutedID int64) error
+	IsBlocked(ctx context.Context, userID int64, otherID int64) (bool, error)
+	GetBlockedUserIDs(ctx context.Context, userID int64) ([]int64, error)
+	GetBlockedUsers(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUser, error)
+	GetMutedUsers(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUser, error)
+}
+
+type BlockRepo struct {
+	db *sqlx.DB
+}
+
+func NewBlockRepo(db *sqlx.DB) BlockRepoItf {
+	return &BlockRepo{db: db}
+}
+
+// notBlocked is the condition leaving out the rows of users who blocked the
+// viewer or were blocked by them. It takes the id of the viewer twice.
+func notBlocked(userColumn string) string {
+	return `NOT EXISTS (SELECT 1 FROM blocks WHERE (blocker_id = ? AND blocked_id = ` + userColumn + `)
+		OR (blocker_id = ` + userColumn + ` AND blocked_id = ?))`
+}
+
+// notMuted is the condition leaving out the rows of users muted by the
+// viewer. It takes the id of the viewer.
+func notMuted(userColumn string) string {
+	return `NOT EXISTS (SELECT 1 FROM mutes WHERE muter_id = ? AND muted_id = ` + userColumn + `)`
+}
+
+// Block implements BlockRepoItf. Blocking a user also ends the follows
+// between the two users.
+func (r *BlockRepo) Block(ctx context.Context, blockerID int64, blockedID int64) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT IGNORE INTO blocks (blocker_id, blocked_id) VALUES (?, ?)`, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	DELETE FROM follows
+	WHERE (follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)`,
+		blockerID, blockedID, blockedID, blockerID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+
+	return err
+}
+
+// Unblock implements BlockRepoItf.
+func (r *BlockRepo) Unblock(ctx context.Context, blockerID int64, blockedID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+	DELETE FROM blocks WHERE blocker_id = ? AND blocked_id = ?`, blockerID, blockedID)
+
+	return err
+}
+
+// Mute implements BlockRepoItf.
+func (r *BlockRepo) Mute(ctx context.Context, muterID int64, mutedID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+	INSERT IGNORE INTO mutes (muter_id, muted_id) VALUES (?, ?)`, muterID, mutedID)
+
+	return err
+}
+
+// Unmute implements BlockRepoItf.
+func (r *BlockRepo) Unmute(ctx context.Context, muterID int64, mutedID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+	DELETE FROM mutes WHERE muter_id = ? AND muted_id = ?`, muterID, mutedID)
+
+	return err
+}
+
+// IsBlocked implements BlockRepoItf. It reports whether one of the users
+// blocked the other.
+func (r *BlockRepo) IsBlocked(ctx context.Context, userID int64, otherID int64) (bool, error) {
+	var blocked bool
+
+	err := r.db.GetContext(ctx, &blocked, `
+	SELECT EXISTS(
+		SELECT 1 FROM blocks
+		WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)
+	)`, userID, otherID, otherID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return blocked, nil
+}
+
+// GetBlockedUserIDs implements BlockRepoItf. It returns the users the user
+// blocked and the ones who blocked them.
+func (r *BlockRepo) GetBlockedUserIDs(ctx context.Context, userID int64) ([]int64, error) {
+	var ids []int64
+
+	err := r.db.SelectContext(ctx, &ids, `
+	SELECT blocked_id FROM blocks WHERE blocker_id = ?
+	UNION
+	SELECT blocker_id FROM blocks WHERE blocked_id = ?`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetBlockedUsers implements BlockRepoItf. The most recent blocks come
+// first.
+func (r *BlockRepo) GetBlockedUsers(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUser, error) {
+	return r.listRestrictions(ctx, `
+	SELECT b.id AS entry_id, u.id, u.name, u.photo, b.created_at AS since
+	FROM blocks AS b
+	JOIN users AS u ON u.id = b.blocked_id`, `b`, `b.blocker_id = ?`, userID, filter)
+}
+
+// GetMutedUsers implements BlockRepoItf. The most recent mutes come first.
+func (r *BlockRepo) GetMutedUsers(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUser, error) {
+	return r.listRestrictions(ctx, `
+	SELECT m.id AS entry_id, u.id, u.name, u.photo, m.created_at AS since
+	FROM mutes AS m
+	JOIN users AS u ON u.id = m.muted_id`, `m`, `m.muter_id = ?`, userID, filter)
+}
+
+func (r *BlockRepo) listRestrictions(ctx context.Context, base string, alias string, scope string,
+	userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUser, error) {
+	var users []*model.RestrictedUser
+
+	qb := newQueryBuilder(base).Where(scope, userID)
+
+	if c := filter.Cursor; c != nil {
+		qb.Where(alias+`.id < ?`, c.ID)
+	}
+
+	qb.Suffix(`ORDER BY ` + alias + `.id DESC`)
+
+	if filter.Limit > 0 {
+		qb.Suffix(`LIMIT ?`, filter.Limit)
+	}
+
+	query, args := qb.Build()
+
+	if err := r.db.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+type BookmarkRepoItf interface {
+	SetBookmark(ctx context.Context, userID int64, postID int64, collection string) error
+	DeleteBookmark(ctx context.Context, userID int64, postID int64) error
+	GetBookmarks(ctx context.Context, userID int64, filter model.BookmarkFilter) ([]*model.BookmarkedPost, error)
+	GetCollections(ctx context.Context, userID int64) ([]*model.BookmarkCollection, error)
+}
+
+type BookmarkRepo struct {
+	db *sqlx.DB
+}
+
+func NewBookmarkRepo(db *sqlx.DB) BookmarkRepoItf {
+	return &BookmarkRepo{db: db}
+}
+
+// SetBookmark implements BookmarkRepoItf. Bookmarking a saved post again
+// moves it to the given collection.
+func (r *BookmarkRepo) SetBookmark(ctx context.Context, userID int64, postID int64, collection string) error {
+	_, err := r.db.ExecContext(ctx, `
+	INSERT INTO bookmarks (user_id, post_id, collection) VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE collection = VALUES(collection)`, userID, postID, collection)
+
+	return err
+}
+
+// DeleteBookmark implements BookmarkRepoItf.
+func (r *BookmarkRepo) DeleteBookmark(ctx context.Context, userID int64, postID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+	DELETE FROM bookmarks WHERE user_id = ? AND post_id = ?`, userID, postID)
+
+	return err
+}
+
+// selectBookmarkQuery lists posts through the bookmarks b, it takes the id
+// of the viewer three times like selectPostQuery.
+const selectBookmarkQuery = `SELECT` + postColumns + viewerPostColumns + `,
+		b.id AS bookmark_id,
+		b.collection,
+		b.created_at AS bookmarked_at
+	FROM bookmarks AS b
+	JOIN posts AS p ON p.id = b.post_id
+	JOIN users AS u ON u.id = p.user_id` + viewerPostJoin
+
+// GetBookmarks implements BookmarkRepoItf. The most recent bookmarks come
+// first and the posts of users blocked either way are left out.
+func (r *BookmarkRepo) GetBookmarks(ctx context.Context, userID int64, filter model.BookmarkFilter) ([]*model.BookmarkedPost, error) {
+	var posts []*model.BookmarkedPost
+
+	qb := newQueryBuilder(selectBookmarkQuery, userID, userID, userID).
+		Where(`b.user_id = ?`, userID)
+
+	if filter.Collection != "" {
+		qb.Where(`b.collection = ?`, filter.Collection)
+	}
+
+	if c := filter.Cursor; c != nil {
+		qb.Where(`b.id < ?`, c.ID)
+	}
+
+	qb.Where(notBlocked(`p.user_id`), userID, userID).
+		Suffix(`ORDER BY b.id DESC`)
+
+	if filter.Limit > 0 {
+		qb.Suffix(`LIMIT ?`, filter.Limit)
+	}
+
+	query, args := qb.Build()
+
+	if err := r.db.SelectContext(ctx, &posts, query, args...); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// GetCollections implements BookmarkRepoItf. Collections come by name and
+// only count the posts GetBookmarks would list.
+func (r *BookmarkRepo) GetCollections(ctx context.Context, userID int64) ([]*model.BookmarkCollection, error) {
+	var collections []*model.BookmarkCollection
+
+	query, args := newQueryBuilder(`
+	SELECT b.collection AS name, COUNT(*) AS count
+	FROM bookmarks AS b
+	JOIN posts AS p ON p.id = b.post_id`).
+		Where(`b.user_id = ?`, userID).
+		Where(`b.collection <> ''`).
+		Where(notBlocked(`p.user_id`), userID, userID).
+		Suffix(`GROUP BY b.collection ORDER BY b.collection`).
+		Build()
+
+	if err := r.db.SelectContext(ctx, &collections, query, args...); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
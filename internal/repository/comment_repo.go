@@ -2,7 +2,11 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/federicodosantos/socialize/internal/model"
 	customerror "github.com/federicodosantos/socialize/pkg/custom-error"
@@ -12,8 +16,16 @@ import (
 
 type CommentRepoItf interface {
 	CreateComment(ctx context.Context, comment *model.Comment) error
-	GetAllCommentsByPostId(ctx context.Context, postId int64) ([]*model.Comment, error)
+	GetCommentThread(ctx context.Context, postID int64, filter model.CommentTreeFilter) ([]*model.Comment, error)
+	GetRepliesBatch(ctx context.Context, parentIDs []int64, limit int, viewerID int64) ([]*model.Comment, error)
+	GetCommentByID(ctx context.Context, id int64) (*model.Comment, error)
+	GetCommentsByUserID(ctx context.Context, userID int64, filter model.CommentFilter) ([]*model.Comment, error)
+	UpdateComment(ctx context.Context, comment *model.Comment) error
+	GetCommentRevisions(ctx context.Context, commentID int64) ([]*model.CommentRevision, error)
 	DeleteComment(ctx context.Context, id int64) error
+
+	SetVote(ctx context.Context, commentID int64, userID int64, vote int64) (*model.VoteCount, error)
+	DeleteVote(ctx context.Context, commentID int64, userID int64) (*model.VoteCount, error)
 }
 
 type CommentRepo struct {
@@ -24,75 +36,520 @@ func NewCommentRepo(db *sqlx.DB) CommentRepoItf {
 	return &CommentRepo{db: db}
 }
 
+const selectCommentQuery = `
+	SELECT
+		c.id,
+		c.post_id,
+		c.user_id,
+		c.comment,
+		c.created_at,
+		c.edited_at,
+		c.parent_id,
+		c.reply_count,
+		c.deleted_at,
+		c.up_vote,
+		c.down_vote,
+		u.name AS user_name,
+		u.photo AS user_photo
+	FROM comments AS c
+	JOIN users AS u ON u.id = c.user_id`
+
 func (r *CommentRepo) CreateComment(ctx context.Context, comment *model.Comment) error {
-	createdAtStr := util.ConvertTimeToString(comment.CreatedAt)
-	
-	createCommentQuery := fmt.Sprintf(
-		`INSERT INTO comments(user_id, post_id, comment, created_at)
-		VALUES(%d, %d, '%s', '%s')`, comment.UserID, comment.PostID, comment.Comment, createdAtStr)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
+
+	createCommentQuery := `INSERT INTO comments(user_id, post_id, parent_id, comment, created_at)
+		VALUES(:user_id, :post_id, :parent_id, :comment, :created_at)`
 
-	res, err := r.db.ExecContext(ctx, createCommentQuery)	
+	res, err := tx.NamedExecContext(ctx, createCommentQuery, comment)
 	if err != nil {
 		return err
 	}
 
 	rows, err := res.RowsAffected()
 	if err != nil {
-		return customerror.ErrRowsAffected
+		err = customerror.ErrRowsAffected
+		return err
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		return customerror.ErrLastInsertId
+		err = customerror.ErrLastInsertId
+		return err
+	}
+
+	if err = util.ErrRowsAffected(rows); err != nil {
+		return err
+	}
+
+	if comment.ParentID.Valid {
+		if err = refreshReplyCount(ctx, tx, comment.ParentID.Int64); err != nil {
+			return err
+		}
+	}
+
+	if err = refreshCommentCount(ctx, tx, comment.PostID); err != nil {
+		return err
 	}
 
-	util.ErrRowsAffected(rows)
+	if err = tx.Commit(); err != nil {
+		return err
+	}
 
 	comment.ID = id
 
 	return nil
 }
 
-func (r *CommentRepo) GetAllCommentsByPostId(ctx context.Context, postId int64) ([]*model.Comment, error) {
+// refreshCommentCount recounts the comments of the post in the transaction
+// that changed them. Tombstones are not counted.
+func refreshCommentCount(ctx context.Context, tx *sqlx.Tx, postID int64) error {
+	_, err := tx.ExecContext(ctx, `
+	UPDATE posts SET comment_count = (
+		SELECT COUNT(*) FROM comments WHERE post_id = ? AND deleted_at IS NULL
+	) WHERE id = ?`, postID, postID)
+
+	return err
+}
+
+// refreshReplyCount recounts the direct replies of the comment, tombstones
+// included since they are still shown in the thread.
+func refreshReplyCount(ctx context.Context, tx *sqlx.Tx, commentID int64) error {
+	_, err := tx.ExecContext(ctx, `
+	UPDATE comments AS c
+	JOIN (SELECT COUNT(*) AS replies FROM comments WHERE parent_id = ?) AS r
+	SET c.reply_count = r.replies
+	WHERE c.id = ?`, commentID, commentID)
+
+	return err
+}
+
+// GetCommentThread returns a page of one level of the comments of the post,
+// oldest first. A zero ParentID lists the top level comments.
+func (r *CommentRepo) GetCommentThread(ctx context.Context, postID int64, filter model.CommentTreeFilter) ([]*model.Comment, error) {
 	var comments []*model.Comment
 
-	getAllCommentsByPostIdQUery := fmt.Sprintf(`
-	SELECT 
-		c.id,
-		c.post_id,
-		c.user_id,
-		c.comment,
-		c.created_at,
-		u.name AS user_name,      
-		u.photo AS user_photo     
-	FROM comments AS c
-	JOIN users AS u ON u.id = c.user_id
-	WHERE c.post_id = %d`, postId)
+	qb := newQueryBuilder(selectCommentQuery).Where(`c.post_id = ?`, postID)
+
+	if filter.ParentID == 0 {
+		qb.Where(`c.parent_id IS NULL`)
+	} else {
+		qb.Where(`c.parent_id = ?`, filter.ParentID)
+	}
+
+	if c := filter.Cursor; c != nil {
+		qb.Where(`c.id > ?`, c.ID)
+	}
+
+	// a tombstone has no author to hide, it stays to keep its replies
+	if filter.ViewerID != 0 {
+		qb.Where(`(c.deleted_at IS NOT NULL OR `+notBlocked(`c.user_id`)+`)`, filter.ViewerID, filter.ViewerID)
+	}
+
+	qb.Suffix(`ORDER BY c.id`)
+
+	if filter.Limit > 0 {
+		qb.Suffix(`LIMIT ?`, filter.Limit)
+	}
+
+	query, args := qb.Build()
+
+	if err := r.db.SelectContext(ctx, &comments, query, args...); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
 
-	err := r.db.SelectContext(ctx, &comments, getAllCommentsByPostIdQUery)
+// GetRepliesBatch returns the first limit replies of each of the parents,
+// oldest first, so one query loads a whole level of a thread. Replies of
+// users blocked by the viewer or who blocked them are left out.
+func (r *CommentRepo) GetRepliesBatch(ctx context.Context, parentIDs []int64, limit int, viewerID int64) ([]*model.Comment, error) {
+	var comments []*model.Comment
+
+	if len(parentIDs) == 0 {
+		return comments, nil
+	}
+
+	query, args, err := sqlx.In(`
+	SELECT * FROM (
+		SELECT
+			c.id,
+			c.post_id,
+			c.user_id,
+			c.comment,
+			c.created_at,
+			c.edited_at,
+			c.parent_id,
+			c.reply_count,
+			c.deleted_at,
+			c.up_vote,
+			c.down_vote,
+			u.name AS user_name,
+			u.photo AS user_photo,
+			ROW_NUMBER() OVER (PARTITION BY c.parent_id ORDER BY c.id) AS position
+		FROM comments AS c
+		JOIN users AS u ON u.id = c.user_id
+		WHERE c.parent_id IN (?)
+		AND (c.deleted_at IS NOT NULL OR `+notBlocked(`c.user_id`)+`)
+	) AS replies
+	WHERE position <= ?
+	ORDER BY parent_id, id`, parentIDs, viewerID, viewerID, limit)
 	if err != nil {
 		return nil, err
 	}
 
+	var rows []struct {
+		model.Comment
+		Position int64 `db:"position"`
+	}
+
+	if err := r.db.SelectContext(ctx, &rows, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		comments = append(comments, &rows[i].Comment)
+	}
+
 	return comments, nil
 }
 
-func (r *CommentRepo) DeleteComment(ctx context.Context, id int64) error {
-	deleteCommentQuery := fmt.Sprintf(`DELETE FROM comments where id = %d`, id)
+func (r *CommentRepo) GetCommentByID(ctx context.Context, id int64) (*model.Comment, error) {
+	var comment model.Comment
+
+	err := r.db.GetContext(ctx, &comment, selectCommentQuery+`
+	WHERE c.id = ?`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("comment %d: %w", id, customerror.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// GetCommentsByUserID returns a page of the comments written by the user,
+// newest first.
+func (r *CommentRepo) GetCommentsByUserID(ctx context.Context, userID int64, filter model.CommentFilter) ([]*model.Comment, error) {
+	var comments []*model.Comment
+
+	qb := newQueryBuilder(selectCommentQuery).
+		Where(`c.user_id = ?`, userID).
+		Where(`c.deleted_at IS NULL`)
+
+	if c := filter.Cursor; c != nil {
+		qb.Where(`c.id < ?`, c.ID)
+	}
+
+	qb.Suffix(`ORDER BY c.id DESC`)
+
+	if filter.Limit > 0 {
+		qb.Suffix(`LIMIT ?`, filter.Limit)
+	}
+
+	query, args := qb.Build()
+
+	if err := r.db.SelectContext(ctx, &comments, query, args...); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// GetCommentRevisions returns the previous versions of the comment, newest
+// first.
+func (r *CommentRepo) GetCommentRevisions(ctx context.Context, commentID int64) ([]*model.CommentRevision, error) {
+	var revisions []*model.CommentRevision
+
+	err := r.db.SelectContext(ctx, &revisions, `
+	SELECT id, comment_id, comment, created_at, replaced_at
+	FROM comment_revisions
+	WHERE comment_id = ?
+	ORDER BY id DESC`, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// UpdateComment saves an edit of the comment. The text being replaced is
+// copied to comment_revisions in the same transaction.
+func (r *CommentRepo) UpdateComment(ctx context.Context, comment *model.Comment) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
 
-	res, err := r.db.ExecContext(ctx, deleteCommentQuery)
+	res, err := tx.ExecContext(ctx, `
+	INSERT INTO comment_revisions (comment_id, comment, created_at, replaced_at)
+	SELECT id, comment, COALESCE(edited_at, created_at), ?
+	FROM comments WHERE id = ?`, comment.EditedAt.Time, comment.ID)
 	if err != nil {
 		return err
 	}
 
 	rows, err := res.RowsAffected()
 	if err != nil {
-		return customerror.ErrRowsAffected
+		err = customerror.ErrRowsAffected
+		return err
 	}
 
-	util.ErrRowsAffected(rows)
+	if rows == 0 {
+		err = fmt.Errorf("comment %d: %w", comment.ID, customerror.ErrNotFound)
+		return err
+	}
 
-	return nil
+	_, err = tx.NamedExecContext(ctx, `
+	UPDATE comments SET comment = :comment, edited_at = :edited_at WHERE id = :id`, comment)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+
+	return err
+}
+
+// DeleteComment removes a comment. A comment with replies is turned into a
+// tombstone so the replies keep their place, and tombstones left without any
+// reply by the deletion are removed along.
+func (r *CommentRepo) DeleteComment(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
+
+	var comment model.Comment
+	err = tx.GetContext(ctx, &comment, `
+	SELECT id, post_id, parent_id, reply_count, deleted_at FROM comments WHERE id = ? FOR UPDATE`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("comment %d: %w", id, customerror.ErrNotFound)
+		}
+		return err
+	}
+
+	if comment.DeletedAt.Valid {
+		err = fmt.Errorf("comment %d: %w", id, customerror.ErrNotFound)
+		return err
+	}
+
+	if comment.ReplyCount > 0 {
+		err = tombstoneComment(ctx, tx, id)
+	} else {
+		err = removeCommentBranch(ctx, tx, &comment)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = refreshCommentCount(ctx, tx, comment.PostID); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+
+	return err
+}
+
+// tombstoneComment clears the text of the comment, its history, its reactions
+// and its notifications, only the position of the comment in the thread is
+// kept.
+func tombstoneComment(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, `
+	UPDATE comments SET comment = '', deleted_at = ? WHERE id = ?`, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM comment_revisions WHERE comment_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteCommentReactions(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return deleteCommentNotifications(ctx, tx, id)
+}
+
+// deleteCommentReactions removes the reactions left on the comment, they have
+// no foreign key to cascade with.
+func deleteCommentReactions(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, `
+	DELETE FROM reactions WHERE target_type = ? AND target_id = ?`, model.ReactionTargetComment, id)
+
+	return err
 }
 
+// deleteCommentNotifications removes the notifications about the comment and
+// their actors, so no notification points at a deleted comment.
+func deleteCommentNotifications(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, `
+	DELETE a FROM notification_actors AS a
+	JOIN notifications AS n ON n.id = a.notification_id
+	WHERE n.target_type = ? AND n.target_id = ?`, model.NotificationTargetComment, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	DELETE FROM notifications WHERE target_type = ? AND target_id = ?`, model.NotificationTargetComment, id)
+
+	return err
+}
+
+// removeCommentBranch deletes a comment without replies, then walks up its
+// ancestors deleting the tombstones that have no reply left.
+func removeCommentBranch(ctx context.Context, tx *sqlx.Tx, comment *model.Comment) error {
+	for {
+		if err := deleteCommentReactions(ctx, tx, comment.ID); err != nil {
+			return err
+		}
+
+		if err := deleteCommentNotifications(ctx, tx, comment.ID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM comments WHERE id = ?`, comment.ID); err != nil {
+			return err
+		}
+
+		if !comment.ParentID.Valid {
+			return nil
+		}
+
+		parentID := comment.ParentID.Int64
+
+		if err := refreshReplyCount(ctx, tx, parentID); err != nil {
+			return err
+		}
+
+		var parent model.Comment
+		err := tx.GetContext(ctx, &parent, `
+		SELECT id, post_id, parent_id, reply_count, deleted_at FROM comments WHERE id = ? FOR UPDATE`, parentID)
+		if err != nil {
+			return err
+		}
 
+		if !parent.DeletedAt.Valid || parent.ReplyCount > 0 {
+			return nil
+		}
+
+		comment = &parent
+	}
+}
+
+// SetVote records the vote of the user on the comment, replacing their
+// previous vote, and returns the new counters.
+func (r *CommentRepo) SetVote(ctx context.Context, commentID int64, userID int64, vote int64) (*model.VoteCount, error) {
+	return r.changeVote(ctx, commentID, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+		INSERT INTO comment_votes (comment_id, user_id, vote) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE vote = VALUES(vote)`, commentID, userID, vote)
+		if err != nil {
+			return fmt.Errorf("failed to set comment vote: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteVote removes the vote of the user on the comment, if any, and
+// returns the new counters.
+func (r *CommentRepo) DeleteVote(ctx context.Context, commentID int64, userID int64) (*model.VoteCount, error) {
+	return r.changeVote(ctx, commentID, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM comment_votes WHERE comment_id = ? AND user_id = ?`, commentID, userID)
+
+		return err
+	})
+}
+
+// changeVote applies change to the votes of the comment and recounts them in
+// one transaction, holding the lock of the comment row like
+// PostRepo.changeVote. Tombstones cannot be voted on.
+func (r *CommentRepo) changeVote(ctx context.Context, commentID int64, change func(tx *sqlx.Tx) error) (*model.VoteCount, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
+
+	var id int64
+	err = tx.GetContext(ctx, &id, `
+	SELECT id FROM comments WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, commentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("comment %d: %w", commentID, customerror.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	if err = change(tx); err != nil {
+		return nil, err
+	}
+
+	if err = refreshCommentVoteCounts(ctx, tx, commentID); err != nil {
+		return nil, err
+	}
+
+	var count model.VoteCount
+	if err = tx.GetContext(ctx, &count, `SELECT up_vote, down_vote FROM comments WHERE id = ?`, commentID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &count, nil
+}
+
+// refreshCommentVoteCounts recounts the votes of the comment in the
+// transaction that changed them.
+func refreshCommentVoteCounts(ctx context.Context, tx *sqlx.Tx, commentID int64) error {
+	_, err := tx.ExecContext(ctx, `
+	UPDATE comments SET
+		up_vote = (SELECT COUNT(*) FROM comment_votes WHERE comment_id = ? AND vote = 1),
+		down_vote = (SELECT COUNT(*) FROM comment_votes WHERE comment_id = ? AND vote = -1)
+	WHERE id = ?`, commentID, commentID, commentID)
+
+	return err
+}
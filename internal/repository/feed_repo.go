@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// FeedSource builds the home feed of a user out of the posts of the accounts
+// they follow. The feed is paged like GetAllPost.
+//
+// The fan-out-on-read source below looks the posts up on every read. A
+// fan-out-on-write source would copy each post to a timeline table of every
+// follower of its author from AddPost and page that table instead, leaving
+// the accounts with too many followers to be merged on read.
+type FeedSource interface {
+	GetFeed(ctx context.Context, userID int64, filter model.PostFilter) ([]*model.Post, error)
+	// AddPost is called once a post is written so it can be pushed to the
+	// feeds of the followers of its author.
+	AddPost(ctx context.Context, post *model.Post) error
+}
+
+type fanOutOnReadFeed struct {
+	posts *PostRepo
+}
+
+func NewFanOutOnReadFeed(db *sqlx.DB) FeedSource {
+	return &fanOutOnReadFeed{posts: &PostRepo{db: db}}
+}
+
+// GetFeed implements FeedSource.
+func (f *fanOutOnReadFeed) GetFeed(ctx context.Context, userID int64, filter model.PostFilter) ([]*model.Post, error) {
+	return f.posts.listPosts(ctx, filter,
+		`p.user_id IN (SELECT followee_id FROM follows WHERE follower_id = ?)`, userID)
+}
+
+// AddPost implements FeedSource. The post is found on read, there is nothing
+// to push.
+func (f *fanOutOnReadFeed) AddPost(ctx context.Context, post *model.Post) error {
+	return nil
+}
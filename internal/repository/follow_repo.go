@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+type FollowRepoItf interface {
+	Follow(ctx context.Context, followerID int64, followeeID int64) error
+	Unfollow(ctx context.Context, followerID int64, followeeID int64) error
+	GetFollowStatus(ctx context.Context, viewerID int64, userID int64) (*model.FollowStatus, error)
+	GetFollowers(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUser, error)
+	GetFollowing(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUser, error)
+}
+
+type FollowRepo struct {
+	db *sqlx.DB
+}
+
+func NewFollowRepo(db *sqlx.DB) FollowRepoItf {
+	return &FollowRepo{db: db}
+}
+
+// Follow implements FollowRepoItf. Following a user twice keeps the first
+// follow.
+func (r *FollowRepo) Follow(ctx context.Context, followerID int64, followeeID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+	INSERT IGNORE INTO follows (follower_id, followee_id) VALUES (?, ?)`, followerID, followeeID)
+
+	return err
+}
+
+// Unfollow implements FollowRepoItf.
+func (r *FollowRepo) Unfollow(ctx context.Context, followerID int64, followeeID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+	DELETE FROM follows WHERE follower_id = ? AND followee_id = ?`, followerID, followeeID)
+
+	return err
+}
+
+// GetFollowStatus implements FollowRepoItf.
+func (r *FollowRepo) GetFollowStatus(ctx context.Context, viewerID int64, userID int64) (*model.FollowStatus, error) {
+	var status model.FollowStatus
+
+	err := r.db.GetContext(ctx, &status, `
+	SELECT
+		EXISTS(SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = ?) AS is_following,
+		EXISTS(SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = ?) AS follows_you`,
+		viewerID, userID, userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// followUserColumns are the columns of a user listed through the follow f,
+// the status columns take the id of the viewer twice.
+const followUserColumns = `
+	SELECT
+		f.id AS follow_id,
+		u.id,
+		u.name,
+		u.photo,
+		f.created_at AS followed_at,
+		EXISTS(SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = u.id) AS is_following,
+		EXISTS(SELECT 1 FROM follows WHERE follower_id = u.id AND followee_id = ?) AS follows_you
+	FROM follows AS f`
+
+// GetFollowers implements FollowRepoItf. Followers come by follow date,
+// newest first.
+func (r *FollowRepo) GetFollowers(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUser, error) {
+	return r.listFollows(ctx, `JOIN users AS u ON u.id = f.follower_id`, `f.followee_id = ?`, userID, filter)
+}
+
+// GetFollowing implements FollowRepoItf. The followed users come by follow
+// date, newest first.
+func (r *FollowRepo) GetFollowing(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUser, error) {
+	return r.listFollows(ctx, `JOIN users AS u ON u.id = f.followee_id`, `f.follower_id = ?`, userID, filter)
+}
+
+func (r *FollowRepo) listFollows(ctx context.Context, join string, scope string, userID int64, filter model.FollowFilter) ([]*model.FollowUser, error) {
+	var users []*model.FollowUser
+
+	qb := newQueryBuilder(followUserColumns+`
+	`+join, filter.ViewerID, filter.ViewerID).
+		Where(scope, userID)
+
+	if c := filter.Cursor; c != nil {
+		qb.Where(`f.id < ?`, c.ID)
+	}
+
+	if filter.ViewerID != 0 {
+		qb.Where(notBlocked(`u.id`), filter.ViewerID, filter.ViewerID)
+	}
+
+	qb.Suffix(`ORDER BY f.id DESC`)
+
+	if filter.Limit > 0 {
+		qb.Suffix(`LIMIT ?`, filter.Limit)
+	}
+
+	query, args := qb.Build()
+
+	if err := r.db.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
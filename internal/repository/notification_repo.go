@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/jmoiron/sqlx"
+)
+
+type NotificationRepoItf interface {
+	AddEvent(ctx context.Context, event *model.NotificationEvent) error
+	GetNotifications(ctx context.Context, userID int64, filter model.NotificationFilter) ([]*model.Notification, error)
+	CountUnread(ctx context.Context, userID int64) (int64, error)
+	MarkRead(ctx context.Context, userID int64, notificationID int64) error
+	MarkAllRead(ctx context.Context, userID int64) error
+
+	IsEnabled(ctx context.Context, userID int64, notificationType string) (bool, error)
+	GetPreferences(ctx context.Context, userID int64) ([]*model.NotificationPreference, error)
+	SetPreferences(ctx context.Context, userID int64, prefs []model.NotificationPreference) error
+}
+
+type NotificationRepo struct {
+	db *sqlx.DB
+}
+
+func NewNotificationRepo(db *sqlx.DB) NotificationRepoItf {
+	return &NotificationRepo{db: db}
+}
+
+// AddEvent implements NotificationRepoItf. The event joins the unread
+// notification of the same type and target when there is one, the actor is
+// only counted once per notification.
+func (r *NotificationRepo) AddEvent(ctx context.Context, event *model.NotificationEvent) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
+
+	postID := &event.PostID
+	if event.PostID == 0 {
+		postID = nil
+	}
+
+	// LAST_INSERT_ID(id) makes the id of the existing notification the
+	// insert id when the event joins it
+	res, err := tx.ExecContext(ctx, `
+	INSERT INTO notifications (user_id, type, target_type, target_id, post_id, last_actor_id)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE
+		id = LAST_INSERT_ID(id),
+		last_actor_id = VALUES(last_actor_id),
+		updated_at = CURRENT_TIMESTAMP`,
+		event.UserID, event.Type, event.TargetType, event.TargetID, postID, event.ActorID)
+	if err != nil {
+		return fmt.Errorf("failed to add notification: %w", err)
+	}
+
+	notificationID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	res, err = tx.ExecContext(ctx, `
+	INSERT IGNORE INTO notification_actors (notification_id, actor_id) VALUES (?, ?)`,
+		notificationID, event.ActorID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows > 0 {
+		_, err = tx.ExecContext(ctx, `
+		UPDATE notifications SET actor_count = actor_count + 1 WHERE id = ?`, notificationID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+
+	return err
+}
+
+// GetNotifications implements NotificationRepoItf. The notifications with
+// the most recent events come first, the ones last touched by a user blocked
+// either way are left out.
+func (r *NotificationRepo) GetNotifications(ctx context.Context, userID int64, filter model.NotificationFilter) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+
+	qb := newQueryBuilder(`
+	SELECT
+		n.id,
+		n.type,
+		n.target_type,
+		n.target_id,
+		n.post_id,
+		n.actor_count,
+		u.id AS actor_id,
+		u.name AS actor_name,
+		u.photo AS actor_photo,
+		n.read_at,
+		n.created_at,
+		n.updated_at
+	FROM notifications AS n
+	JOIN users AS u ON u.id = n.last_actor_id`).
+		Where(`n.user_id = ?`, userID).
+		Where(notBlocked("n.last_actor_id"), userID, userID)
+
+	if filter.UnreadOnly {
+		qb.Where(`n.unread = 1`)
+	}
+
+	if c := filter.Cursor; c != nil {
+		updatedAt := time.Unix(c.UpdatedAt, 0)
+		qb.Where(`(n.updated_at < ? OR (n.updated_at = ? AND n.id < ?))`, updatedAt, updatedAt, c.ID)
+	}
+
+	qb.Suffix(`ORDER BY n.updated_at DESC, n.id DESC`)
+
+	if filter.Limit > 0 {
+		qb.Suffix(`LIMIT ?`, filter.Limit)
+	}
+
+	query, args := qb.Build()
+
+	if err := r.db.SelectContext(ctx, &notifications, query, args...); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// CountUnread implements NotificationRepoItf. It counts the notifications
+// GetNotifications lists.
+func (r *NotificationRepo) CountUnread(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+
+	err := r.db.GetContext(ctx, &count, `
+	SELECT COUNT(*) FROM notifications AS n
+	WHERE n.user_id = ? AND n.unread = 1 AND `+notBlocked("n.last_actor_id"), userID, userID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MarkRead implements NotificationRepoItf. Marking a read notification again
+// is not an error, a notification of another user is not found.
+func (r *NotificationRepo) MarkRead(ctx context.Context, userID int64, notificationID int64) error {
+	res, err := r.db.ExecContext(ctx, `
+	UPDATE notifications SET unread = NULL, read_at = CURRENT_TIMESTAMP
+	WHERE id = ? AND user_id = ? AND unread = 1`, notificationID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return customError.ErrRowsAffected
+	}
+
+	if rows > 0 {
+		return nil
+	}
+
+	var exists bool
+
+	err = r.db.GetContext(ctx, &exists, `
+	SELECT EXISTS(SELECT 1 FROM notifications WHERE id = ? AND user_id = ?)`, notificationID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("notification %d: %w", notificationID, customError.ErrNotFound)
+	}
+
+	return nil
+}
+
+// MarkAllRead implements NotificationRepoItf.
+func (r *NotificationRepo) MarkAllRead(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+	UPDATE notifications SET unread = NULL, read_at = CURRENT_TIMESTAMP
+	WHERE user_id = ? AND unread = 1`, userID)
+
+	return err
+}
+
+// IsEnabled implements NotificationRepoItf. Every type is enabled until the
+// user turns it off.
+func (r *NotificationRepo) IsEnabled(ctx context.Context, userID int64, notificationType string) (bool, error) {
+	var disabled bool
+
+	err := r.db.GetContext(ctx, &disabled, `
+	SELECT EXISTS(
+		SELECT 1 FROM notification_preferences WHERE user_id = ? AND type = ? AND enabled = 0
+	)`, userID, notificationType)
+	if err != nil {
+		return false, err
+	}
+
+	return !disabled, nil
+}
+
+// GetPreferences implements NotificationRepoItf. It only returns the types
+// the user set a preference for.
+func (r *NotificationRepo) GetPreferences(ctx context.Context, userID int64) ([]*model.NotificationPreference, error) {
+	var prefs []*model.NotificationPreference
+
+	err := r.db.SelectContext(ctx, &prefs, `
+	SELECT type, enabled FROM notification_preferences WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+// SetPreferences implements NotificationRepoItf. The types left out of prefs
+// keep their preference.
+func (r *NotificationRepo) SetPreferences(ctx context.Context, userID int64, prefs []model.NotificationPreference) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
+
+	for _, pref := range prefs {
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO notification_preferences (user_id, type, enabled) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)`, userID, pref.Type, pref.Enabled)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+
+	return err
+}
@@ -2,10 +2,15 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/federicodosantos/socialize/internal/model"
 	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/ranking"
 	"github.com/federicodosantos/socialize/pkg/util"
 	"github.com/jmoiron/sqlx"
 )
@@ -13,33 +18,79 @@ import (
 type PostRepoItf interface {
 	CreatePost(ctx context.Context, post *model.Post) error
 	GetAllPost(ctx context.Context, filter model.PostFilter) ([]*model.Post, error)
-	GetPostByID(ctx context.Context, postID int64) (*model.Post, error)
+	GetAllPostByUserID(ctx context.Context, filter model.PostFilter, userID int64) ([]*model.Post, error)
+	GetPostByID(ctx context.Context, postID int64, viewerID int64) (*model.Post, error)
+	UpdatePost(ctx context.Context, post *model.Post) error
+	GetPostRevisions(ctx context.Context, postID int64) ([]*model.PostRevision, error)
 	DeletePost(ctx context.Context, postID int64) error
 
-	CreateVote(ctx context.Context, postID int64, userID int64, vote int64) error
-	DeletVote(ctx context.Context, postID int64, userID int64) error
+	SetVote(ctx context.Context, postID int64, userID int64, vote int64) (*model.VoteCount, error)
+	DeleteVote(ctx context.Context, postID int64, userID int64) (*model.VoteCount, error)
+
+	GetPostStats(ctx context.Context, postID int64) (*model.PostStats, error)
+	GetPostStatsBatch(ctx context.Context, since time.Time, afterID int64, limit int) ([]*model.PostStats, error)
+	UpdatePostScores(ctx context.Context, scores []model.PostScore) error
 }
 
 type PostRepo struct {
 	db *sqlx.DB
+	// hotScore computes the hot score as of the time bound to its placeholder
+	// in place of the stored one, it is only set for time dependent scorers
+	hotScore string
 }
 
-func NewPostRepo(db *sqlx.DB) PostRepoItf {
-	return &PostRepo{db: db}
+func NewPostRepo(db *sqlx.DB, scorer ranking.Scorer) PostRepoItf {
+	r := &PostRepo{db: db}
+
+	if s, ok := scorer.(ranking.SQLScorer); ok && s.TimeDependent() {
+		r.hotScore = s.ScoreSQL(`p.net_vote`, `p.created_at`)
+	}
+
+	return r
 }
 
-func (r *PostRepo) CreatePost(ctx context.Context, post *model.Post) error {
-	createdAtStr := util.ConvertTimeToString(post.CreatedAt)
-	updatedAtStr := util.ConvertTimeToString(post.UpdatedAt)
+const postColumns = `
+		p.id,
+		p.title,
+		p.content,
+		p.user_id,
+		p.image,
+		u.name AS user_name,
+		u.photo AS user_photo,
+		p.created_at,
+		p.updated_at, 
+		p.edited_at,
+		p.up_vote,
+		p.down_vote,
+		p.comment_count,
+		p.score`
+
+const postFrom = `
+	FROM posts AS p
+	JOIN users AS u ON u.id = p.user_id`
+
+// viewerPostColumns and viewerPostJoin add the fields that depend on who the
+// post is shown to. The columns take the id of the viewer twice and the join
+// once.
+const viewerPostColumns = `,
+		COALESCE(v.vote, 0) AS my_vote,
+		p.user_id = ? AS is_owner,
+		EXISTS(SELECT 1 FROM bookmarks WHERE user_id = ? AND post_id = p.id) AS bookmarked`
+
+const viewerPostJoin = `
+	LEFT JOIN votes AS v ON v.post_id = p.id AND v.user_id = ?`
+
+const selectPostQuery = `SELECT` + postColumns + viewerPostColumns + postFrom + viewerPostJoin
 
-	insertPostQuery := fmt.Sprintf(`
+func (r *PostRepo) CreatePost(ctx context.Context, post *model.Post) error {
+	insertPostQuery := `
 	INSERT INTO posts (
 		user_id, title, content, image, created_at, updated_at
 	) VALUES (
-		%d, '%s', '%s', '%s', '%s', '%s'
-	)`, post.UserID, post.Title, post.Content, post.Image.String, createdAtStr, updatedAtStr)
+		:user_id, :title, :content, :image, :created_at, :updated_at
+	)`
 
-	res, err := r.db.ExecContext(ctx, insertPostQuery)
+	res, err := r.db.NamedExecContext(ctx, insertPostQuery, post)
 	if err != nil {
 		return err
 	}
@@ -59,64 +110,97 @@ func (r *PostRepo) CreatePost(ctx context.Context, post *model.Post) error {
 	return util.ErrRowsAffected(rows)
 }
 
+// postSortKey holds the value each sort orders posts by. The vote counts and
+// the score are kept up to date on the posts row so every sort can use an
+// index.
+var postSortKey = map[string]string{
+	model.PostSortNew: `0`,
+	model.PostSortTop: `p.net_vote`,
+	model.PostSortHot: `p.score`,
+}
+
+func rankedPostQuery(sortKey string) string {
+	return `SELECT` + postColumns + viewerPostColumns + `,
+		` + sortKey + ` AS sort_key` + postFrom + viewerPostJoin
+}
+
+// GetAllPost returns a page of posts ordered by the sort of the filter, with
+// the post id breaking ties so the order is total. Pages are keyset based, a
+// post inserted while paging either shows up before the cursor or on a later
+// page, it never shifts the posts already returned.
 func (r *PostRepo) GetAllPost(ctx context.Context, filter model.PostFilter) ([]*model.Post, error) {
+	return r.listPosts(ctx, filter, "")
+}
+
+// GetAllPostByUserID returns a page of the posts written by the user, paged
+// like GetAllPost.
+func (r *PostRepo) GetAllPostByUserID(ctx context.Context, filter model.PostFilter, userID int64) ([]*model.Post, error) {
+	return r.listPosts(ctx, filter, `p.user_id = ?`, userID)
+}
+
+// listPosts returns a page of the posts matching scope and the filter. An
+// empty scope lists every post.
+func (r *PostRepo) listPosts(ctx context.Context, filter model.PostFilter, scope string, scopeArgs ...any) ([]*model.Post, error) {
 	var posts []*model.Post
 
-	getAllPostQuery := fmt.Sprintf(`
-	SELECT 
-		p.id,
-		p.title,
-		p.content,
-		p.user_id,
-		p.image,
-		u.name AS user_name,
-		u.photo AS user_photo,
-		p.created_at,
-		p.updated_at, 
-		(SELECT count(*) from votes WHERE vote = 1 AND post_id = p.id) AS up_vote, 
-		(SELECT count(*) from votes WHERE vote = -1 AND post_id = p.id) AS down_vote  
-	FROM posts AS p
-	JOIN users AS u ON u.id = p.user_id
-	`)
+	sortKey, ok := postSortKey[filter.Sort]
+	if !ok {
+		sortKey = postSortKey[model.PostSortNew]
+	}
+
+	// every page of a time dependent hot listing is ranked as of the same
+	// time, the sort key then binds it wherever it appears
+	var sortArgs []any
+	if filter.Sort == model.PostSortHot && r.hotScore != "" {
+		rankedAt := filter.RankedAt
+		if rankedAt.IsZero() {
+			rankedAt = time.Now()
+		}
+
+		sortKey = r.hotScore
+		sortArgs = []any{rankedAt}
+	}
+
+	baseArgs := append([]any{filter.ViewerID, filter.ViewerID}, sortArgs...)
+	qb := newQueryBuilder(rankedPostQuery(sortKey), append(baseArgs, filter.ViewerID)...)
+
+	if scope != "" {
+		qb.Where(scope, scopeArgs...)
+	}
 
 	if filter.Keyword != "" {
-		getAllPostQuery = fmt.Sprintf(`%s WHERE p.content LIKE '%%%s%%'`, getAllPostQuery, filter.Keyword)
+		qb.Where(`p.content LIKE ?`, containsPattern(filter.Keyword))
 	}
 
-	err := r.db.SelectContext(ctx, &posts, getAllPostQuery)
-	if err != nil {
-		return nil, err
+	if !filter.Since.IsZero() {
+		qb.Where(`p.created_at >= ?`, filter.Since)
 	}
 
-	return posts, nil
-}
+	if c := filter.Cursor; c != nil {
+		cursorArgs := append(append([]any{}, sortArgs...), c.Score)
+		cursorArgs = append(cursorArgs, sortArgs...)
+		cursorArgs = append(cursorArgs, c.Score, c.ID)
 
-func (r *PostRepo) GetAllPostByUserID(ctx context.Context, filter model.PostFilter, userID int64) ([]*model.Post, error) {
-	var posts []*model.Post
+		qb.Where(`(`+sortKey+` < ? OR (`+sortKey+` = ? AND p.id < ?))`, cursorArgs...)
+	}
 
-	getAllPostQuery := fmt.Sprintf(`
-	SELECT 
-		p.id,
-		p.title,
-		p.content,
-		p.user_id,
-		p.image,
-		u.name AS user_name,
-		u.photo AS user_photo,
-		p.created_at,
-		p.updated_at, 
-		(SELECT count(*) from votes WHERE vote = 1 AND post_id = p.id) AS up_vote, 
-		(SELECT count(*) from votes WHERE vote = -1 AND post_id = p.id) AS down_vote   
-	FROM posts AS p
-	JOIN users AS u ON u.id = p.user_id
-	WHERE user_id = %d
-	`, userID)
+	if filter.ViewerID != 0 {
+		qb.Where(notBlocked(`p.user_id`), filter.ViewerID, filter.ViewerID)
 
-	if filter.Keyword != "" {
-		getAllPostQuery = fmt.Sprintf(`%s WHERE content LIKE '%%%s%%'`, getAllPostQuery, filter.Keyword)
+		if filter.ExcludeMuted {
+			qb.Where(notMuted(`p.user_id`), filter.ViewerID)
+		}
+	}
+
+	qb.Suffix(`ORDER BY sort_key DESC, p.id DESC`)
+
+	if filter.Limit > 0 {
+		qb.Suffix(`LIMIT ?`, filter.Limit)
 	}
 
-	err := r.db.SelectContext(ctx, &posts, getAllPostQuery)
+	query, args := qb.Build()
+
+	err := r.db.SelectContext(ctx, &posts, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -124,92 +208,326 @@ func (r *PostRepo) GetAllPostByUserID(ctx context.Context, filter model.PostFilt
 	return posts, nil
 }
 
-func (r *PostRepo) GetPostByID(ctx context.Context, postID int64) (*model.Post, error) {
+// GetPostByID returns the post with the fields that depend on the viewer
+// filled for viewerID.
+func (r *PostRepo) GetPostByID(ctx context.Context, postID int64, viewerID int64) (*model.Post, error) {
 	var post model.Post
 
-	query := fmt.Sprintf(`
-	SELECT 
-		p.id,
-		p.title,
-		p.content,
-		p.user_id,
-		p.image,
-		u.name AS user_name,
-		u.photo AS user_photo,
-		p.created_at,
-		p.updated_at, 
-		(SELECT count(*) from votes WHERE vote = 1 AND post_id = p.id) AS up_vote, 
-		(SELECT count(*) from votes WHERE vote = -1 AND post_id = p.id) AS down_vote   
-	FROM posts AS p
-	JOIN users AS u ON u.id = p.user_id 
-	WHERE p.id = %d`, postID)
+	query, args := newQueryBuilder(selectPostQuery, viewerID, viewerID, viewerID).Where(`p.id = ?`, postID).Build()
 
-	err := r.db.GetContext(ctx, &post, query)
+	err := r.db.GetContext(ctx, &post, query, args...)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("post %d: %w", postID, customError.ErrNotFound)
+		}
 		return nil, err
 	}
 
 	return &post, nil
 }
 
+// UpdatePost saves an edit of the post. The version being replaced is copied
+// to post_revisions in the same transaction so no edit is lost.
+func (r *PostRepo) UpdatePost(ctx context.Context, post *model.Post) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+	INSERT INTO post_revisions (post_id, title, content, image, created_at, replaced_at)
+	SELECT id, title, content, image, COALESCE(edited_at, created_at), ?
+	FROM posts WHERE id = ?`, post.EditedAt.Time, post.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		err = customError.ErrRowsAffected
+		return err
+	}
+
+	if rows == 0 {
+		err = fmt.Errorf("post %d: %w", post.ID, customError.ErrNotFound)
+		return err
+	}
+
+	_, err = tx.NamedExecContext(ctx, `
+	UPDATE posts SET
+		title = :title,
+		content = :content,
+		image = :image,
+		updated_at = :updated_at,
+		edited_at = :edited_at
+	WHERE id = :id`, post)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+
+	return err
+}
+
+// GetPostRevisions returns the previous versions of the post, newest first.
+func (r *PostRepo) GetPostRevisions(ctx context.Context, postID int64) ([]*model.PostRevision, error) {
+	var revisions []*model.PostRevision
+
+	err := r.db.SelectContext(ctx, &revisions, `
+	SELECT id, post_id, title, content, image, created_at, replaced_at
+	FROM post_revisions
+	WHERE post_id = ?
+	ORDER BY id DESC`, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// DeletePost removes the post together with its votes, bookmarks,
+// notifications, reactions and comments, which would otherwise keep it from
+// being deleted or be left dangling.
 func (r *PostRepo) DeletePost(ctx context.Context, postID int64) error {
-	query := fmt.Sprintf("DELETE FROM posts WHERE id = %d", postID)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
 
-	res, err := r.db.ExecContext(ctx, query)
+	if _, err = tx.ExecContext(ctx, "DELETE FROM votes WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM bookmarks WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	DELETE a FROM notification_actors AS a
+	JOIN notifications AS n ON n.id = a.notification_id
+	WHERE n.post_id = ?`, postID)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM notifications WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	DELETE r FROM reactions AS r
+	JOIN comments AS c ON c.id = r.target_id
+	WHERE r.target_type = ? AND c.post_id = ?`, model.ReactionTargetComment, postID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	DELETE FROM reactions WHERE target_type = ? AND target_id = ?`, model.ReactionTargetPost, postID)
+	if err != nil {
+		return err
+	}
+
+	// replies reference their parent, the thread is flattened first so the
+	// comments can go in any order
+	if _, err = tx.ExecContext(ctx, "UPDATE comments SET parent_id = NULL WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM comments WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM posts WHERE id = ?", postID)
 	if err != nil {
 		return err
 	}
 
 	rows, err := res.RowsAffected()
 	if err != nil {
-		return customError.ErrRowsAffected
+		err = customError.ErrRowsAffected
+		return err
 	}
 
-	return util.ErrRowsAffected(rows)
+	if rows == 0 {
+		err = fmt.Errorf("post %d: %w", postID, customError.ErrNotFound)
+		return err
+	}
+
+	err = tx.Commit()
+
+	return err
+}
+
+// SetVote records the vote of the user on the post, replacing their previous
+// vote, and returns the new counters. Voting the same way twice changes
+// nothing.
+func (r *PostRepo) SetVote(ctx context.Context, postID int64, userID int64, vote int64) (*model.VoteCount, error) {
+	return r.changeVote(ctx, postID, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+		INSERT INTO votes (post_id, user_id, vote) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE vote = VALUES(vote)`, postID, userID, vote)
+		if err != nil {
+			return fmt.Errorf("failed to set vote: %w", err)
+		}
+
+		return nil
+	})
 }
 
-func (r *PostRepo) CreateVote(ctx context.Context, postID int64, userID int64, vote int64) error {
-    queryCheck := fmt.Sprintf("SELECT COUNT(*) FROM posts WHERE id = %d", postID)
-    var count int
-    err := r.db.QueryRowContext(ctx, queryCheck).Scan(&count)
-    if err != nil {
-        return fmt.Errorf("failed to check post_id: %w", err)
-    }
+// DeleteVote removes the vote of the user on the post, if any, and returns
+// the new counters.
+func (r *PostRepo) DeleteVote(ctx context.Context, postID int64, userID int64) (*model.VoteCount, error) {
+	return r.changeVote(ctx, postID, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM votes WHERE post_id = ? AND user_id = ?", postID, userID)
 
-    if count == 0 {
-        return fmt.Errorf("postID %d does not exist in posts", postID)
-    }
+		return err
+	})
+}
 
-    queryCheckVote := fmt.Sprintf("SELECT COUNT(*) FROM votes WHERE post_id = %d AND user_id = %d", postID, userID)
-    err = r.db.QueryRowContext(ctx, queryCheckVote).Scan(&count)
-    if err != nil {
-        return fmt.Errorf("failed to check existing vote: %w", err)
-    }
+// changeVote applies change to the votes of the post and recounts them in one
+// transaction. The post row is locked first so concurrent votes on the same
+// post are applied one after the other and the counters never miss one.
+func (r *PostRepo) changeVote(ctx context.Context, postID int64, change func(tx *sqlx.Tx) error) (*model.VoteCount, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
 
-    if count > 0 {
-        queryUpdateVote := fmt.Sprintf("UPDATE votes SET vote = %d WHERE post_id = %d AND user_id = %d", vote, postID, userID)
-        _, err = r.db.ExecContext(ctx, queryUpdateVote)
-        if err != nil {
-            return fmt.Errorf("failed to update vote: %w", err)
-        }
-    } else {
-        queryInsert := fmt.Sprintf("INSERT INTO votes (post_id, user_id, vote) VALUES (%d, %d, %d)", postID, userID, vote)
-        _, err = r.db.ExecContext(ctx, queryInsert)
-        if err != nil {
-            return fmt.Errorf("failed to insert vote: %w", err)
-        }
-    }
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
 
-    return nil
+	var id int64
+	err = tx.GetContext(ctx, &id, "SELECT id FROM posts WHERE id = ? FOR UPDATE", postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("post %d: %w", postID, customError.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	if err = change(tx); err != nil {
+		return nil, err
+	}
+
+	if err = refreshVoteCounts(ctx, tx, postID); err != nil {
+		return nil, err
+	}
+
+	var count model.VoteCount
+	if err = tx.GetContext(ctx, &count, "SELECT up_vote, down_vote FROM posts WHERE id = ?", postID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &count, nil
+}
+
+// refreshVoteCounts recounts the votes of the post in the transaction that
+// changed them. Counting instead of incrementing keeps the counters right
+// even if they drifted.
+func refreshVoteCounts(ctx context.Context, tx *sqlx.Tx, postID int64) error {
+	_, err := tx.ExecContext(ctx, `
+	UPDATE posts SET
+		up_vote = (SELECT COUNT(*) FROM votes WHERE post_id = ? AND vote = 1),
+		down_vote = (SELECT COUNT(*) FROM votes WHERE post_id = ? AND vote = -1)
+	WHERE id = ?`, postID, postID, postID)
+
+	return err
 }
 
-func (r *PostRepo) DeletVote(ctx context.Context, postID int64, userID int64) error {
-	query := fmt.Sprintf("DELETE FROM votes WHERE post_id = %d AND user_id = %d", postID, userID)
+const selectPostStatsQuery = `SELECT id, up_vote, down_vote, comment_count, created_at FROM posts`
+
+// GetPostStats returns the counters of the post for its ranking score.
+func (r *PostRepo) GetPostStats(ctx context.Context, postID int64) (*model.PostStats, error) {
+	var stats model.PostStats
 
-	_, err := r.db.ExecContext(ctx, query)
+	err := r.db.GetContext(ctx, &stats, selectPostStatsQuery+` WHERE id = ?`, postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("post %d: %w", postID, customError.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// GetPostStatsBatch returns the counters of up to limit posts with an id
+// above afterID created at or after since, ordered by id so callers can walk
+// the whole table batch by batch.
+func (r *PostRepo) GetPostStatsBatch(ctx context.Context, since time.Time, afterID int64, limit int) ([]*model.PostStats, error) {
+	var stats []*model.PostStats
+
+	qb := newQueryBuilder(selectPostStatsQuery).Where(`id > ?`, afterID)
+
+	if !since.IsZero() {
+		qb.Where(`created_at >= ?`, since)
+	}
+
+	query, args := qb.Suffix(`ORDER BY id`).Suffix(`LIMIT ?`, limit).Build()
+
+	err := r.db.SelectContext(ctx, &stats, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// UpdatePostScores saves the ranking scores in one transaction.
+func (r *PostRepo) UpdatePostScores(ctx context.Context, scores []model.PostScore) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
+
+	stmt, err := tx.PreparexContext(ctx, `UPDATE posts SET score = ? WHERE id = ?`)
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
+
+	for _, score := range scores {
+		if _, err = stmt.ExecContext(ctx, score.Score, score.ID); err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
 
-	return nil
+	return err
 }
@@ -0,0 +1,69 @@
+package repository
+
+import "strings"
+
+// queryBuilder composes a SELECT statement from a fixed base query and a set
+// of optional conditions. Every value is carried as a bound argument so user
+// input never ends up inside the SQL text itself.
+type queryBuilder struct {
+	base       string
+	conditions []string
+	args       []any
+	suffix     []string
+	suffixArgs []any
+}
+
+func newQueryBuilder(base string, args ...any) *queryBuilder {
+	return &queryBuilder{
+		base: base,
+		args: args,
+	}
+}
+
+// Where adds a condition joined with AND to the others. The condition must use
+// "?" placeholders for each argument.
+func (q *queryBuilder) Where(condition string, args ...any) *queryBuilder {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+
+	return q
+}
+
+// Suffix appends a clause such as ORDER BY or LIMIT after the WHERE clause.
+func (q *queryBuilder) Suffix(clause string, args ...any) *queryBuilder {
+	q.suffix = append(q.suffix, clause)
+	q.suffixArgs = append(q.suffixArgs, args...)
+
+	return q
+}
+
+// Build returns the final query and its arguments in placeholder order.
+func (q *queryBuilder) Build() (string, []any) {
+	var sb strings.Builder
+
+	sb.WriteString(q.base)
+
+	if len(q.conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(q.conditions, " AND "))
+	}
+
+	for _, clause := range q.suffix {
+		sb.WriteString(" ")
+		sb.WriteString(clause)
+	}
+
+	args := make([]any, 0, len(q.args)+len(q.suffixArgs))
+	args = append(args, q.args...)
+	args = append(args, q.suffixArgs...)
+
+	return sb.String(), args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern turns a keyword into a LIKE pattern matching it anywhere,
+// escaping the LIKE wildcards so they are matched literally.
+func containsPattern(keyword string) string {
+	return "%" + likeEscaper.Replace(keyword) + "%"
+}
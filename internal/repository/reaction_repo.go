@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+type ReactionRepoItf interface {
+	SetReaction(ctx context.Context, reaction *model.Reaction) error
+	DeleteReaction(ctx context.Context, userID int64, targetType string, targetID int64) error
+	GetReactionCounts(ctx context.Context, targetType string, targetIDs []int64) ([]*model.ReactionCount, error)
+	GetUserReactions(ctx context.Context, userID int64, targetType string, targetIDs []int64) ([]*model.Reaction, error)
+}
+
+type ReactionRepo struct {
+	db *sqlx.DB
+}
+
+func NewReactionRepo(db *sqlx.DB) ReactionRepoItf {
+	return &ReactionRepo{db: db}
+}
+
+// SetReaction implements ReactionRepoItf. A user has one reaction per
+// target, reacting again replaces the previous reaction.
+func (r *ReactionRepo) SetReaction(ctx context.Context, reaction *model.Reaction) error {
+	_, err := r.db.NamedExecContext(ctx, `
+	INSERT INTO reactions (user_id, target_type, target_id, reaction, created_at)
+	VALUES (:user_id, :target_type, :target_id, :reaction, :created_at)
+	ON DUPLICATE KEY UPDATE reaction = VALUES(reaction), created_at = VALUES(created_at)`, reaction)
+
+	return err
+}
+
+// DeleteReaction implements ReactionRepoItf.
+func (r *ReactionRepo) DeleteReaction(ctx context.Context, userID int64, targetType string, targetID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+	DELETE FROM reactions WHERE user_id = ? AND target_type = ? AND target_id = ?`, userID, targetType, targetID)
+
+	return err
+}
+
+// GetReactionCounts implements ReactionRepoItf.
+func (r *ReactionRepo) GetReactionCounts(ctx context.Context, targetType string, targetIDs []int64) ([]*model.ReactionCount, error) {
+	var counts []*model.ReactionCount
+
+	if len(targetIDs) == 0 {
+		return counts, nil
+	}
+
+	query, args, err := sqlx.In(`
+	SELECT target_id, reaction, COUNT(*) AS count
+	FROM reactions
+	WHERE target_type = ? AND target_id IN (?)
+	GROUP BY target_id, reaction`, targetType, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.SelectContext(ctx, &counts, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// GetUserReactions implements ReactionRepoItf.
+func (r *ReactionRepo) GetUserReactions(ctx context.Context, userID int64, targetType string, targetIDs []int64) ([]*model.Reaction, error) {
+	var reactions []*model.Reaction
+
+	if len(targetIDs) == 0 {
+		return reactions, nil
+	}
+
+	query, args, err := sqlx.In(`
+	SELECT user_id, target_type, target_id, reaction, created_at
+	FROM reactions
+	WHERE user_id = ? AND target_type = ? AND target_id IN (?)`, userID, targetType, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.SelectContext(ctx, &reactions, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	return reactions, nil
+}
@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// SearchIndex finds posts, comments and users by their text. Index and Delete
+// keep the index in sync with the database, deleting a post also removes its
+// comments.
+type SearchIndex interface {
+	Index(ctx context.Context, docs ...*model.SearchDocument) error
+	Delete(ctx context.Context, docType string, id int64) error
+	Search(ctx context.Context, query model.SearchQuery) ([]*model.SearchHit, error)
+	// SyncRequired reports whether the index lives outside of the database
+	// and has to be fed through Index and Delete.
+	SyncRequired() bool
+}
+
+// SearchSourceRepoItf reads the documents of the posts, comments and users,
+// to fill a search index or to refresh one document after a change.
+type SearchSourceRepoItf interface {
+	GetDocument(ctx context.Context, docType string, id int64) (*model.SearchDocument, error)
+	GetDocuments(ctx context.Context, docType string, afterID int64, limit int) ([]*model.SearchDocument, error)
+}
+
+type searchSource struct {
+	columns string
+	from    string
+	// match lists the columns of the FULLTEXT index of the type
+	match         string
+	idColumn      string
+	authorColumn  string
+	createdColumn string
+	// scope leaves out the rows that must not be found, it may be empty
+	scope string
+}
+
+// newQuery starts a query over the rows of the source that can be found.
+func (s searchSource) newQuery(selectList string, args ...any) *queryBuilder {
+	qb := newQueryBuilder(`SELECT`+selectList+s.from, args...)
+
+	if s.scope != "" {
+		qb.Where(s.scope)
+	}
+
+	return qb
+}
+
+var searchSources = map[string]searchSource{
+	model.SearchTypePost: {
+		columns: `
+		'post' AS type,
+		p.id,
+		p.id AS post_id,
+		p.user_id AS author_id,
+		u.name AS author_name,
+		p.title,
+		COALESCE(p.content, '') AS body,
+		COALESCE(p.image, '') <> '' AS has_image,
+		p.net_vote AS score,
+		p.created_at`,
+		from: `
+	FROM posts AS p
+	JOIN users AS u ON u.id = p.user_id`,
+		match:         `p.title, p.content`,
+		idColumn:      `p.id`,
+		authorColumn:  `p.user_id`,
+		createdColumn: `p.created_at`,
+	},
+	model.SearchTypeComment: {
+		columns: `
+		'comment' AS type,
+		c.id,
+		c.post_id,
+		c.user_id AS author_id,
+		u.name AS author_name,
+		'' AS title,
+		c.comment AS body,
+		FALSE AS has_image,
+		c.up_vote - c.down_vote AS score,
+		c.created_at`,
+		from: `
+	FROM comments AS c
+	JOIN users AS u ON u.id = c.user_id`,
+		match:         `c.comment`,
+		idColumn:      `c.id`,
+		authorColumn:  `c.user_id`,
+		createdColumn: `c.created_at`,
+		// deleted comments with replies stay as tombstones
+		scope: `c.deleted_at IS NULL`,
+	},
+	model.SearchTypeUser: {
+		columns: `
+		'user' AS type,
+		u.id,
+		0 AS post_id,
+		u.id AS author_id,
+		u.name AS author_name,
+		u.name AS title,
+		'' AS body,
+		FALSE AS has_image,
+		0 AS score,
+		u.created_at`,
+		from: `
+	FROM users AS u`,
+		match:         `u.name`,
+		idColumn:      `u.id`,
+		authorColumn:  `u.id`,
+		createdColumn: `u.created_at`,
+	},
+}
+
+func getSearchSource(docType string) (searchSource, error) {
+	source, ok := searchSources[docType]
+	if !ok {
+		return searchSource{}, fmt.Errorf("unknown search document type %q", docType)
+	}
+
+	return source, nil
+}
+
+type SearchSourceRepo struct {
+	db *sqlx.DB
+}
+
+func NewSearchSourceRepo(db *sqlx.DB) SearchSourceRepoItf {
+	return &SearchSourceRepo{db: db}
+}
+
+// GetDocument returns nil when the document does not exist anymore.
+func (r *SearchSourceRepo) GetDocument(ctx context.Context, docType string, id int64) (*model.SearchDocument, error) {
+	source, err := getSearchSource(docType)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []*model.SearchDocument
+
+	query, args := source.newQuery(source.columns).
+		Where(source.idColumn+` = ?`, id).
+		Build()
+
+	err = r.db.SelectContext(ctx, &docs, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	return docs[0], nil
+}
+
+// GetDocuments returns up to limit documents of the type with an id above
+// afterID, ordered by id so callers can walk the whole table.
+func (r *SearchSourceRepo) GetDocuments(ctx context.Context, docType string, afterID int64, limit int) ([]*model.SearchDocument, error) {
+	source, err := getSearchSource(docType)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []*model.SearchDocument
+
+	query, args := source.newQuery(source.columns).
+		Where(source.idColumn+` > ?`, afterID).
+		Suffix(`ORDER BY `+source.idColumn).
+		Suffix(`LIMIT ?`, limit).
+		Build()
+
+	err = r.db.SelectContext(ctx, &docs, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// MySQLSearchIndex searches the tables themselves through their FULLTEXT
+// indexes. MySQL keeps those up to date, so Index and Delete have nothing to
+// do.
+type MySQLSearchIndex struct {
+	db *sqlx.DB
+}
+
+func NewMySQLSearchIndex(db *sqlx.DB) SearchIndex {
+	return &MySQLSearchIndex{db: db}
+}
+
+func (s *MySQLSearchIndex) Index(ctx context.Context, docs ...*model.SearchDocument) error {
+	return nil
+}
+
+func (s *MySQLSearchIndex) Delete(ctx context.Context, docType string, id int64) error {
+	return nil
+}
+
+func (s *MySQLSearchIndex) SyncRequired() bool {
+	return false
+}
+
+// Search ranks the documents by the natural language relevance MySQL gives
+// them.
+func (s *MySQLSearchIndex) Search(ctx context.Context, query model.SearchQuery) ([]*model.SearchHit, error) {
+	source, err := getSearchSource(query.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	match := `MATCH(` + source.match + `) AGAINST (? IN NATURAL LANGUAGE MODE)`
+
+	qb := source.newQuery(source.columns+`,
+		`+match+` AS relevance`, query.Text).
+		Where(match, query.Text)
+
+	if query.AuthorID != 0 {
+		qb.Where(source.authorColumn+` = ?`, query.AuthorID)
+	}
+
+	if len(query.ExcludeAuthorIDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat(`?, `, len(query.ExcludeAuthorIDs)), `, `)
+
+		args := make([]any, 0, len(query.ExcludeAuthorIDs))
+		for _, id := range query.ExcludeAuthorIDs {
+			args = append(args, id)
+		}
+
+		qb.Where(source.authorColumn+` NOT IN (`+placeholders+`)`, args...)
+	}
+
+	if !query.From.IsZero() {
+		qb.Where(source.createdColumn+` >= ?`, query.From)
+	}
+
+	if !query.To.IsZero() {
+		qb.Where(source.createdColumn+` < ?`, query.To)
+	}
+
+	if query.Type == model.SearchTypePost {
+		if query.HasImage != nil {
+			if *query.HasImage {
+				qb.Where(`COALESCE(p.image, '') <> ''`)
+			} else {
+				qb.Where(`COALESCE(p.image, '') = ''`)
+			}
+		}
+
+		if query.MinScore != nil {
+			qb.Where(`p.net_vote >= ?`, *query.MinScore)
+		}
+	}
+
+	qb.Suffix(`ORDER BY relevance DESC, `+source.idColumn+` DESC`).
+		Suffix(`LIMIT ? OFFSET ?`, query.Limit, query.Offset)
+
+	var rows []struct {
+		model.SearchDocument
+		Relevance float64 `db:"relevance"`
+	}
+
+	sqlQuery, args := qb.Build()
+
+	err = s.db.SelectContext(ctx, &rows, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]*model.SearchHit, 0, len(rows))
+	for i := range rows {
+		hits = append(hits, &model.SearchHit{
+			Document:  &rows[i].SearchDocument,
+			Relevance: rows[i].Relevance,
+		})
+	}
+
+	return hits, nil
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/jmoiron/sqlx"
+)
+
+type TokenRepoItf interface {
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id int64, usedAt time.Time) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int64, revokedAt time.Time) error
+
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string, userID int64, issuedAt time.Time) (bool, error)
+	DeleteExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error)
+
+	CreateUserToken(ctx context.Context, token *model.UserToken) error
+	GetUserToken(ctx context.Context, purpose string, tokenHash string) (*model.UserToken, error)
+	GetLatestUserToken(ctx context.Context, userID int64, purpose string) (*model.UserToken, error)
+	MarkUserTokenUsed(ctx context.Context, id int64, usedAt time.Time) (bool, error)
+
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string, createdAt time.Time) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string, usedAt time.Time) (bool, error)
+	DeleteRecoveryCodes(ctx context.Context, userID int64) error
+}
+
+type TokenRepo struct {
+	db *sqlx.DB
+}
+
+func NewTokenRepo(db *sqlx.DB) TokenRepoItf {
+	return &TokenRepo{db: db}
+}
+
+// CreateRefreshToken implements TokenRepoItf.
+func (r *TokenRepo) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens(user_id, family_id, token_hash, expires_at, created_at)
+	VALUES (:user_id, :family_id, :token_hash, :expires_at, :created_at)`
+
+	res, err := r.db.NamedExecContext(ctx, query, token)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return customError.ErrLastInsertId
+	}
+
+	token.ID = id
+
+	return nil
+}
+
+// GetRefreshTokenByHash implements TokenRepoItf.
+func (r *TokenRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+
+	err := r.db.GetContext(ctx, &token, `SELECT * FROM refresh_tokens WHERE token_hash = ?`, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed implements TokenRepoItf. It reports false when the
+// token had already been used or revoked, which happens when two requests
+// race to rotate the same token.
+func (r *TokenRepo) MarkRefreshTokenUsed(ctx context.Context, id int64, usedAt time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = ?
+	WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL`, usedAt, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, customError.ErrRowsAffected
+	}
+
+	return rows == 1, nil
+}
+
+// RevokeRefreshTokenFamily implements TokenRepoItf.
+func (r *TokenRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ?
+	WHERE family_id = ? AND revoked_at IS NULL`, revokedAt, familyID)
+
+	return err
+}
+
+// RevokeUserRefreshTokens implements TokenRepoItf.
+func (r *TokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID int64, revokedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ?
+	WHERE user_id = ? AND revoked_at IS NULL`, revokedAt, userID)
+
+	return err
+}
+
+// RevokeAccessToken implements TokenRepoItf.
+func (r *TokenRepo) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `INSERT IGNORE INTO revoked_tokens(jti, expires_at) VALUES (?, ?)`, jti, expiresAt)
+
+	return err
+}
+
+// IsAccessTokenRevoked implements TokenRepoItf. A token is revoked either on
+// its own, at logout, or together with every token of the user issued before
+// their sessions were revoked.
+func (r *TokenRepo) IsAccessTokenRevoked(ctx context.Context, jti string, userID int64, issuedAt time.Time) (bool, error) {
+	var count int
+
+	query := `SELECT
+		(SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?) +
+		(SELECT COUNT(*) FROM users WHERE id = ? AND sessions_revoked_at > ?)`
+
+	err := r.db.QueryRowxContext(ctx, query, jti, userID, issuedAt).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// DeleteExpiredRevokedTokens implements TokenRepoItf. An expired token is
+// refused on its own, its revocation is no longer needed.
+func (r *TokenRepo) DeleteExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, customError.ErrRowsAffected
+	}
+
+	return rows, nil
+}
+
+// CreateUserToken implements TokenRepoItf.
+func (r *TokenRepo) CreateUserToken(ctx context.Context, token *model.UserToken) error {
+	query := `INSERT INTO user_tokens(user_id, purpose, token_hash, expires_at, created_at)
+	VALUES (:user_id, :purpose, :token_hash, :expires_at, :created_at)`
+
+	res, err := r.db.NamedExecContext(ctx, query, token)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return customError.ErrLastInsertId
+	}
+
+	token.ID = id
+
+	return nil
+}
+
+// GetUserToken implements TokenRepoItf.
+func (r *TokenRepo) GetUserToken(ctx context.Context, purpose string, tokenHash string) (*model.UserToken, error) {
+	var token model.UserToken
+
+	err := r.db.GetContext(ctx, &token, `SELECT * FROM user_tokens WHERE purpose = ? AND token_hash = ?`, purpose, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetLatestUserToken implements TokenRepoItf.
+func (r *TokenRepo) GetLatestUserToken(ctx context.Context, userID int64, purpose string) (*model.UserToken, error) {
+	var token model.UserToken
+
+	err := r.db.GetContext(ctx, &token, `SELECT * FROM user_tokens WHERE user_id = ? AND purpose = ?
+	ORDER BY created_at DESC, id DESC LIMIT 1`, userID, purpose)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUserTokenUsed implements TokenRepoItf. It reports false when the token
+// was already used.
+func (r *TokenRepo) MarkUserTokenUsed(ctx context.Context, id int64, usedAt time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE user_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL`, usedAt, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, customError.ErrRowsAffected
+	}
+
+	return rows == 1, nil
+}
+
+// ReplaceRecoveryCodes implements TokenRepoItf. Codes issued before are
+// deleted in the same transaction so only the latest set is usable.
+func (r *TokenRepo) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string, createdAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("cannot rollback tx: %s", rbErr)
+			}
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+
+	codes := make([]model.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, model.RecoveryCode{
+			UserID:    userID,
+			CodeHash:  hash,
+			CreatedAt: createdAt,
+		})
+	}
+
+	if len(codes) > 0 {
+		_, err = tx.NamedExecContext(ctx, `INSERT INTO recovery_codes(user_id, code_hash, created_at)
+		VALUES (:user_id, :code_hash, :created_at)`, codes)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+
+	return err
+}
+
+// UseRecoveryCode implements TokenRepoItf. It reports false when the code
+// does not belong to the user or was already used.
+func (r *TokenRepo) UseRecoveryCode(ctx context.Context, userID int64, codeHash string, usedAt time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE recovery_codes SET used_at = ?
+	WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`, usedAt, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, customError.ErrRowsAffected
+	}
+
+	return rows == 1, nil
+}
+
+// DeleteRecoveryCodes implements TokenRepoItf.
+func (r *TokenRepo) DeleteRecoveryCodes(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+
+	return err
+}
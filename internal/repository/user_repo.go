@@ -4,8 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"log"
+	"time"
 
 	"github.com/federicodosantos/socialize/internal/model"
 	customError "github.com/federicodosantos/socialize/pkg/custom-error"
@@ -17,10 +17,24 @@ type UserRepoItf interface {
 	CreateUser(ctx context.Context, user *model.User) error
 	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
 	GetUserById(ctx context.Context, userId int64) (*model.User, error)
+	GetUserProfile(ctx context.Context, userId int64) (*model.UserProfile, error)
 	CheckEmailExist(ctx context.Context, email string) (bool, error)
 	UpdateUserData(ctx context.Context, user *model.User) error
 	UpdateUserPhoto(ctx context.Context, user *model.User) error
-	UserLogin(ctx context.Context, email string, password string) (*model.User, error)
+	UpdateUserPassword(ctx context.Context, userId int64, password string) error
+	MarkUserVerified(ctx context.Context, userId int64, verifiedAt time.Time) error
+	RevokeUserSessions(ctx context.Context, userId int64, revokedAt time.Time) error
+	SetPendingEmail(ctx context.Context, userId int64, email string) error
+	ConfirmPendingEmail(ctx context.Context, userId int64, email string, confirmedAt time.Time) error
+	SetTOTPSecret(ctx context.Context, userId int64, secret string) error
+	EnableTOTP(ctx context.Context, userId int64, step int64, enabledAt time.Time) error
+	DisableTOTP(ctx context.Context, userId int64) error
+	UpdateTOTPLastStep(ctx context.Context, userId int64, step int64) (bool, error)
+	GetUserByIdentity(ctx context.Context, provider string, subject string) (*model.User, error)
+	CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error
+	GetAllUsers(ctx context.Context, filter model.UserFilter) ([]*model.User, error)
+	UpdateUserRole(ctx context.Context, userId int64, role string) error
+	SetUserBanned(ctx context.Context, userId int64, bannedAt sql.NullTime) error
 }
 
 type UserRepo struct {
@@ -33,11 +47,8 @@ func NewUserRepo(db *sqlx.DB) UserRepoItf {
 
 // CreateUser implements UserRepoItf.
 func (r *UserRepo) CreateUser(ctx context.Context, user *model.User) error {
-	createdAtStr := util.ConvertTimeToString(user.CreatedAt)
-	updatedAtStr := util.ConvertTimeToString(user.UpdatedAt)
-
-	insertUserQuery := fmt.Sprintf(`INSERT INTO users(name, email, password, created_at, updated_at) VALUES ('%s', '%s', '%s', '%s', '%s')`,
-		user.Name, user.Email, user.Password, createdAtStr, updatedAtStr)
+	insertUserQuery := `INSERT INTO users(name, email, password, created_at, updated_at)
+	VALUES (:name, :email, :password, :created_at, :updated_at)`
 
 	exist, err := r.CheckEmailExist(ctx, user.Email)
 	if err != nil {
@@ -48,7 +59,7 @@ func (r *UserRepo) CreateUser(ctx context.Context, user *model.User) error {
 		return customError.ErrEmailExist
 	}
 
-	res, err := r.db.ExecContext(ctx, insertUserQuery)
+	res, err := r.db.NamedExecContext(ctx, insertUserQuery, user)
 	if err != nil {
 		return err
 	}
@@ -70,11 +81,9 @@ func (r *UserRepo) CreateUser(ctx context.Context, user *model.User) error {
 
 // GetUserById implements UserRepoItf.
 func (r *UserRepo) GetUserById(ctx context.Context, userId int64) (*model.User, error) {
-	query := fmt.Sprintf("SELECT * FROM users WHERE id = %d", userId)
-
 	var user model.User
 
-	err := r.db.QueryRowxContext(ctx, query).StructScan(&user)
+	err := r.db.QueryRowxContext(ctx, "SELECT * FROM users WHERE id = ?", userId).StructScan(&user)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, customError.ErrUserNotFound
@@ -85,13 +94,42 @@ func (r *UserRepo) GetUserById(ctx context.Context, userId int64) (*model.User,
 	return &user, nil
 }
 
+// GetUserProfile implements UserRepoItf.
+func (r *UserRepo) GetUserProfile(ctx context.Context, userId int64) (*model.UserProfile, error) {
+	var profile model.UserProfile
+
+	getUserProfileQuery := `
+	SELECT
+		u.id,
+		u.name,
+		u.photo,
+		u.role,
+		u.banned_at,
+		u.created_at,
+		(SELECT COUNT(*) FROM posts WHERE user_id = u.id) AS post_count,
+		(SELECT COUNT(*) FROM comments WHERE user_id = u.id AND deleted_at IS NULL) AS comment_count,
+		(SELECT COALESCE(SUM(net_vote), 0) FROM posts WHERE user_id = u.id) AS karma,
+		(SELECT COUNT(*) FROM follows WHERE followee_id = u.id) AS follower_count,
+		(SELECT COUNT(*) FROM follows WHERE follower_id = u.id) AS following_count
+	FROM users AS u
+	WHERE u.id = ?`
+
+	err := r.db.GetContext(ctx, &profile, getUserProfileQuery, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
 // GetUserByEmail implements UserRepoItf.
 func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := fmt.Sprintf("SELECT * FROM users WHERE email = '%s'", email)
-
 	var user model.User
 
-	err := r.db.QueryRowxContext(ctx, query).StructScan(&user)
+	err := r.db.QueryRowxContext(ctx, "SELECT * FROM users WHERE email = ?", email).StructScan(&user)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, customError.ErrEmailNotFound
@@ -104,11 +142,9 @@ func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (*model.Use
 
 // UpdateUserData implements UserRepoItf.
 func (r *UserRepo) UpdateUserData(ctx context.Context, user *model.User) error {
-	updatedAtStr := util.ConvertTimeToString(user.UpdatedAt)
-
-	query := fmt.Sprintf(`UPDATE users 
-	SET name = '%s', email = '%s', password = '%s', updated_at = '%s'
-	WHERE id = %d`, user.Name, user.Email, user.Password, updatedAtStr, user.ID)
+	query := `UPDATE users 
+	SET name = :name, updated_at = :updated_at
+	WHERE id = :id`
 
 	tx, err := r.db.Beginx()
 	if err != nil {
@@ -124,7 +160,7 @@ func (r *UserRepo) UpdateUserData(ctx context.Context, user *model.User) error {
 		}
 	}()
 
-	res, err := tx.ExecContext(ctx, query)
+	res, err := tx.NamedExecContext(ctx, query, user)
 	if err != nil {
 		return err
 	}
@@ -146,11 +182,9 @@ func (r *UserRepo) UpdateUserData(ctx context.Context, user *model.User) error {
 
 // UpdateUserData implements UserRepoItf.
 func (u *UserRepo) UpdateUserPhoto(ctx context.Context, user *model.User) error {
-	updatedAtStr := util.ConvertTimeToString(user.UpdatedAt)
-
-	query := fmt.Sprintf(`UPDATE users 
-	SET photo = '%s', updated_at = '%s'
-	WHERE id = %d`, user.Photo.String, updatedAtStr, user.ID)
+	query := `UPDATE users 
+	SET photo = :photo, updated_at = :updated_at
+	WHERE id = :id`
 
 	tx, err := u.db.Beginx()
 	if err != nil {
@@ -166,7 +200,7 @@ func (u *UserRepo) UpdateUserPhoto(ctx context.Context, user *model.User) error
 		}
 	}()
 
-	res, err := tx.ExecContext(ctx, query)
+	res, err := tx.NamedExecContext(ctx, query, user)
 	if err != nil {
 		return err
 	}
@@ -188,11 +222,9 @@ func (u *UserRepo) UpdateUserPhoto(ctx context.Context, user *model.User) error
 
 // CheckEmailExist implements UserRepoItf.
 func (u *UserRepo) CheckEmailExist(ctx context.Context, email string) (bool, error) {
-	query := fmt.Sprintf(`SELECT COUNT(*) FROM users WHERE email = '%s'`, email)
-
 	var count int
 
-	err := u.db.QueryRowxContext(ctx, query).Scan(&count)
+	err := u.db.QueryRowxContext(ctx, `SELECT COUNT(*) FROM users WHERE email = ?`, email).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -200,12 +232,140 @@ func (u *UserRepo) CheckEmailExist(ctx context.Context, email string) (bool, err
 	return count > 0, nil
 }
 
-func (u *UserRepo) UserLogin(ctx context.Context, email string, password string) (*model.User, error) {
-	query := fmt.Sprintf("SELECT * FROM users WHERE email = '%s' AND password = '%s'", email, password)
+// UpdateUserPassword implements UserRepoItf.
+func (u *UserRepo) UpdateUserPassword(ctx context.Context, userId int64, password string) error {
+	res, err := u.db.ExecContext(ctx, `UPDATE users SET password = ? WHERE id = ?`, password, userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return customError.ErrRowsAffected
+	}
+
+	return util.ErrRowsAffected(rows)
+}
+
+// MarkUserVerified implements UserRepoItf.
+func (u *UserRepo) MarkUserVerified(ctx context.Context, userId int64, verifiedAt time.Time) error {
+	_, err := u.db.ExecContext(ctx, `UPDATE users SET verified_at = ? WHERE id = ? AND verified_at IS NULL`, verifiedAt, userId)
+
+	return err
+}
+
+// RevokeUserSessions implements UserRepoItf.
+func (u *UserRepo) RevokeUserSessions(ctx context.Context, userId int64, revokedAt time.Time) error {
+	_, err := u.db.ExecContext(ctx, `UPDATE users SET sessions_revoked_at = ? WHERE id = ?`, revokedAt, userId)
+
+	return err
+}
+
+// SetPendingEmail implements UserRepoItf.
+func (u *UserRepo) SetPendingEmail(ctx context.Context, userId int64, email string) error {
+	_, err := u.db.ExecContext(ctx, `UPDATE users SET pending_email = ? WHERE id = ?`, email, userId)
+
+	return err
+}
+
+// ConfirmPendingEmail implements UserRepoItf. The address is only swapped if
+// it is still the pending one, a newer change request wins over older links.
+func (u *UserRepo) ConfirmPendingEmail(ctx context.Context, userId int64, email string, confirmedAt time.Time) error {
+	res, err := u.db.ExecContext(ctx, `UPDATE users
+	SET email = pending_email, pending_email = NULL, verified_at = ?, updated_at = ?
+	WHERE id = ? AND pending_email = ?`, confirmedAt, confirmedAt, userId, email)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return customError.ErrRowsAffected
+	}
+
+	if rows != 1 {
+		return customError.ErrInvalidToken
+	}
+
+	return nil
+}
+
+// SetTOTPSecret implements UserRepoItf. The secret is only stored while two
+// factor authentication is not enabled yet, so a running enrollment cannot
+// replace the secret of an active one.
+func (u *UserRepo) SetTOTPSecret(ctx context.Context, userId int64, secret string) error {
+	res, err := u.db.ExecContext(ctx, `UPDATE users SET totp_secret = ?, totp_last_step = NULL
+	WHERE id = ? AND totp_enabled_at IS NULL`, secret, userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return customError.ErrRowsAffected
+	}
+
+	if rows != 1 {
+		return customError.ErrTwoFactorAlreadyEnabled
+	}
+
+	return nil
+}
+
+// EnableTOTP implements UserRepoItf. step is the time step of the code that
+// confirmed the enrollment, it cannot be used again to log in.
+func (u *UserRepo) EnableTOTP(ctx context.Context, userId int64, step int64, enabledAt time.Time) error {
+	res, err := u.db.ExecContext(ctx, `UPDATE users SET totp_enabled_at = ?, totp_last_step = ?
+	WHERE id = ? AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL`, enabledAt, step, userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return customError.ErrRowsAffected
+	}
+
+	if rows != 1 {
+		return customError.ErrTwoFactorAlreadyEnabled
+	}
+
+	return nil
+}
+
+// DisableTOTP implements UserRepoItf.
+func (u *UserRepo) DisableTOTP(ctx context.Context, userId int64) error {
+	_, err := u.db.ExecContext(ctx, `UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
+	WHERE id = ?`, userId)
 
+	return err
+}
+
+// UpdateTOTPLastStep implements UserRepoItf. It reports false when a code of
+// the same or a later time step was already accepted, which stops a code
+// from being replayed.
+func (u *UserRepo) UpdateTOTPLastStep(ctx context.Context, userId int64, step int64) (bool, error) {
+	res, err := u.db.ExecContext(ctx, `UPDATE users SET totp_last_step = ?
+	WHERE id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)`, step, userId, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, customError.ErrRowsAffected
+	}
+
+	return rows == 1, nil
+}
+
+// GetUserByIdentity implements UserRepoItf.
+func (u *UserRepo) GetUserByIdentity(ctx context.Context, provider string, subject string) (*model.User, error) {
 	var user model.User
 
-	err := u.db.QueryRowxContext(ctx, query).StructScan(&user)
+	err := u.db.QueryRowxContext(ctx, `SELECT users.* FROM users
+	JOIN user_identities ON user_identities.user_id = users.id
+	WHERE user_identities.provider = ? AND user_identities.subject = ?`, provider, subject).StructScan(&user)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, customError.ErrUserNotFound
@@ -215,3 +375,76 @@ func (u *UserRepo) UserLogin(ctx context.Context, email string, password string)
 
 	return &user, nil
 }
+
+// CreateUserIdentity implements UserRepoItf.
+func (u *UserRepo) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	query := `INSERT INTO user_identities(user_id, provider, subject, email, created_at)
+	VALUES (:user_id, :provider, :subject, :email, :created_at)`
+
+	res, err := u.db.NamedExecContext(ctx, query, identity)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return customError.ErrLastInsertId
+	}
+
+	identity.ID = id
+
+	return nil
+}
+
+// GetAllUsers implements UserRepoItf.
+func (u *UserRepo) GetAllUsers(ctx context.Context, filter model.UserFilter) ([]*model.User, error) {
+	var users []*model.User
+
+	query, args := newQueryBuilder(`SELECT * FROM users`).
+		Suffix(`ORDER BY id LIMIT ? OFFSET ?`, filter.Limit, filter.Offset).
+		Build()
+
+	if err := u.db.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// UpdateUserRole implements UserRepoItf.
+func (u *UserRepo) UpdateUserRole(ctx context.Context, userId int64, role string) error {
+	res, err := u.db.ExecContext(ctx, `UPDATE users SET role = ?, updated_at = ? WHERE id = ?`, role, time.Now(), userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return customError.ErrRowsAffected
+	}
+
+	if rows != 1 {
+		return customError.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetUserBanned implements UserRepoItf. A null bannedAt lifts the ban.
+func (u *UserRepo) SetUserBanned(ctx context.Context, userId int64, bannedAt sql.NullTime) error {
+	res, err := u.db.ExecContext(ctx, `UPDATE users SET banned_at = ? WHERE id = ?`, bannedAt, userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return customError.ErrRowsAffected
+	}
+
+	if rows != 1 {
+		return customError.ErrUserNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/rbac"
+)
+
+// AdminUsecaseItf holds the moderation and user management actions. Every
+// method checks the permission of the actor itself, the route middleware is
+// only a first line of defence.
+type AdminUsecaseItf interface {
+	GetAllUsers(ctx context.Context, actor model.Actor, filter model.UserFilter) ([]*model.UserResponse, error)
+	UpdateUserRole(ctx context.Context, actor model.Actor, userId int64, req *model.UpdateRoleRequest) (*model.UserResponse, error)
+	BanUser(ctx context.Context, actor model.Actor, userId int64) (*model.UserResponse, error)
+	UnbanUser(ctx context.Context, actor model.Actor, userId int64) (*model.UserResponse, error)
+	RemovePost(ctx context.Context, actor model.Actor, postID int64) error
+	RemoveComment(ctx context.Context, actor model.Actor, commentID int64) error
+}
+
+type AdminUsecase struct {
+	userRepo    repository.UserRepoItf
+	tokenRepo   repository.TokenRepoItf
+	commentRepo repository.CommentRepoItf
+	posts       PostUsecaseItf
+}
+
+func NewAdminUsecase(userRepo repository.UserRepoItf, tokenRepo repository.TokenRepoItf,
+	commentRepo repository.CommentRepoItf, posts PostUsecaseItf) AdminUsecaseItf {
+	return &AdminUsecase{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		commentRepo: commentRepo,
+		posts:       posts,
+	}
+}
+
+// GetAllUsers implements AdminUsecaseItf.
+func (a *AdminUsecase) GetAllUsers(ctx context.Context, actor model.Actor, filter model.UserFilter) ([]*model.UserResponse, error) {
+	if !actor.Role.Can(rbac.PermManageUsers) {
+		return nil, customError.ErrPermissionDenied
+	}
+
+	users, err := a.userRepo.GetAllUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	usersResp := make([]*model.UserResponse, 0, len(users))
+	for _, user := range users {
+		usersResp = append(usersResp, convertToUserRespone(user))
+	}
+
+	return usersResp, nil
+}
+
+// UpdateUserRole implements AdminUsecaseItf. The access tokens of the user
+// are revoked so the new role is picked up at their next refresh instead of
+// when the tokens expire.
+func (a *AdminUsecase) UpdateUserRole(ctx context.Context, actor model.Actor, userId int64, req *model.UpdateRoleRequest) (*model.UserResponse, error) {
+	if !actor.Role.Can(rbac.PermManageUsers) {
+		return nil, customError.ErrPermissionDenied
+	}
+
+	// an admin demoting themselves could leave nobody able to manage users
+	if actor.UserID == userId {
+		return nil, customError.ErrPermissionDenied
+	}
+
+	role, err := rbac.ParseRole(req.Role)
+	if err != nil {
+		return nil, customError.ErrInvalidRole
+	}
+
+	if err := a.userRepo.UpdateUserRole(ctx, userId, string(role)); err != nil {
+		return nil, err
+	}
+
+	// token iat claims have a one second resolution
+	if err := a.userRepo.RevokeUserSessions(ctx, userId, time.Now().Truncate(time.Second)); err != nil {
+		return nil, err
+	}
+
+	user, err := a.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToUserRespone(user), nil
+}
+
+// BanUser implements AdminUsecaseItf. Every session of the user is ended and
+// they cannot log in again until the ban is lifted.
+func (a *AdminUsecase) BanUser(ctx context.Context, actor model.Actor, userId int64) (*model.UserResponse, error) {
+	if !actor.Role.Can(rbac.PermManageUsers) || actor.UserID == userId {
+		return nil, customError.ErrPermissionDenied
+	}
+
+	user, err := a.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// admins have to be demoted before they can be banned
+	if rbac.Role(user.Role).Can(rbac.PermManageUsers) {
+		return nil, customError.ErrPermissionDenied
+	}
+
+	now := time.Now()
+
+	if err := a.userRepo.SetUserBanned(ctx, userId, sql.NullTime{Time: now, Valid: true}); err != nil {
+		return nil, err
+	}
+
+	if err := a.tokenRepo.RevokeUserRefreshTokens(ctx, userId, now); err != nil {
+		return nil, err
+	}
+
+	if err := a.userRepo.RevokeUserSessions(ctx, userId, now.Truncate(time.Second)); err != nil {
+		return nil, err
+	}
+
+	user.BannedAt = sql.NullTime{Time: now, Valid: true}
+
+	return convertToUserRespone(user), nil
+}
+
+// UnbanUser implements AdminUsecaseItf.
+func (a *AdminUsecase) UnbanUser(ctx context.Context, actor model.Actor, userId int64) (*model.UserResponse, error) {
+	if !actor.Role.Can(rbac.PermManageUsers) {
+		return nil, customError.ErrPermissionDenied
+	}
+
+	if err := a.userRepo.SetUserBanned(ctx, userId, sql.NullTime{}); err != nil {
+		return nil, err
+	}
+
+	user, err := a.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToUserRespone(user), nil
+}
+
+// RemovePost implements AdminUsecaseItf. The post goes through
+// PostUsecase.DeletePost like a removal by its author.
+func (a *AdminUsecase) RemovePost(ctx context.Context, actor model.Actor, postID int64) error {
+	if !actor.Role.Can(rbac.PermRemoveAnyPost) {
+		return customError.ErrPermissionDenied
+	}
+
+	return a.posts.DeletePost(ctx, postID, actor)
+}
+
+// RemoveComment implements AdminUsecaseItf. The comment goes through
+// PostUsecase.DeleteComment like a removal by its author.
+func (a *AdminUsecase) RemoveComment(ctx context.Context, actor model.Actor, commentID int64) error {
+	if !actor.Role.Can(rbac.PermRemoveAnyComment) {
+		return customError.ErrPermissionDenied
+	}
+
+	comment, err := a.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	return a.posts.DeleteComment(ctx, comment.PostID, commentID, actor)
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/cursor"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/util"
+)
+
+// BlockUsecaseItf manages the users a user blocked or muted. Two users where
+// one blocked the other do not see each other and cannot comment, vote,
+// react or follow between them. Muting a user only leaves their posts out of
+// the feeds of the muter.
+type BlockUsecaseItf interface {
+	Block(ctx context.Context, userID int64, blockerID int64) error
+	Unblock(ctx context.Context, userID int64, blockerID int64) error
+	Mute(ctx context.Context, userID int64, muterID int64) error
+	Unmute(ctx context.Context, userID int64, muterID int64) error
+	GetBlockedUsers(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUserResponse, string, error)
+	GetMutedUsers(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUserResponse, string, error)
+}
+
+type BlockUsecase struct {
+	blockRepo repository.BlockRepoItf
+	userRepo  repository.UserRepoItf
+}
+
+func NewBlockUsecase(blockRepo repository.BlockRepoItf, userRepo repository.UserRepoItf) BlockUsecaseItf {
+	return &BlockUsecase{
+		blockRepo: blockRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// isBlocked reports whether one of the users blocked the other.
+func isBlocked(ctx context.Context, blocks repository.BlockRepoItf, userID int64, otherID int64) (bool, error) {
+	if userID == otherID {
+		return false, nil
+	}
+
+	return blocks.IsBlocked(ctx, userID, otherID)
+}
+
+// checkNotBlocked returns ErrBlocked when one of the users blocked the
+// other, it guards the interactions between two users.
+func checkNotBlocked(ctx context.Context, blocks repository.BlockRepoItf, userID int64, otherID int64) error {
+	blocked, err := isBlocked(ctx, blocks, userID, otherID)
+	if err != nil {
+		return err
+	}
+
+	if blocked {
+		return fmt.Errorf("user %d: %w", otherID, customError.ErrBlocked)
+	}
+
+	return nil
+}
+
+// checkUserVisible returns ErrUserNotFound when the user does not exist or
+// when the user and the viewer blocked one another.
+func checkUserVisible(ctx context.Context, users repository.UserRepoItf, blocks repository.BlockRepoItf,
+	viewerID int64, userID int64) error {
+	if _, err := users.GetUserById(ctx, userID); err != nil {
+		return err
+	}
+
+	blocked, err := isBlocked(ctx, blocks, viewerID, userID)
+	if err != nil {
+		return err
+	}
+
+	if blocked {
+		return customError.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// getVisiblePost returns the post unless its author and the viewer blocked
+// one another, the post is then hidden as if it did not exist.
+func getVisiblePost(ctx context.Context, posts repository.PostRepoItf, blocks repository.BlockRepoItf,
+	postID int64, viewerID int64) (*model.Post, error) {
+	post, err := posts.GetPostByID(ctx, postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := isBlocked(ctx, blocks, viewerID, post.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if blocked {
+		return nil, fmt.Errorf("post %d: %w", postID, customError.ErrNotFound)
+	}
+
+	return post, nil
+}
+
+// Block implements BlockUsecaseItf. Blocking a user twice keeps the first
+// block.
+func (uc *BlockUsecase) Block(ctx context.Context, userID int64, blockerID int64) error {
+	if err := uc.checkTarget(ctx, userID, blockerID); err != nil {
+		return err
+	}
+
+	return uc.blockRepo.Block(ctx, blockerID, userID)
+}
+
+// Unblock implements BlockUsecaseItf.
+func (uc *BlockUsecase) Unblock(ctx context.Context, userID int64, blockerID int64) error {
+	return uc.blockRepo.Unblock(ctx, blockerID, userID)
+}
+
+// Mute implements BlockUsecaseItf. Muting a user twice keeps the first mute.
+func (uc *BlockUsecase) Mute(ctx context.Context, userID int64, muterID int64) error {
+	if err := uc.checkTarget(ctx, userID, muterID); err != nil {
+		return err
+	}
+
+	return uc.blockRepo.Mute(ctx, muterID, userID)
+}
+
+// Unmute implements BlockUsecaseItf.
+func (uc *BlockUsecase) Unmute(ctx context.Context, userID int64, muterID int64) error {
+	return uc.blockRepo.Unmute(ctx, muterID, userID)
+}
+
+// checkTarget makes sure userID is an existing user other than actorID.
+func (uc *BlockUsecase) checkTarget(ctx context.Context, userID int64, actorID int64) error {
+	if userID == actorID {
+		return customError.ErrRestrictSelf
+	}
+
+	_, err := uc.userRepo.GetUserById(ctx, userID)
+
+	return err
+}
+
+// GetBlockedUsers implements BlockUsecaseItf.
+func (uc *BlockUsecase) GetBlockedUsers(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUserResponse, string, error) {
+	return pageRestrictions(ctx, userID, filter, uc.blockRepo.GetBlockedUsers)
+}
+
+// GetMutedUsers implements BlockUsecaseItf.
+func (uc *BlockUsecase) GetMutedUsers(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUserResponse, string, error) {
+	return pageRestrictions(ctx, userID, filter, uc.blockRepo.GetMutedUsers)
+}
+
+// pageRestrictions fetches a page of the blocks or mutes of the user through
+// list and builds the cursor of the next page.
+func pageRestrictions(ctx context.Context, userID int64, filter model.RestrictionFilter,
+	list func(ctx context.Context, userID int64, filter model.RestrictionFilter) ([]*model.RestrictedUser, error)) ([]*model.RestrictedUserResponse, string, error) {
+	limit := filter.Limit
+	if limit < 1 {
+		limit = util.DefaultPageLimit
+	}
+
+	// the extra user tells whether there is a next page
+	filter.Limit = limit + 1
+
+	users, err := list(ctx, userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(users) > limit {
+		users = users[:limit]
+
+		nextCursor, err = cursor.Encode(model.RestrictionCursor{ID: users[limit-1].EntryID})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	usersResp := make([]*model.RestrictedUserResponse, 0, len(users))
+	for _, user := range users {
+		usersResp = append(usersResp, &model.RestrictedUserResponse{
+			ID:    user.ID,
+			Name:  user.Name,
+			Photo: user.Photo.String,
+			Since: user.Since,
+		})
+	}
+
+	return usersResp, nextCursor, nil
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/cursor"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/util"
+)
+
+// BookmarkUsecaseItf manages the posts a user saved, optionally sorted into
+// named collections.
+type BookmarkUsecaseItf interface {
+	Bookmark(ctx context.Context, postID int64, req *model.BookmarkRequest, userID int64) error
+	RemoveBookmark(ctx context.Context, postID int64, userID int64) error
+	GetBookmarks(ctx context.Context, userID int64, filter model.BookmarkFilter) ([]*model.BookmarkResponse, string, error)
+	GetCollections(ctx context.Context, userID int64) ([]*model.BookmarkCollection, error)
+}
+
+type BookmarkUsecase struct {
+	bookmarkRepo repository.BookmarkRepoItf
+	postRepo     repository.PostRepoItf
+	blocks       repository.BlockRepoItf
+	reactions    ReactionUsecaseItf
+}
+
+func NewBookmarkUsecase(bookmarkRepo repository.BookmarkRepoItf, postRepo repository.PostRepoItf,
+	blocks repository.BlockRepoItf, reactions ReactionUsecaseItf) BookmarkUsecaseItf {
+	return &BookmarkUsecase{
+		bookmarkRepo: bookmarkRepo,
+		postRepo:     postRepo,
+		blocks:       blocks,
+		reactions:    reactions,
+	}
+}
+
+// Bookmark implements BookmarkUsecaseItf. Bookmarking a saved post again
+// moves it to the collection of the request.
+func (uc *BookmarkUsecase) Bookmark(ctx context.Context, postID int64, req *model.BookmarkRequest, userID int64) error {
+	var collection string
+	if req != nil {
+		collection = strings.TrimSpace(req.Collection)
+	}
+
+	if utf8.RuneCountInString(collection) > model.MaxCollectionLength {
+		return customError.ErrInvalidCollection
+	}
+
+	if _, err := getVisiblePost(ctx, uc.postRepo, uc.blocks, postID, userID); err != nil {
+		return err
+	}
+
+	return uc.bookmarkRepo.SetBookmark(ctx, userID, postID, collection)
+}
+
+// RemoveBookmark implements BookmarkUsecaseItf. Removing a bookmark that
+// does not exist is not an error.
+func (uc *BookmarkUsecase) RemoveBookmark(ctx context.Context, postID int64, userID int64) error {
+	return uc.bookmarkRepo.DeleteBookmark(ctx, userID, postID)
+}
+
+// GetBookmarks implements BookmarkUsecaseItf.
+func (uc *BookmarkUsecase) GetBookmarks(ctx context.Context, userID int64, filter model.BookmarkFilter) ([]*model.BookmarkResponse, string, error) {
+	filter.Collection = strings.TrimSpace(filter.Collection)
+
+	limit := filter.Limit
+	if limit < 1 {
+		limit = util.DefaultPageLimit
+	}
+
+	// the extra bookmark tells whether there is a next page
+	filter.Limit = limit + 1
+
+	posts, err := uc.bookmarkRepo.GetBookmarks(ctx, userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(posts) > limit {
+		posts = posts[:limit]
+
+		nextCursor, err = cursor.Encode(model.BookmarkCursor{ID: posts[limit-1].BookmarkID})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	bookmarksResp := make([]*model.BookmarkResponse, 0, len(posts))
+	postsResp := make([]*model.PostResponse, 0, len(posts))
+	for _, post := range posts {
+		postResp := convertToPostRespone(&post.Post)
+
+		bookmarksResp = append(bookmarksResp, &model.BookmarkResponse{
+			Collection:   post.Collection,
+			BookmarkedAt: post.BookmarkedAt,
+			Post:         postResp,
+		})
+		postsResp = append(postsResp, postResp)
+	}
+
+	if err := uc.reactions.AttachToPosts(ctx, userID, postsResp...); err != nil {
+		return nil, "", err
+	}
+
+	return bookmarksResp, nextCursor, nil
+}
+
+// GetCollections implements BookmarkUsecaseItf. Posts saved without a
+// collection are not counted in any.
+func (uc *BookmarkUsecase) GetCollections(ctx context.Context, userID int64) ([]*model.BookmarkCollection, error) {
+	return uc.bookmarkRepo.GetCollections(ctx, userID)
+}
@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/cursor"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/util"
+)
+
+// FollowUsecaseItf manages who follows whom and the home feed built from it.
+type FollowUsecaseItf interface {
+	Follow(ctx context.Context, userID int64, followerID int64) (*model.FollowStatus, error)
+	Unfollow(ctx context.Context, userID int64, followerID int64) (*model.FollowStatus, error)
+	GetFollowers(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUserResponse, string, error)
+	GetFollowing(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUserResponse, string, error)
+	GetFeed(ctx context.Context, userID int64, filter model.PostFilter) ([]model.PostResponse, string, error)
+}
+
+type FollowUsecase struct {
+	followRepo repository.FollowRepoItf
+	userRepo   repository.UserRepoItf
+	feed       repository.FeedSource
+	blocks     repository.BlockRepoItf
+	reactions  ReactionUsecaseItf
+	notifier   NotificationUsecaseItf
+}
+
+func NewFollowUsecase(followRepo repository.FollowRepoItf, userRepo repository.UserRepoItf,
+	feed repository.FeedSource, blocks repository.BlockRepoItf, reactions ReactionUsecaseItf,
+	notifier NotificationUsecaseItf) FollowUsecaseItf {
+	return &FollowUsecase{
+		followRepo: followRepo,
+		userRepo:   userRepo,
+		feed:       feed,
+		blocks:     blocks,
+		reactions:  reactions,
+		notifier:   notifier,
+	}
+}
+
+// Follow implements FollowUsecaseItf. It returns the relationship between
+// the follower and the user after the follow.
+func (uc *FollowUsecase) Follow(ctx context.Context, userID int64, followerID int64) (*model.FollowStatus, error) {
+	if userID == followerID {
+		return nil, customError.ErrFollowSelf
+	}
+
+	if _, err := uc.userRepo.GetUserById(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := checkNotBlocked(ctx, uc.blocks, followerID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := uc.followRepo.Follow(ctx, followerID, userID); err != nil {
+		return nil, err
+	}
+
+	// the follows of a user are gathered on the user themselves
+	uc.notifier.Notify(ctx, model.NotificationEvent{
+		UserID:     userID,
+		ActorID:    followerID,
+		Type:       model.NotificationFollow,
+		TargetType: model.NotificationTargetUser,
+		TargetID:   userID,
+	})
+
+	return uc.getFollowStatus(ctx, followerID, userID)
+}
+
+// Unfollow implements FollowUsecaseItf. Unfollowing a user that is not
+// followed is not an error.
+func (uc *FollowUsecase) Unfollow(ctx context.Context, userID int64, followerID int64) (*model.FollowStatus, error) {
+	if _, err := uc.userRepo.GetUserById(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := uc.followRepo.Unfollow(ctx, followerID, userID); err != nil {
+		return nil, err
+	}
+
+	return uc.getFollowStatus(ctx, followerID, userID)
+}
+
+func (uc *FollowUsecase) getFollowStatus(ctx context.Context, viewerID int64, userID int64) (*model.FollowStatus, error) {
+	status, err := uc.followRepo.GetFollowStatus(ctx, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Mutual = status.IsFollowing && status.FollowsYou
+
+	return status, nil
+}
+
+// GetFollowers implements FollowUsecaseItf.
+func (uc *FollowUsecase) GetFollowers(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUserResponse, string, error) {
+	return uc.pageFollows(ctx, userID, filter, uc.followRepo.GetFollowers)
+}
+
+// GetFollowing implements FollowUsecaseItf.
+func (uc *FollowUsecase) GetFollowing(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUserResponse, string, error) {
+	return uc.pageFollows(ctx, userID, filter, uc.followRepo.GetFollowing)
+}
+
+// pageFollows fetches a page of the follows of the user through list and
+// builds the cursor of the next page.
+func (uc *FollowUsecase) pageFollows(ctx context.Context, userID int64, filter model.FollowFilter,
+	list func(ctx context.Context, userID int64, filter model.FollowFilter) ([]*model.FollowUser, error)) ([]*model.FollowUserResponse, string, error) {
+	// an unknown user is a 404, not an empty page
+	if err := checkUserVisible(ctx, uc.userRepo, uc.blocks, filter.ViewerID, userID); err != nil {
+		return nil, "", err
+	}
+
+	limit := filter.Limit
+	if limit < 1 {
+		limit = util.DefaultPageLimit
+	}
+
+	// the extra follow tells whether there is a next page
+	filter.Limit = limit + 1
+
+	users, err := list(ctx, userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(users) > limit {
+		users = users[:limit]
+
+		nextCursor, err = cursor.Encode(model.FollowCursor{ID: users[limit-1].FollowID})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	usersResp := make([]*model.FollowUserResponse, 0, len(users))
+	for _, user := range users {
+		usersResp = append(usersResp, &model.FollowUserResponse{
+			ID:         user.ID,
+			Name:       user.Name,
+			Photo:      user.Photo.String,
+			FollowedAt: user.FollowedAt,
+			FollowStatus: model.FollowStatus{
+				IsFollowing: user.IsFollowing,
+				FollowsYou:  user.FollowsYou,
+				Mutual:      user.IsFollowing && user.FollowsYou,
+			},
+		})
+	}
+
+	return usersResp, nextCursor, nil
+}
+
+// GetFeed implements FollowUsecaseItf. The feed holds the posts of the
+// accounts the user follows and is paged like the main listing.
+func (uc *FollowUsecase) GetFeed(ctx context.Context, userID int64, filter model.PostFilter) ([]model.PostResponse, string, error) {
+	filter.ViewerID = userID
+	filter.ExcludeMuted = true
+
+	posts, nextCursor, err := pagePosts(ctx, filter, func(ctx context.Context, filter model.PostFilter) ([]*model.Post, error) {
+		return uc.feed.GetFeed(ctx, userID, filter)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := attachPostReactions(ctx, uc.reactions, userID, posts); err != nil {
+		return nil, "", err
+	}
+
+	return posts, nextCursor, nil
+}
@@ -0,0 +1,281 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/cursor"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/mention"
+	"github.com/federicodosantos/socialize/pkg/realtime"
+	"github.com/federicodosantos/socialize/pkg/util"
+)
+
+// NotificationUsecaseItf tells users what happened to their posts, comments
+// and account. Notify and NotifyMentions are called by the other usecases
+// once a change is saved, they only log their failures so a notification
+// never fails the change that caused it.
+type NotificationUsecaseItf interface {
+	Notify(ctx context.Context, event model.NotificationEvent)
+	NotifyMentions(ctx context.Context, event model.NotificationEvent, text string)
+
+	GetNotifications(ctx context.Context, userID int64, filter model.NotificationFilter) (*model.NotificationPage, string, error)
+	GetUnreadCount(ctx context.Context, userID int64) (*model.UnreadCount, error)
+	MarkRead(ctx context.Context, notificationID int64, userID int64) (*model.UnreadCount, error)
+	MarkAllRead(ctx context.Context, userID int64) (*model.UnreadCount, error)
+
+	GetPreferences(ctx context.Context, userID int64) (map[string]bool, error)
+	UpdatePreferences(ctx context.Context, userID int64, prefs map[string]bool) (map[string]bool, error)
+}
+
+type NotificationUsecase struct {
+	notificationRepo repository.NotificationRepoItf
+	userRepo         repository.UserRepoItf
+	blocks           repository.BlockRepoItf
+	realtime         RealtimeUsecaseItf
+}
+
+func NewNotificationUsecase(notificationRepo repository.NotificationRepoItf, userRepo repository.UserRepoItf,
+	blocks repository.BlockRepoItf, realtime RealtimeUsecaseItf) NotificationUsecaseItf {
+	return &NotificationUsecase{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		blocks:           blocks,
+		realtime:         realtime,
+	}
+}
+
+// Notify implements NotificationUsecaseItf. Users are not notified of their
+// own actions nor of the types they turned off. The user is then pushed the
+// notification with their new unread count.
+func (uc *NotificationUsecase) Notify(ctx context.Context, event model.NotificationEvent) {
+	if event.UserID == event.ActorID {
+		return
+	}
+
+	enabled, err := uc.notificationRepo.IsEnabled(ctx, event.UserID, event.Type)
+	if err != nil {
+		log.Printf("cannot read the notification preferences of user %d: %s", event.UserID, err)
+		return
+	}
+
+	if !enabled {
+		return
+	}
+
+	if err := uc.notificationRepo.AddEvent(ctx, &event); err != nil {
+		log.Printf("cannot notify user %d of a %s on %s %d: %s",
+			event.UserID, event.Type, event.TargetType, event.TargetID, err)
+		return
+	}
+
+	unread, err := uc.notificationRepo.CountUnread(ctx, event.UserID)
+	if err != nil {
+		log.Printf("cannot count the unread notifications of user %d: %s", event.UserID, err)
+		return
+	}
+
+	uc.realtime.Publish(ctx, realtime.UserChannel(event.UserID), realtime.EventNotification, event.ActorID,
+		model.NotificationUpdate{
+			Type:        event.Type,
+			TargetType:  event.TargetType,
+			TargetID:    event.TargetID,
+			PostID:      event.PostID,
+			ActorID:     event.ActorID,
+			UnreadCount: unread,
+		})
+}
+
+// NotifyMentions implements NotificationUsecaseItf. It sends a mention to
+// every user mentioned in text, event is the mention without its user.
+// Unknown users and users blocked either way are skipped.
+func (uc *NotificationUsecase) NotifyMentions(ctx context.Context, event model.NotificationEvent, text string) {
+	event.Type = model.NotificationMention
+
+	for _, userID := range mention.UserIDs(text) {
+		err := checkUserVisible(ctx, uc.userRepo, uc.blocks, event.ActorID, userID)
+		if errors.Is(err, customError.ErrUserNotFound) {
+			continue
+		}
+
+		if err != nil {
+			log.Printf("cannot check mentioned user %d: %s", userID, err)
+			continue
+		}
+
+		event.UserID = userID
+		uc.Notify(ctx, event)
+	}
+}
+
+// GetNotifications implements NotificationUsecaseItf. The page comes with the
+// unread count of the user so a client does not need a second request.
+func (uc *NotificationUsecase) GetNotifications(ctx context.Context, userID int64, filter model.NotificationFilter) (*model.NotificationPage, string, error) {
+	limit := filter.Limit
+	if limit < 1 {
+		limit = util.DefaultPageLimit
+	}
+
+	// the extra notification tells whether there is a next page
+	filter.Limit = limit + 1
+
+	notifications, err := uc.notificationRepo.GetNotifications(ctx, userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[limit-1]
+
+		nextCursor, err = cursor.Encode(model.NotificationCursor{UpdatedAt: last.UpdatedAt.Unix(), ID: last.ID})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	unread, err := uc.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	page := &model.NotificationPage{
+		UnreadCount:   unread,
+		Notifications: make([]*model.NotificationResponse, 0, len(notifications)),
+	}
+
+	for _, notification := range notifications {
+		page.Notifications = append(page.Notifications, convertToNotificationResponse(notification))
+	}
+
+	return page, nextCursor, nil
+}
+
+func convertToNotificationResponse(notification *model.Notification) *model.NotificationResponse {
+	return &model.NotificationResponse{
+		ID:         notification.ID,
+		Type:       notification.Type,
+		TargetType: notification.TargetType,
+		TargetID:   notification.TargetID,
+		PostID:     notification.PostID.Int64,
+		Actor: model.NotificationActor{
+			ID:    notification.ActorID,
+			Name:  notification.ActorName,
+			Photo: notification.ActorPhoto.String,
+		},
+		ActorCount: notification.ActorCount,
+		Message:    notificationMessage(notification),
+		Read:       notification.ReadAt.Valid,
+		CreatedAt:  notification.CreatedAt,
+		UpdatedAt:  notification.UpdatedAt,
+	}
+}
+
+// notificationMessage describes a notification, the actors of a gathered
+// notification are counted rather than named.
+func notificationMessage(notification *model.Notification) string {
+	who := notification.ActorName
+	if notification.ActorCount > 1 {
+		who = fmt.Sprintf("%d people", notification.ActorCount)
+	}
+
+	var what string
+	switch notification.Type {
+	case model.NotificationComment:
+		what = "commented on your post"
+	case model.NotificationReply:
+		what = "replied to your comment"
+	case model.NotificationVote:
+		what = "upvoted your " + notification.TargetType
+	case model.NotificationFollow:
+		what = "followed you"
+	case model.NotificationMention:
+		what = "mentioned you in a " + notification.TargetType
+	default:
+		what = "interacted with you"
+	}
+
+	return who + " " + what
+}
+
+// GetUnreadCount implements NotificationUsecaseItf.
+func (uc *NotificationUsecase) GetUnreadCount(ctx context.Context, userID int64) (*model.UnreadCount, error) {
+	unread, err := uc.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UnreadCount{UnreadCount: unread}, nil
+}
+
+// MarkRead implements NotificationUsecaseItf. It returns the unread count
+// left.
+func (uc *NotificationUsecase) MarkRead(ctx context.Context, notificationID int64, userID int64) (*model.UnreadCount, error) {
+	if err := uc.notificationRepo.MarkRead(ctx, userID, notificationID); err != nil {
+		return nil, err
+	}
+
+	return uc.GetUnreadCount(ctx, userID)
+}
+
+// MarkAllRead implements NotificationUsecaseItf. It returns the unread count
+// left, which is only above zero when new notifications came in meanwhile.
+func (uc *NotificationUsecase) MarkAllRead(ctx context.Context, userID int64) (*model.UnreadCount, error) {
+	if err := uc.notificationRepo.MarkAllRead(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return uc.GetUnreadCount(ctx, userID)
+}
+
+// GetPreferences implements NotificationUsecaseItf. It tells for every type
+// whether the user gets its notifications.
+func (uc *NotificationUsecase) GetPreferences(ctx context.Context, userID int64) (map[string]bool, error) {
+	saved, err := uc.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := make(map[string]bool, len(model.NotificationTypes))
+	for _, notificationType := range model.NotificationTypes {
+		prefs[notificationType] = true
+	}
+
+	for _, pref := range saved {
+		if _, ok := prefs[pref.Type]; ok {
+			prefs[pref.Type] = pref.Enabled
+		}
+	}
+
+	return prefs, nil
+}
+
+// UpdatePreferences implements NotificationUsecaseItf. The types missing from
+// prefs keep their preference.
+func (uc *NotificationUsecase) UpdatePreferences(ctx context.Context, userID int64, prefs map[string]bool) (map[string]bool, error) {
+	for notificationType := range prefs {
+		if !slices.Contains(model.NotificationTypes, notificationType) {
+			return nil, fmt.Errorf("%q: %w", notificationType, customError.ErrUnknownNotificationType)
+		}
+	}
+
+	// the changes are saved in the order of the types, not of the map
+	changes := make([]model.NotificationPreference, 0, len(prefs))
+	for _, notificationType := range model.NotificationTypes {
+		if enabled, ok := prefs[notificationType]; ok {
+			changes = append(changes, model.NotificationPreference{Type: notificationType, Enabled: enabled})
+		}
+	}
+
+	if err := uc.notificationRepo.SetPreferences(ctx, userID, changes); err != nil {
+		return nil, err
+	}
+
+	return uc.GetPreferences(ctx, userID)
+}
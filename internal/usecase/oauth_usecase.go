@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/oidc"
+	"github.com/federicodosantos/socialize/pkg/rbac"
+	"github.com/federicodosantos/socialize/pkg/token"
+)
+
+// StartOAuth implements UserUCItf. The returned session carries the state,
+// nonce and PKCE verifier of this attempt and must be handed back to
+// OAuthCallback, the caller keeps it in a cookie so the callback is bound to
+// the browser that started the login.
+func (u *UserUsecase) StartOAuth(ctx context.Context, provider string) (*model.OAuthAuthorization, error) {
+	oauthProvider, ok := u.oauthProviders[provider]
+	if !ok {
+		return nil, customError.ErrUnknownOAuthProvider
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	codeVerifier, codeChallenge, err := oidc.NewPKCE()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := oauthProvider.AuthCodeURL(ctx, state, nonce, codeChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := json.Marshal(model.OAuthSession{
+		Provider:     provider,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(u.config.OAuthStateTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OAuthAuthorization{
+		AuthURL:   authURL,
+		Session:   u.tokenSigner.Sign(model.TokenPurposeOAuthState, base64.RawURLEncoding.EncodeToString(session)),
+		ExpiresIn: u.config.OAuthStateTTL,
+	}, nil
+}
+
+// OAuthCallback implements UserUCItf. The provider identity is matched to a
+// user by its subject, or else linked to the account owning the same verified
+// email, or else a new account is created. The login then continues like a
+// password login, including the two-factor challenge.
+func (u *UserUsecase) OAuthCallback(ctx context.Context, req *model.OAuthCallbackRequest) (*model.LoginResponse, error) {
+	oauthProvider, ok := u.oauthProviders[req.Provider]
+	if !ok {
+		return nil, customError.ErrUnknownOAuthProvider
+	}
+
+	session, err := u.readOAuthSession(req.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Provider != req.Provider ||
+		subtle.ConstantTimeCompare([]byte(session.State), []byte(req.State)) != 1 {
+		return nil, customError.ErrInvalidOAuthState
+	}
+
+	if req.Error != "" || req.Code == "" {
+		return nil, customError.ErrOAuthDenied
+	}
+
+	claims, err := oauthProvider.Exchange(ctx, req.Code, session.CodeVerifier, session.Nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidIDToken) || errors.Is(err, oidc.ErrNonceMismatch) {
+			return nil, fmt.Errorf("%w: %v", customError.ErrInvalidOAuthState, err)
+		}
+		return nil, err
+	}
+
+	user, err := u.userRepo.GetUserByIdentity(ctx, req.Provider, claims.Subject)
+	if err != nil {
+		if !errors.Is(err, customError.ErrUserNotFound) {
+			return nil, err
+		}
+
+		user, err = u.linkOAuthIdentity(ctx, req.Provider, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return u.completeLogin(ctx, user)
+}
+
+func (u *UserUsecase) readOAuthSession(signed string) (*model.OAuthSession, error) {
+	plain, ok := u.tokenSigner.Verify(model.TokenPurposeOAuthState, signed)
+	if !ok {
+		return nil, customError.ErrInvalidOAuthState
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(plain)
+	if err != nil {
+		return nil, customError.ErrInvalidOAuthState
+	}
+
+	var session model.OAuthSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, customError.ErrInvalidOAuthState
+	}
+
+	if time.Now().Unix() > session.ExpiresAt {
+		return nil, customError.ErrInvalidOAuthState
+	}
+
+	return &session, nil
+}
+
+// linkOAuthIdentity attaches a provider identity seen for the first time to
+// the account with the same email, creating the account if there is none.
+// Only emails the provider verified are trusted, and accounts that never
+// proved they own their email are not linked, otherwise someone could
+// register the address first and share the account with its real owner.
+func (u *UserUsecase) linkOAuthIdentity(ctx context.Context, provider string, claims *oidc.Claims) (*model.User, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, customError.ErrOAuthEmailNotVerified
+	}
+
+	now := time.Now()
+
+	user, err := u.userRepo.GetUserByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if !user.VerifiedAt.Valid {
+			return nil, fmt.Errorf("email : %s already exists: %w", claims.Email, customError.ErrEmailExist)
+		}
+	case errors.Is(err, customError.ErrEmailNotFound):
+		user, err = u.createOAuthUser(ctx, claims, now)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	err = u.userRepo.CreateUserIdentity(ctx, &model.UserIdentity{
+		UserID:    user.ID,
+		Provider:  provider,
+		Subject:   claims.Subject,
+		Email:     sql.NullString{String: claims.Email, Valid: true},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createOAuthUser creates a verified account for a provider identity. It gets
+// a random password nobody knows, a password can be set later through the
+// forgot password flow.
+func (u *UserUsecase) createOAuthUser(ctx context.Context, claims *oidc.Claims, now time.Time) (*model.User, error) {
+	randomPassword, _, err := token.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := u.hasher.Hash(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	user := &model.User{
+		Name:      name,
+		Email:     claims.Email,
+		Password:  hashedPassword,
+		Role:      string(rbac.RoleUser),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := u.userRepo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	u.search.SyncDocument(ctx, model.SearchTypeUser, user.ID)
+
+	if err := u.userRepo.MarkUserVerified(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
+	user.VerifiedAt = sql.NullTime{Time: now, Valid: true}
+
+	if claims.Picture != "" {
+		user.Photo = sql.NullString{String: claims.Picture, Valid: true}
+
+		if err := u.userRepo.UpdateUserPhoto(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
@@ -3,34 +3,77 @@ package usecase
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/federicodosantos/socialize/internal/model"
 	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/cursor"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/rbac"
+	"github.com/federicodosantos/socialize/pkg/realtime"
+	"github.com/federicodosantos/socialize/pkg/util"
 )
 
 type PostUsecaseItf interface {
 	CreatePost(ctx context.Context, req *model.PostCreate, userID int64) (*model.PostResponse, error)
-	GetAllPost(ctx context.Context, filter model.PostFilter) ([]model.PostResponse, error)
-	GetPostByID(ctx context.Context, postID int64) (*model.PostResponse, error)
-	DeletePost(ctx context.Context, postID int64) error
+	GetAllPost(ctx context.Context, filter model.PostFilter) ([]model.PostResponse, string, error)
+	GetPostByID(ctx context.Context, postID int64, viewerID int64) (*model.PostResponse, error)
+	UpdatePost(ctx context.Context, postID int64, req *model.PostUpdate, actor model.Actor) (*model.PostResponse, error)
+	GetPostHistory(ctx context.Context, postID int64, viewerID int64) ([]*model.PostRevisionResponse, error)
+	GetCommentHistory(ctx context.Context, postID, commentID int64, viewerID int64) ([]*model.CommentRevisionResponse, error)
+	DeletePost(ctx context.Context, postID int64, actor model.Actor) error
 
-	CreateComment(ctx context.Context, req *model.CommentCreate, userID int64) error
-	DeleteComment(ctx context.Context, id int64) error
+	GetComments(ctx context.Context, postID int64, filter model.CommentTreeFilter) ([]*model.CommentResponse, string, error)
+	CreateComment(ctx context.Context, req *model.CommentCreate, userID int64) (*model.CommentResponse, error)
+	UpdateComment(ctx context.Context, postID, commentID int64, req *model.CommentUpdate, actor model.Actor) (*model.CommentResponse, error)
+	DeleteComment(ctx context.Context, postID, commentID int64, actor model.Actor) error
 
-	CreateUpVote(ctx context.Context, postID int64, userID int64) error
-	CreateDownVote(ctx context.Context, postID int64, userID int64) error
+	CreateUpVote(ctx context.Context, postID int64, userID int64) (*model.VoteResponse, error)
+	CreateDownVote(ctx context.Context, postID int64, userID int64) (*model.VoteResponse, error)
+	RetractVote(ctx context.Context, postID int64, userID int64) (*model.VoteResponse, error)
+	CreateCommentUpVote(ctx context.Context, postID, commentID int64, userID int64) (*model.VoteResponse, error)
+	CreateCommentDownVote(ctx context.Context, postID, commentID int64, userID int64) (*model.VoteResponse, error)
+	RetractCommentVote(ctx context.Context, postID, commentID int64, userID int64) (*model.VoteResponse, error)
 }
 
 type PostUsecase struct {
 	postRepo    repository.PostRepoItf
 	commentRepo repository.CommentRepoItf
+	ranking     RankingUsecaseItf
+	search      SearchUsecaseItf
+	reactions   ReactionUsecaseItf
+	feed        repository.FeedSource
+	blocks      repository.BlockRepoItf
+	notifier    NotificationUsecaseItf
+	realtime    RealtimeUsecaseItf
 }
 
-func NewPostUsecase(postRepo repository.PostRepoItf, commentRepo repository.CommentRepoItf) PostUsecaseItf {
+func NewPostUsecase(postRepo repository.PostRepoItf, commentRepo repository.CommentRepoItf,
+	ranking RankingUsecaseItf, search SearchUsecaseItf, reactions ReactionUsecaseItf,
+	feed repository.FeedSource, blocks repository.BlockRepoItf, notifier NotificationUsecaseItf,
+	realtime RealtimeUsecaseItf) PostUsecaseItf {
 	return &PostUsecase{
 		postRepo:    postRepo,
 		commentRepo: commentRepo,
+		ranking:     ranking,
+		search:      search,
+		reactions:   reactions,
+		feed:        feed,
+		blocks:      blocks,
+		notifier:    notifier,
+		realtime:    realtime,
+	}
+}
+
+// refreshScore rescores a post after its votes or comments changed. A failure
+// is only logged, the change itself is saved and the recomputation job
+// catches the score up.
+func (uc *PostUsecase) refreshScore(ctx context.Context, postID int64) {
+	if err := uc.ranking.RefreshPost(ctx, postID); err != nil {
+		log.Printf("cannot refresh score of post %d: %s", postID, err)
 	}
 }
 
@@ -38,7 +81,7 @@ func (uc *PostUsecase) CreatePost(ctx context.Context, req *model.PostCreate, us
 	data := &model.Post{
 		Title:     req.Title,
 		Content:   req.Content,
-		Image:     sql.NullString{String: req.Image},
+		Image:     sql.NullString{String: req.Image, Valid: req.Image != ""},
 		UserID:    userID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -49,15 +92,113 @@ func (uc *PostUsecase) CreatePost(ctx context.Context, req *model.PostCreate, us
 		return nil, err
 	}
 
+	uc.refreshScore(ctx, data.ID)
+	uc.search.SyncDocument(ctx, model.SearchTypePost, data.ID)
+
+	// the post is saved, a feed missing it is only logged
+	if err := uc.feed.AddPost(ctx, data); err != nil {
+		log.Printf("cannot add post %d to the feeds: %s", data.ID, err)
+	}
+
+	uc.notifier.NotifyMentions(ctx, model.NotificationEvent{
+		ActorID:    userID,
+		TargetType: model.NotificationTargetPost,
+		TargetID:   data.ID,
+		PostID:     data.ID,
+	}, data.Title+"\n"+data.Content)
+
 	res := convertToPostRespone(data)
 
 	return res, nil
 }
 
-func (uc *PostUsecase) GetAllPost(ctx context.Context, filter model.PostFilter) ([]model.PostResponse, error) {
-	posts, err := uc.postRepo.GetAllPost(ctx, filter)
+// GetAllPost returns a page of posts and the cursor of the next page, which
+// is empty on the last page. The listing is a feed, the users muted by the
+// viewer are left out.
+func (uc *PostUsecase) GetAllPost(ctx context.Context, filter model.PostFilter) ([]model.PostResponse, string, error) {
+	filter.ExcludeMuted = true
+
+	posts, nextCursor, err := pagePosts(ctx, filter, uc.postRepo.GetAllPost)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	if err := attachPostReactions(ctx, uc.reactions, filter.ViewerID, posts); err != nil {
+		return nil, "", err
+	}
+
+	return posts, nextCursor, nil
+}
+
+func attachPostReactions(ctx context.Context, reactions ReactionUsecaseItf, viewerID int64, posts []model.PostResponse) error {
+	postsRef := make([]*model.PostResponse, 0, len(posts))
+	for i := range posts {
+		postsRef = append(postsRef, &posts[i])
+	}
+
+	return reactions.AttachToPosts(ctx, viewerID, postsRef...)
+}
+
+// flattenComments lists the comments of a tree with all their replies.
+func flattenComments(comments []*model.CommentResponse) []*model.CommentResponse {
+	var flat []*model.CommentResponse
+	for _, comment := range comments {
+		flat = append(flat, comment)
+		flat = append(flat, flattenComments(comment.Replies)...)
+	}
+
+	return flat
+}
+
+// pagePosts fetches a page of posts through list and builds the cursor of the
+// next page.
+func pagePosts(ctx context.Context, filter model.PostFilter,
+	list func(ctx context.Context, filter model.PostFilter) ([]*model.Post, error)) ([]model.PostResponse, string, error) {
+	if filter.Cursor != nil && filter.Cursor.Since != 0 {
+		filter.Since = time.Unix(filter.Cursor.Since, 0)
+	} else if filter.Window > 0 {
+		filter.Since = time.Now().Add(-filter.Window).Truncate(time.Second)
+	}
+
+	if filter.Sort == model.PostSortHot {
+		if filter.Cursor != nil && filter.Cursor.RankedAt != 0 {
+			filter.RankedAt = time.Unix(filter.Cursor.RankedAt, 0)
+		} else {
+			filter.RankedAt = time.Now().Truncate(time.Second)
+		}
+	}
+
+	limit := filter.Limit
+	if limit < 1 {
+		limit = util.DefaultPageLimit
+	}
+
+	// the extra post tells whether there is a next page
+	filter.Limit = limit + 1
+
+	posts, err := list(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[limit-1]
+
+		position := model.PostCursor{Sort: filter.Sort, Score: last.SortKey, ID: last.ID}
+		if !filter.Since.IsZero() {
+			position.Since = filter.Since.Unix()
+		}
+
+		if !filter.RankedAt.IsZero() {
+			position.RankedAt = filter.RankedAt.Unix()
+		}
+
+		nextCursor, err = cursor.Encode(position)
+		if err != nil {
+			return nil, "", err
+		}
 	}
 
 	var postsResp []model.PostResponse
@@ -65,60 +206,373 @@ func (uc *PostUsecase) GetAllPost(ctx context.Context, filter model.PostFilter)
 		postsResp = append(postsResp, *convertToPostRespone(post))
 	}
 
-	return postsResp, nil
+	return postsResp, nextCursor, nil
 }
 
 func convertToPostRespone(post *model.Post) *model.PostResponse {
 	return &model.PostResponse{
-		ID:        post.ID,
-		UserID:    post.UserID,
-		Title:     post.Title,
-		Content:   post.Content,
-		Image:     post.Image.String,
-		UserName: post.UserName,
-		UserPhoto: post.Image.String,
-		CreatedAt: post.CreatedAt,
-		UpdatedAt: post.UpdatedAt,
-		UpVote:    post.UpVote,
-		DownVote:  post.DownVote,
+		ID:           post.ID,
+		UserID:       post.UserID,
+		Title:        post.Title,
+		Content:      post.Content,
+		Image:        post.Image.String,
+		UserName:     post.UserName,
+		UserPhoto:    post.Image.String,
+		CreatedAt:    post.CreatedAt,
+		UpdatedAt:    post.UpdatedAt,
+		UpVote:       post.UpVote,
+		DownVote:     post.DownVote,
+		CommentCount: post.CommentCount,
+		MyVote:       post.MyVote,
+		IsOwner:      post.IsOwner,
+		Bookmarked:   post.Bookmarked,
+		Edited:       post.EditedAt.Valid,
+	}
+}
+
+// deletedCommentText stands in for the text of a comment tombstone.
+const deletedCommentText = "[deleted]"
+
+func convertToCommentResponse(comment *model.Comment) *model.CommentResponse {
+	// a tombstone keeps its place in the thread but not who wrote it
+	if comment.DeletedAt.Valid {
+		return &model.CommentResponse{
+			ID:         comment.ID,
+			PostID:     comment.PostID,
+			ParentID:   comment.ParentID.Int64,
+			Comment:    deletedCommentText,
+			CreatedAt:  comment.CreatedAt,
+			Deleted:    true,
+			ReplyCount: comment.ReplyCount,
+		}
+	}
+
+	return &model.CommentResponse{
+		ID:         comment.ID,
+		PostID:     comment.PostID,
+		UserID:     comment.UserID,
+		UserName:   comment.UserName,
+		UserPhoto:  comment.UserPhoto.String,
+		Comment:    comment.Comment,
+		CreatedAt:  comment.CreatedAt,
+		Edited:     comment.EditedAt.Valid,
+		ParentID:   comment.ParentID.Int64,
+		ReplyCount: comment.ReplyCount,
+		UpVote:     comment.UpVote,
+		DownVote:   comment.DownVote,
 	}
 }
 
-func (uc *PostUsecase) GetPostByID(ctx context.Context, postID int64) (*model.PostResponse, error) {
-	post, err := uc.postRepo.GetPostByID(ctx, postID)
+func (uc *PostUsecase) GetPostByID(ctx context.Context, postID int64, viewerID int64) (*model.PostResponse, error) {
+	post, err := getVisiblePost(ctx, uc.postRepo, uc.blocks, postID, viewerID)
 	if err != nil {
 		return nil, err
 	}
 
-	comments, err := uc.commentRepo.GetAllCommentsByPostId(ctx, post.ID)
+	comments, nextCursor, err := uc.loadCommentTree(ctx, post.ID, model.CommentTreeFilter{ViewerID: viewerID})
 	if err != nil {
 		return nil, err
 	}
 
-	var commentsResp []*model.CommentResponse
+	postResponse := convertToPostRespone(post)
+	postResponse.Comment = comments
+	postResponse.CommentsCursor = nextCursor
+
+	if err := uc.reactions.AttachToPosts(ctx, viewerID, postResponse); err != nil {
+		return nil, err
+	}
+
+	return postResponse, nil
+}
+
+// GetComments returns a page of the top level comments of the post, or of the
+// replies to filter.ParentID, with the replies below them loaded down to
+// filter.Depth levels.
+func (uc *PostUsecase) GetComments(ctx context.Context, postID int64, filter model.CommentTreeFilter) ([]*model.CommentResponse, string, error) {
+	if _, err := getVisiblePost(ctx, uc.postRepo, uc.blocks, postID, filter.ViewerID); err != nil {
+		return nil, "", err
+	}
+
+	if filter.ParentID != 0 {
+		parent, err := uc.commentRepo.GetCommentByID(ctx, filter.ParentID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if parent.PostID != postID {
+			return nil, "", fmt.Errorf("comment %d of post %d: %w", filter.ParentID, postID, customError.ErrNotFound)
+		}
+
+		if !parent.DeletedAt.Valid {
+			blocked, err := isBlocked(ctx, uc.blocks, filter.ViewerID, parent.UserID)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if blocked {
+				return nil, "", fmt.Errorf("comment %d of post %d: %w", filter.ParentID, postID, customError.ErrNotFound)
+			}
+		}
+	}
+
+	return uc.loadCommentTree(ctx, postID, filter)
+}
+
+// loadCommentTree loads a page of one level of a thread, then the replies
+// below it one level at a time. A comment with more replies than
+// filter.ReplyLimit gets a cursor to load the rest of its branch.
+func (uc *PostUsecase) loadCommentTree(ctx context.Context, postID int64, filter model.CommentTreeFilter) ([]*model.CommentResponse, string, error) {
+	limit := filter.Limit
+	if limit < 1 {
+		limit = util.DefaultPageLimit
+	}
+
+	if filter.Depth < 1 {
+		filter.Depth = util.DefaultCommentDepth
+	}
+
+	if filter.ReplyLimit < 1 {
+		filter.ReplyLimit = util.DefaultReplyLimit
+	}
+
+	// the extra comment tells whether there is a next page
+	filter.Limit = limit + 1
+
+	comments, err := uc.commentRepo.GetCommentThread(ctx, postID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(comments) > limit {
+		comments = comments[:limit]
+
+		nextCursor, err = cursor.Encode(model.CommentCursor{ID: comments[limit-1].ID})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	roots := make([]*model.CommentResponse, 0, len(comments))
 	for _, comment := range comments {
-		commentsResp = append(commentsResp, &model.CommentResponse{
-			ID:        comment.ID,
-			PostID:    comment.PostID,
-			UserID:    comment.UserID,
-			UserName:  comment.UserName,
-			UserPhoto: comment.UserPhoto.String,
-			Comment:   comment.Comment,
-			CreatedAt: comment.CreatedAt,
+		roots = append(roots, convertToCommentResponse(comment))
+	}
+
+	level := roots
+	for depth := 1; depth < filter.Depth; depth++ {
+		parents := make(map[int64]*model.CommentResponse)
+		var parentIDs []int64
+
+		for _, node := range level {
+			if node.ReplyCount > 0 {
+				parents[node.ID] = node
+				parentIDs = append(parentIDs, node.ID)
+			}
+		}
+
+		if len(parentIDs) == 0 {
+			break
+		}
+
+		replies, err := uc.commentRepo.GetRepliesBatch(ctx, parentIDs, filter.ReplyLimit+1, filter.ViewerID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		var next []*model.CommentResponse
+		for _, reply := range replies {
+			parent, ok := parents[reply.ParentID.Int64]
+			if !ok {
+				continue
+			}
+
+			// the extra reply of a branch tells there are more to load
+			if len(parent.Replies) == filter.ReplyLimit {
+				parent.RepliesCursor, err = cursor.Encode(model.CommentCursor{ID: parent.Replies[filter.ReplyLimit-1].ID})
+				if err != nil {
+					return nil, "", err
+				}
+				continue
+			}
+
+			node := convertToCommentResponse(reply)
+			parent.Replies = append(parent.Replies, node)
+			next = append(next, node)
+		}
+
+		level = next
+	}
+
+	if err := uc.reactions.AttachToComments(ctx, filter.ViewerID, flattenComments(roots)...); err != nil {
+		return nil, "", err
+	}
+
+	return roots, nextCursor, nil
+}
+
+// UpdatePost edits a post written by the actor, empty fields of req keep
+// their current value and req.RemoveImage clears the image. The replaced
+// version is kept in the post history.
+// Moderators cannot edit posts of others, they can only remove them.
+func (uc *PostUsecase) UpdatePost(ctx context.Context, postID int64, req *model.PostUpdate, actor model.Actor) (*model.PostResponse, error) {
+	post, err := uc.postRepo.GetPostByID(ctx, postID, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.UserID != actor.UserID {
+		return nil, customError.ErrPermissionDenied
+	}
+
+	changed := false
+
+	if req.Title != "" && req.Title != post.Title {
+		post.Title = req.Title
+		changed = true
+	}
+
+	if req.Content != "" && req.Content != post.Content {
+		post.Content = req.Content
+		changed = true
+	}
+
+	if req.RemoveImage {
+		if post.Image.Valid {
+			post.Image = sql.NullString{}
+			changed = true
+		}
+	} else if req.Image != "" && req.Image != post.Image.String {
+		post.Image = sql.NullString{String: req.Image, Valid: true}
+		changed = true
+	}
+
+	// nothing to keep in the history when the edit does not change anything
+	if !changed {
+		return convertToPostRespone(post), nil
+	}
+
+	now := time.Now()
+	post.UpdatedAt = now
+	post.EditedAt = sql.NullTime{Time: now, Valid: true}
+
+	if err := uc.postRepo.UpdatePost(ctx, post); err != nil {
+		return nil, err
+	}
+
+	uc.search.SyncDocument(ctx, model.SearchTypePost, post.ID)
+
+	return convertToPostRespone(post), nil
+}
+
+// GetPostHistory returns the previous versions of a post, newest first. The
+// history is hidden like the post from users blocked either way.
+func (uc *PostUsecase) GetPostHistory(ctx context.Context, postID int64, viewerID int64) ([]*model.PostRevisionResponse, error) {
+	if _, err := getVisiblePost(ctx, uc.postRepo, uc.blocks, postID, viewerID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := uc.postRepo.GetPostRevisions(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionsResp := make([]*model.PostRevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		revisionsResp = append(revisionsResp, &model.PostRevisionResponse{
+			ID:         revision.ID,
+			Title:      revision.Title,
+			Content:    revision.Content,
+			Image:      revision.Image.String,
+			CreatedAt:  revision.CreatedAt,
+			ReplacedAt: revision.ReplacedAt,
 		})
 	}
 
-	postResponse := convertToPostRespone(post)
-	postResponse.Comment = commentsResp
+	return revisionsResp, nil
+}
 
-	return postResponse, nil
+// GetCommentHistory returns the previous versions of a comment of the post,
+// newest first. The history of a deleted comment is gone with it, and the
+// post and the comment are hidden from users blocked either way.
+func (uc *PostUsecase) GetCommentHistory(ctx context.Context, postID, commentID int64, viewerID int64) ([]*model.CommentRevisionResponse, error) {
+	if _, err := getVisiblePost(ctx, uc.postRepo, uc.blocks, postID, viewerID); err != nil {
+		return nil, err
+	}
+
+	comment, err := uc.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.PostID != postID || comment.DeletedAt.Valid {
+		return nil, fmt.Errorf("comment %d of post %d: %w", commentID, postID, customError.ErrNotFound)
+	}
+
+	blocked, err := isBlocked(ctx, uc.blocks, viewerID, comment.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if blocked {
+		return nil, fmt.Errorf("comment %d of post %d: %w", commentID, postID, customError.ErrNotFound)
+	}
+
+	revisions, err := uc.commentRepo.GetCommentRevisions(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionsResp := make([]*model.CommentRevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		revisionsResp = append(revisionsResp, &model.CommentRevisionResponse{
+			ID:         revision.ID,
+			Comment:    revision.Comment,
+			CreatedAt:  revision.CreatedAt,
+			ReplacedAt: revision.ReplacedAt,
+		})
+	}
+
+	return revisionsResp, nil
 }
 
-func (uc *PostUsecase) DeletePost(ctx context.Context, postID int64) error {
-	return uc.postRepo.DeletePost(ctx, postID)
+// DeletePost removes a post written by the actor. Moderators and admins may
+// remove any post.
+func (uc *PostUsecase) DeletePost(ctx context.Context, postID int64, actor model.Actor) error {
+	post, err := uc.postRepo.GetPostByID(ctx, postID, actor.UserID)
+	if err != nil {
+		return err
+	}
+
+	if post.UserID != actor.UserID && !actor.Role.Can(rbac.PermRemoveAnyPost) {
+		return customError.ErrPermissionDenied
+	}
+
+	if err := uc.postRepo.DeletePost(ctx, postID); err != nil {
+		return err
+	}
+
+	uc.search.RemoveDocument(ctx, model.SearchTypePost, postID)
+
+	return nil
 }
 
-func (uc *PostUsecase) CreateComment(ctx context.Context, req *model.CommentCreate, userID int64) error {
+// CreateComment adds a comment to the post, or a reply when req.ParentID is
+// set. Deleted comments cannot be replied to.
+func (uc *PostUsecase) CreateComment(ctx context.Context, req *model.CommentCreate, userID int64) (*model.CommentResponse, error) {
+	if strings.TrimSpace(req.Comment) == "" {
+		return nil, customError.ErrEmptyComment
+	}
+
+	post, err := uc.postRepo.GetPostByID(ctx, req.PostID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// a blocked user may still see the post through a link, not comment on it
+	if err := checkNotBlocked(ctx, uc.blocks, userID, post.UserID); err != nil {
+		return nil, err
+	}
+
 	comment := &model.Comment{
 		PostID:    req.PostID,
 		UserID:    userID,
@@ -126,32 +580,258 @@ func (uc *PostUsecase) CreateComment(ctx context.Context, req *model.CommentCrea
 		CreatedAt: time.Now(),
 	}
 
-	err := uc.commentRepo.CreateComment(ctx, comment)
+	// a comment notifies the author of the post, a reply the author of the
+	// comment it answers
+	event := model.NotificationEvent{
+		UserID:     post.UserID,
+		ActorID:    userID,
+		Type:       model.NotificationComment,
+		TargetType: model.NotificationTargetPost,
+		TargetID:   post.ID,
+		PostID:     post.ID,
+	}
+
+	if req.ParentID != 0 {
+		parent, err := uc.commentRepo.GetCommentByID(ctx, req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.PostID != req.PostID || parent.DeletedAt.Valid {
+			return nil, fmt.Errorf("comment %d of post %d: %w", req.ParentID, req.PostID, customError.ErrNotFound)
+		}
+
+		if err := checkNotBlocked(ctx, uc.blocks, userID, parent.UserID); err != nil {
+			return nil, err
+		}
+
+		comment.ParentID = sql.NullInt64{Int64: parent.ID, Valid: true}
+
+		event.UserID = parent.UserID
+		event.Type = model.NotificationReply
+		event.TargetType = model.NotificationTargetComment
+		event.TargetID = parent.ID
+	}
+
+	err = uc.commentRepo.CreateComment(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.refreshScore(ctx, comment.PostID)
+	uc.search.SyncDocument(ctx, model.SearchTypeComment, comment.ID)
+
+	uc.notifier.Notify(ctx, event)
+	uc.notifier.NotifyMentions(ctx, model.NotificationEvent{
+		ActorID:    userID,
+		TargetType: model.NotificationTargetComment,
+		TargetID:   comment.ID,
+		PostID:     comment.PostID,
+	}, comment.Comment)
+
+	commentResp := convertToCommentResponse(comment)
+	uc.realtime.Publish(ctx, realtime.PostChannel(comment.PostID), realtime.EventComment, userID, commentResp)
+
+	return commentResp, nil
+}
+
+// UpdateComment edits a comment written by the actor under postID. The
+// replaced text is kept in the comment history.
+func (uc *PostUsecase) UpdateComment(ctx context.Context, postID, commentID int64, req *model.CommentUpdate, actor model.Actor) (*model.CommentResponse, error) {
+	if strings.TrimSpace(req.Comment) == "" {
+		return nil, customError.ErrEmptyComment
+	}
+
+	comment, err := uc.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.PostID != postID || comment.DeletedAt.Valid {
+		return nil, fmt.Errorf("comment %d of post %d: %w", commentID, postID, customError.ErrNotFound)
+	}
+
+	if comment.UserID != actor.UserID {
+		return nil, customError.ErrPermissionDenied
+	}
+
+	if req.Comment == comment.Comment {
+		return convertToCommentResponse(comment), nil
+	}
+
+	comment.Comment = req.Comment
+	comment.EditedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	if err := uc.commentRepo.UpdateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	uc.search.SyncDocument(ctx, model.SearchTypeComment, comment.ID)
+
+	return convertToCommentResponse(comment), nil
+}
+
+// DeleteComment removes a comment written by the actor. Moderators and admins
+// may remove any comment. A comment that is not under postID is reported as
+// not found so the URL cannot be used to reach comments of another post. A
+// comment with replies is left as a tombstone.
+func (uc *PostUsecase) DeleteComment(ctx context.Context, postID, commentID int64, actor model.Actor) error {
+	comment, err := uc.commentRepo.GetCommentByID(ctx, commentID)
 	if err != nil {
 		return err
 	}
 
+	if comment.PostID != postID || comment.DeletedAt.Valid {
+		return fmt.Errorf("comment %d of post %d: %w", commentID, postID, customError.ErrNotFound)
+	}
+
+	if comment.UserID != actor.UserID && !actor.Role.Can(rbac.PermRemoveAnyComment) {
+		return customError.ErrPermissionDenied
+	}
+
+	if err := uc.commentRepo.DeleteComment(ctx, commentID); err != nil {
+		return err
+	}
+
+	uc.refreshScore(ctx, postID)
+	uc.search.RemoveDocument(ctx, model.SearchTypeComment, commentID)
+
 	return nil
 }
 
-func (uc *PostUsecase) DeleteComment(ctx context.Context, id int64) error {
-	return uc.commentRepo.DeleteComment(ctx, id)
+// CreateUpVote sets the vote of the user on the post to an up vote. Voting
+// again the same way leaves the vote as it is.
+func (uc *PostUsecase) CreateUpVote(ctx context.Context, postID int64, userID int64) (*model.VoteResponse, error) {
+	return uc.votePost(ctx, postID, userID, 1)
+}
+
+// CreateDownVote sets the vote of the user on the post to a down vote.
+func (uc *PostUsecase) CreateDownVote(ctx context.Context, postID int64, userID int64) (*model.VoteResponse, error) {
+	return uc.votePost(ctx, postID, userID, -1)
 }
 
-func (uc *PostUsecase) CreateUpVote(ctx context.Context, postID int64, userID int64) error {
-	err := uc.postRepo.DeletVote(ctx, postID, userID)
+// RetractVote removes the vote of the user on the post, retracting a vote
+// that does not exist is not an error.
+func (uc *PostUsecase) RetractVote(ctx context.Context, postID int64, userID int64) (*model.VoteResponse, error) {
+	return uc.votePost(ctx, postID, userID, 0)
+}
+
+// votePost sets the vote of the user on the post, a zero vote removes it.
+func (uc *PostUsecase) votePost(ctx context.Context, postID int64, userID int64, vote int64) (*model.VoteResponse, error) {
+	var count *model.VoteCount
+	var authorID int64
+	var err error
+
+	// a vote can always be retracted, only new votes are refused
+	if vote != 0 {
+		post, err := uc.postRepo.GetPostByID(ctx, postID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkNotBlocked(ctx, uc.blocks, userID, post.UserID); err != nil {
+			return nil, err
+		}
+
+		authorID = post.UserID
+	}
+
+	if vote == 0 {
+		count, err = uc.postRepo.DeleteVote(ctx, postID, userID)
+	} else {
+		count, err = uc.postRepo.SetVote(ctx, postID, userID, vote)
+	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	uc.refreshScore(ctx, postID)
+	uc.search.SyncDocument(ctx, model.SearchTypePost, postID)
+
+	// only up votes are worth telling the author about
+	if vote > 0 {
+		uc.notifier.Notify(ctx, model.NotificationEvent{
+			UserID:     authorID,
+			ActorID:    userID,
+			Type:       model.NotificationVote,
+			TargetType: model.NotificationTargetPost,
+			TargetID:   postID,
+			PostID:     postID,
+		})
 	}
 
-	return uc.postRepo.CreateVote(ctx, postID, userID, 1)
+	// the counts are anonymous, the event has no actor
+	uc.realtime.Publish(ctx, realtime.PostChannel(postID), realtime.EventVote, 0, model.VoteUpdate{
+		PostID:   postID,
+		UpVote:   count.UpVote,
+		DownVote: count.DownVote,
+	})
+
+	return &model.VoteResponse{UpVote: count.UpVote, DownVote: count.DownVote, MyVote: vote}, nil
 }
 
-func (uc *PostUsecase) CreateDownVote(ctx context.Context, postID int64, userID int64) error {
-	err := uc.postRepo.DeletVote(ctx, postID, userID)
+// CreateCommentUpVote sets the vote of the user on a comment of the post to
+// an up vote, replacing their previous vote.
+func (uc *PostUsecase) CreateCommentUpVote(ctx context.Context, postID, commentID int64, userID int64) (*model.VoteResponse, error) {
+	return uc.voteComment(ctx, postID, commentID, userID, 1)
+}
+
+// CreateCommentDownVote sets the vote of the user on a comment of the post to
+// a down vote, replacing their previous vote.
+func (uc *PostUsecase) CreateCommentDownVote(ctx context.Context, postID, commentID int64, userID int64) (*model.VoteResponse, error) {
+	return uc.voteComment(ctx, postID, commentID, userID, -1)
+}
+
+// RetractCommentVote removes the vote of the user on a comment of the post.
+func (uc *PostUsecase) RetractCommentVote(ctx context.Context, postID, commentID int64, userID int64) (*model.VoteResponse, error) {
+	return uc.voteComment(ctx, postID, commentID, userID, 0)
+}
+
+func (uc *PostUsecase) voteComment(ctx context.Context, postID, commentID int64, userID int64, vote int64) (*model.VoteResponse, error) {
+	comment, err := uc.commentRepo.GetCommentByID(ctx, commentID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if comment.PostID != postID {
+		return nil, fmt.Errorf("comment %d of post %d: %w", commentID, postID, customError.ErrNotFound)
+	}
+
+	if vote != 0 {
+		if err := checkNotBlocked(ctx, uc.blocks, userID, comment.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	var count *model.VoteCount
+	if vote == 0 {
+		count, err = uc.commentRepo.DeleteVote(ctx, commentID, userID)
+	} else {
+		count, err = uc.commentRepo.SetVote(ctx, commentID, userID, vote)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	uc.search.SyncDocument(ctx, model.SearchTypeComment, commentID)
+
+	if vote > 0 {
+		uc.notifier.Notify(ctx, model.NotificationEvent{
+			UserID:     comment.UserID,
+			ActorID:    userID,
+			Type:       model.NotificationVote,
+			TargetType: model.NotificationTargetComment,
+			TargetID:   commentID,
+			PostID:     postID,
+		})
+	}
+
+	uc.realtime.Publish(ctx, realtime.PostChannel(postID), realtime.EventVote, 0, model.VoteUpdate{
+		PostID:    postID,
+		CommentID: commentID,
+		UpVote:    count.UpVote,
+		DownVote:  count.DownVote,
+	})
 
-	return uc.postRepo.CreateVote(ctx, postID, userID, -1)
+	return &model.VoteResponse{UpVote: count.UpVote, DownVote: count.DownVote, MyVote: vote}, nil
 }
@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/cursor"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/util"
+)
+
+// ProfileUsecaseItf serves the public side of a user, their profile and what
+// they wrote. None of it exposes the email or account settings of the user.
+type ProfileUsecaseItf interface {
+	GetProfile(ctx context.Context, userID int64, viewerID int64) (*model.UserProfileResponse, error)
+	GetUserPosts(ctx context.Context, userID int64, filter model.PostFilter) ([]model.PostResponse, string, error)
+	GetUserComments(ctx context.Context, userID int64, filter model.CommentFilter) ([]*model.CommentResponse, string, error)
+}
+
+type ProfileUsecase struct {
+	userRepo    repository.UserRepoItf
+	postRepo    repository.PostRepoItf
+	commentRepo repository.CommentRepoItf
+	followRepo  repository.FollowRepoItf
+	blocks      repository.BlockRepoItf
+	reactions   ReactionUsecaseItf
+}
+
+func NewProfileUsecase(userRepo repository.UserRepoItf, postRepo repository.PostRepoItf,
+	commentRepo repository.CommentRepoItf, followRepo repository.FollowRepoItf,
+	blocks repository.BlockRepoItf, reactions ReactionUsecaseItf) ProfileUsecaseItf {
+	return &ProfileUsecase{
+		userRepo:    userRepo,
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		followRepo:  followRepo,
+		blocks:      blocks,
+		reactions:   reactions,
+	}
+}
+
+// GetProfile implements ProfileUsecaseItf. The profile tells how the viewer
+// and the user follow each other.
+func (p *ProfileUsecase) GetProfile(ctx context.Context, userID int64, viewerID int64) (*model.UserProfileResponse, error) {
+	profile, err := p.userRepo.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := isBlocked(ctx, p.blocks, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// users who blocked one another do not see each other
+	if blocked {
+		return nil, customError.ErrUserNotFound
+	}
+
+	profileResp := &model.UserProfileResponse{
+		ID:             profile.ID,
+		Name:           profile.Name,
+		Photo:          profile.Photo.String,
+		Role:           profile.Role,
+		Banned:         profile.BannedAt.Valid,
+		PostCount:      profile.PostCount,
+		CommentCount:   profile.CommentCount,
+		Karma:          profile.Karma,
+		FollowerCount:  profile.FollowerCount,
+		FollowingCount: profile.FollowingCount,
+		JoinedAt:       profile.CreatedAt,
+	}
+
+	if viewerID != userID {
+		status, err := p.followRepo.GetFollowStatus(ctx, viewerID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		status.Mutual = status.IsFollowing && status.FollowsYou
+		profileResp.Follow = status
+	}
+
+	return profileResp, nil
+}
+
+// GetUserPosts implements ProfileUsecaseItf. The posts are paged and sorted
+// like the main listing.
+func (p *ProfileUsecase) GetUserPosts(ctx context.Context, userID int64, filter model.PostFilter) ([]model.PostResponse, string, error) {
+	// an unknown user is a 404, not an empty page
+	if err := checkUserVisible(ctx, p.userRepo, p.blocks, filter.ViewerID, userID); err != nil {
+		return nil, "", err
+	}
+
+	posts, nextCursor, err := pagePosts(ctx, filter, func(ctx context.Context, filter model.PostFilter) ([]*model.Post, error) {
+		return p.postRepo.GetAllPostByUserID(ctx, filter, userID)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := attachPostReactions(ctx, p.reactions, filter.ViewerID, posts); err != nil {
+		return nil, "", err
+	}
+
+	return posts, nextCursor, nil
+}
+
+// GetUserComments implements ProfileUsecaseItf. Comments come newest first.
+func (p *ProfileUsecase) GetUserComments(ctx context.Context, userID int64, filter model.CommentFilter) ([]*model.CommentResponse, string, error) {
+	if err := checkUserVisible(ctx, p.userRepo, p.blocks, filter.ViewerID, userID); err != nil {
+		return nil, "", err
+	}
+
+	limit := filter.Limit
+	if limit < 1 {
+		limit = util.DefaultPageLimit
+	}
+
+	// the extra comment tells whether there is a next page
+	filter.Limit = limit + 1
+
+	comments, err := p.commentRepo.GetCommentsByUserID(ctx, userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(comments) > limit {
+		comments = comments[:limit]
+
+		nextCursor, err = cursor.Encode(model.CommentCursor{ID: comments[limit-1].ID})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	commentsResp := make([]*model.CommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		commentsResp = append(commentsResp, convertToCommentResponse(comment))
+	}
+
+	if err := p.reactions.AttachToComments(ctx, filter.ViewerID, commentsResp...); err != nil {
+		return nil, "", err
+	}
+
+	return commentsResp, nextCursor, nil
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/ranking"
+)
+
+// recomputeBatchSize is how many posts a recomputation reads and updates at
+// once.
+const recomputeBatchSize = 500
+
+// RankingUsecaseItf keeps the ranking score of the posts up to date. Vote and
+// comment counters are maintained by the repositories in the transactions
+// that change them, the score is derived from them afterwards.
+type RankingUsecaseItf interface {
+	RefreshPost(ctx context.Context, postID int64) error
+	Recompute(ctx context.Context, since time.Time) (int, error)
+	StartRecomputation(ctx context.Context, interval time.Duration, window time.Duration)
+}
+
+type RankingUsecase struct {
+	postRepo repository.PostRepoItf
+	scorer   ranking.Scorer
+}
+
+func NewRankingUsecase(postRepo repository.PostRepoItf, scorer ranking.Scorer) RankingUsecaseItf {
+	return &RankingUsecase{
+		postRepo: postRepo,
+		scorer:   scorer,
+	}
+}
+
+// RefreshPost implements RankingUsecaseItf.
+func (r *RankingUsecase) RefreshPost(ctx context.Context, postID int64) error {
+	stats, err := r.postRepo.GetPostStats(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	return r.postRepo.UpdatePostScores(ctx, []model.PostScore{r.score(stats, time.Now())})
+}
+
+// Recompute implements RankingUsecaseItf. It recomputes the score of every
+// post created at or after since, or of every post when since is zero, and
+// returns how many posts it updated.
+func (r *RankingUsecase) Recompute(ctx context.Context, since time.Time) (int, error) {
+	now := time.Now()
+
+	var afterID int64
+	updated := 0
+
+	for {
+		batch, err := r.postRepo.GetPostStatsBatch(ctx, since, afterID, recomputeBatchSize)
+		if err != nil {
+			return updated, err
+		}
+
+		if len(batch) == 0 {
+			return updated, nil
+		}
+
+		scores := make([]model.PostScore, 0, len(batch))
+		for _, stats := range batch {
+			scores = append(scores, r.score(stats, now))
+		}
+
+		if err := r.postRepo.UpdatePostScores(ctx, scores); err != nil {
+			return updated, err
+		}
+
+		updated += len(scores)
+		afterID = batch[len(batch)-1].ID
+	}
+}
+
+// StartRecomputation implements RankingUsecaseItf. Every post is scored once
+// at startup, which also picks up a change of algorithm. A time independent
+// score only changes with the counters of the post, which RefreshPost
+// follows. A time dependent one is also rescored for the posts of the last
+// window every interval until ctx is done. Older posts keep their score, it
+// has decayed close to its floor by then anyway.
+func (r *RankingUsecase) StartRecomputation(ctx context.Context, interval time.Duration, window time.Duration) {
+	go func() {
+		if _, err := r.Recompute(ctx, time.Time{}); err != nil {
+			log.Printf("cannot recompute post scores: %s", err)
+		}
+
+		if !r.scorer.TimeDependent() {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.Recompute(ctx, time.Now().Add(-window)); err != nil {
+					log.Printf("cannot recompute post scores: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+func (r *RankingUsecase) score(stats *model.PostStats, now time.Time) model.PostScore {
+	return model.PostScore{
+		ID: stats.ID,
+		Score: r.scorer.Score(ranking.Stats{
+			UpVote:       stats.UpVote,
+			DownVote:     stats.DownVote,
+			CommentCount: stats.CommentCount,
+			CreatedAt:    stats.CreatedAt,
+		}, now),
+	}
+}
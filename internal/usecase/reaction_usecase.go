@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/reaction"
+)
+
+// ReactionUsecaseItf holds the emoji reactions left on posts and comments.
+// The Attach methods fill in the reaction counts and the reaction of the
+// viewer on responses built by the other usecases.
+type ReactionUsecaseItf interface {
+	GetReactionSet() []string
+	ReactToPost(ctx context.Context, postID int64, req *model.ReactionRequest, userID int64) error
+	RemovePostReaction(ctx context.Context, postID int64, userID int64) error
+	ReactToComment(ctx context.Context, postID, commentID int64, req *model.ReactionRequest, userID int64) error
+	RemoveCommentReaction(ctx context.Context, postID, commentID int64, userID int64) error
+
+	AttachToPosts(ctx context.Context, viewerID int64, posts ...*model.PostResponse) error
+	AttachToComments(ctx context.Context, viewerID int64, comments ...*model.CommentResponse) error
+}
+
+type ReactionUsecase struct {
+	reactionRepo repository.ReactionRepoItf
+	postRepo     repository.PostRepoItf
+	commentRepo  repository.CommentRepoItf
+	blocks       repository.BlockRepoItf
+	reactions    *reaction.Set
+}
+
+func NewReactionUsecase(reactionRepo repository.ReactionRepoItf, postRepo repository.PostRepoItf,
+	commentRepo repository.CommentRepoItf, blocks repository.BlockRepoItf, reactions *reaction.Set) ReactionUsecaseItf {
+	return &ReactionUsecase{
+		reactionRepo: reactionRepo,
+		postRepo:     postRepo,
+		commentRepo:  commentRepo,
+		blocks:       blocks,
+		reactions:    reactions,
+	}
+}
+
+// GetReactionSet implements ReactionUsecaseItf.
+func (uc *ReactionUsecase) GetReactionSet() []string {
+	return uc.reactions.List()
+}
+
+// ReactToPost implements ReactionUsecaseItf. Reacting again replaces the
+// previous reaction of the user.
+func (uc *ReactionUsecase) ReactToPost(ctx context.Context, postID int64, req *model.ReactionRequest, userID int64) error {
+	if !uc.reactions.Contains(req.Reaction) {
+		return fmt.Errorf("%q: %w", req.Reaction, customError.ErrUnknownReaction)
+	}
+
+	post, err := uc.postRepo.GetPostByID(ctx, postID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkNotBlocked(ctx, uc.blocks, userID, post.UserID); err != nil {
+		return err
+	}
+
+	return uc.reactionRepo.SetReaction(ctx, &model.Reaction{
+		UserID:     userID,
+		TargetType: model.ReactionTargetPost,
+		TargetID:   postID,
+		Reaction:   req.Reaction,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// RemovePostReaction implements ReactionUsecaseItf.
+func (uc *ReactionUsecase) RemovePostReaction(ctx context.Context, postID int64, userID int64) error {
+	return uc.reactionRepo.DeleteReaction(ctx, userID, model.ReactionTargetPost, postID)
+}
+
+// ReactToComment implements ReactionUsecaseItf. Deleted comments cannot be
+// reacted to.
+func (uc *ReactionUsecase) ReactToComment(ctx context.Context, postID, commentID int64, req *model.ReactionRequest, userID int64) error {
+	if !uc.reactions.Contains(req.Reaction) {
+		return fmt.Errorf("%q: %w", req.Reaction, customError.ErrUnknownReaction)
+	}
+
+	comment, err := uc.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	if comment.PostID != postID || comment.DeletedAt.Valid {
+		return fmt.Errorf("comment %d of post %d: %w", commentID, postID, customError.ErrNotFound)
+	}
+
+	if err := checkNotBlocked(ctx, uc.blocks, userID, comment.UserID); err != nil {
+		return err
+	}
+
+	return uc.reactionRepo.SetReaction(ctx, &model.Reaction{
+		UserID:     userID,
+		TargetType: model.ReactionTargetComment,
+		TargetID:   commentID,
+		Reaction:   req.Reaction,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// RemoveCommentReaction implements ReactionUsecaseItf.
+func (uc *ReactionUsecase) RemoveCommentReaction(ctx context.Context, postID, commentID int64, userID int64) error {
+	comment, err := uc.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	if comment.PostID != postID {
+		return fmt.Errorf("comment %d of post %d: %w", commentID, postID, customError.ErrNotFound)
+	}
+
+	return uc.reactionRepo.DeleteReaction(ctx, userID, model.ReactionTargetComment, commentID)
+}
+
+// AttachToPosts implements ReactionUsecaseItf.
+func (uc *ReactionUsecase) AttachToPosts(ctx context.Context, viewerID int64, posts ...*model.PostResponse) error {
+	ids := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	counts, mine, err := uc.summarize(ctx, viewerID, model.ReactionTargetPost, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		post.Reactions = counts[post.ID]
+		post.MyReaction = mine[post.ID]
+	}
+
+	return nil
+}
+
+// AttachToComments implements ReactionUsecaseItf. Only the given comments are
+// filled in, not their replies.
+func (uc *ReactionUsecase) AttachToComments(ctx context.Context, viewerID int64, comments ...*model.CommentResponse) error {
+	ids := make([]int64, 0, len(comments))
+	for _, comment := range comments {
+		if !comment.Deleted {
+			ids = append(ids, comment.ID)
+		}
+	}
+
+	counts, mine, err := uc.summarize(ctx, viewerID, model.ReactionTargetComment, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		comment.Reactions = counts[comment.ID]
+		comment.MyReaction = mine[comment.ID]
+	}
+
+	return nil
+}
+
+// summarize returns the reaction counts of the targets and the reactions of
+// the viewer, both keyed by target id.
+func (uc *ReactionUsecase) summarize(ctx context.Context, viewerID int64, targetType string, ids []int64) (map[int64]map[string]int64, map[int64]string, error) {
+	counts := make(map[int64]map[string]int64)
+	mine := make(map[int64]string)
+
+	if len(ids) == 0 {
+		return counts, mine, nil
+	}
+
+	reactionCounts, err := uc.reactionRepo.GetReactionCounts(ctx, targetType, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, count := range reactionCounts {
+		// reactions dropped from the configured set are not shown anymore
+		if !uc.reactions.Contains(count.Reaction) {
+			continue
+		}
+
+		if counts[count.TargetID] == nil {
+			counts[count.TargetID] = make(map[string]int64)
+		}
+		counts[count.TargetID][count.Reaction] = count.Count
+	}
+
+	if viewerID == 0 {
+		return counts, mine, nil
+	}
+
+	viewerReactions, err := uc.reactionRepo.GetUserReactions(ctx, viewerID, targetType, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, r := range viewerReactions {
+		if uc.reactions.Contains(r.Reaction) {
+			mine[r.TargetID] = r.Reaction
+		}
+	}
+
+	return counts, mine, nil
+}
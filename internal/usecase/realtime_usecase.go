@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/realtime"
+)
+
+// RealtimeUsecaseItf pushes changes to the connected clients. Publish is
+// called by the other usecases once a change is saved, it only logs its
+// failures so a client missing an event never fails the change itself.
+type RealtimeUsecaseItf interface {
+	Publish(ctx context.Context, channel string, eventType string, actorID int64, data any)
+	Subscribe(ctx context.Context, userID int64, filter model.RealtimeFilter) (*RealtimeStream, error)
+}
+
+type RealtimeUsecase struct {
+	hub      realtime.Hub
+	postRepo repository.PostRepoItf
+	blocks   repository.BlockRepoItf
+}
+
+func NewRealtimeUsecase(hub realtime.Hub, postRepo repository.PostRepoItf,
+	blocks repository.BlockRepoItf) RealtimeUsecaseItf {
+	return &RealtimeUsecase{
+		hub:      hub,
+		postRepo: postRepo,
+		blocks:   blocks,
+	}
+}
+
+// RealtimeStream is the subscription of a user, it knows which actors the
+// user must not see the events of.
+type RealtimeStream struct {
+	*realtime.Subscription
+	hidden map[int64]bool
+}
+
+// Visible reports whether the event may be sent to the user, the events of
+// users blocked either way are left out.
+func (s *RealtimeStream) Visible(event realtime.Event) bool {
+	return !s.hidden[event.ActorID]
+}
+
+// Publish implements RealtimeUsecaseItf.
+func (uc *RealtimeUsecase) Publish(ctx context.Context, channel string, eventType string, actorID int64, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("cannot encode the %s event of %s: %s", eventType, channel, err)
+		return
+	}
+
+	err = uc.hub.Publish(ctx, realtime.Event{
+		Channel: channel,
+		Type:    eventType,
+		ActorID: actorID,
+		Data:    payload,
+	})
+	if err != nil {
+		log.Printf("cannot publish the %s event of %s: %s", eventType, channel, err)
+	}
+}
+
+// Subscribe implements RealtimeUsecaseItf. The user always gets the events
+// of their own channel, plus the ones of the posts in the filter which must
+// all be visible to them.
+func (uc *RealtimeUsecase) Subscribe(ctx context.Context, userID int64, filter model.RealtimeFilter) (*RealtimeStream, error) {
+	channels := []string{realtime.UserChannel(userID)}
+
+	for _, postID := range filter.PostIDs {
+		if _, err := getVisiblePost(ctx, uc.postRepo, uc.blocks, postID, userID); err != nil {
+			return nil, err
+		}
+
+		channels = append(channels, realtime.PostChannel(postID))
+	}
+
+	blockedIDs, err := uc.blocks.GetBlockedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[int64]bool, len(blockedIDs))
+	for _, id := range blockedIDs {
+		hidden[id] = true
+	}
+
+	sub, err := uc.hub.Subscribe(ctx, channels, filter.LastEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RealtimeStream{Subscription: sub, hidden: hidden}, nil
+}
@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"context"
+	"log"
+
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/cursor"
+	"github.com/federicodosantos/socialize/pkg/snippet"
+)
+
+const (
+	// reindexBatchSize is how many documents Reindex reads and indexes at once.
+	reindexBatchSize = 500
+	// snippetLength is the length in bytes of the excerpt shown for a hit.
+	snippetLength = 160
+)
+
+var searchTypes = []string{model.SearchTypePost, model.SearchTypeComment, model.SearchTypeUser}
+
+type SearchUsecaseItf interface {
+	Search(ctx context.Context, query model.SearchQuery) ([]*model.SearchHitResponse, string, error)
+	Reindex(ctx context.Context) (int, error)
+	SyncDocument(ctx context.Context, docType string, id int64)
+	RemoveDocument(ctx context.Context, docType string, id int64)
+}
+
+type SearchUsecase struct {
+	index  repository.SearchIndex
+	source repository.SearchSourceRepoItf
+	blocks repository.BlockRepoItf
+}
+
+func NewSearchUsecase(index repository.SearchIndex, source repository.SearchSourceRepoItf,
+	blocks repository.BlockRepoItf) SearchUsecaseItf {
+	return &SearchUsecase{
+		index:  index,
+		source: source,
+		blocks: blocks,
+	}
+}
+
+// Search implements SearchUsecaseItf. It returns a page of hits ordered by
+// relevance and the cursor of the next page, which is empty on the last page.
+func (s *SearchUsecase) Search(ctx context.Context, query model.SearchQuery) ([]*model.SearchHitResponse, string, error) {
+	limit := query.Limit
+
+	// the extra hit tells whether there is a next page
+	query.Limit = limit + 1
+
+	if query.ViewerID != 0 {
+		blockedIDs, err := s.blocks.GetBlockedUserIDs(ctx, query.ViewerID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		query.ExcludeAuthorIDs = blockedIDs
+	}
+
+	hits, err := s.index.Search(ctx, query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(hits) > limit {
+		hits = hits[:limit]
+
+		nextCursor, err = cursor.Encode(model.SearchCursor{Offset: query.Offset + limit})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	terms := snippet.Terms(query.Text)
+
+	hitsResp := make([]*model.SearchHitResponse, 0, len(hits))
+	for _, hit := range hits {
+		doc := hit.Document
+
+		text := doc.Body
+		if text == "" {
+			text = doc.Title
+		}
+
+		hitResp := &model.SearchHitResponse{
+			Type:       doc.Type,
+			ID:         doc.ID,
+			AuthorID:   doc.AuthorID,
+			AuthorName: doc.AuthorName,
+			Snippet:    snippet.Highlight(text, terms, snippetLength),
+			Relevance:  hit.Relevance,
+			CreatedAt:  doc.CreatedAt,
+		}
+
+		if doc.Type != model.SearchTypeUser {
+			hitResp.PostID = doc.PostID
+			hitResp.Title = doc.Title
+		}
+
+		hitsResp = append(hitsResp, hitResp)
+	}
+
+	return hitsResp, nextCursor, nil
+}
+
+// Reindex implements SearchUsecaseItf. It feeds every post, comment and user
+// to the index and returns how many documents it indexed. Documents are
+// indexed by type and id so running it again only refreshes them, which also
+// catches up with changes the index missed, such as the new name of an
+// author on their older posts.
+func (s *SearchUsecase) Reindex(ctx context.Context) (int, error) {
+	if !s.index.SyncRequired() {
+		return 0, nil
+	}
+
+	indexed := 0
+
+	for _, docType := range searchTypes {
+		var afterID int64
+
+		for {
+			docs, err := s.source.GetDocuments(ctx, docType, afterID, reindexBatchSize)
+			if err != nil {
+				return indexed, err
+			}
+
+			if len(docs) == 0 {
+				break
+			}
+
+			if err := s.index.Index(ctx, docs...); err != nil {
+				return indexed, err
+			}
+
+			indexed += len(docs)
+			afterID = docs[len(docs)-1].ID
+		}
+	}
+
+	return indexed, nil
+}
+
+// SyncDocument implements SearchUsecaseItf. It indexes the current version of
+// the document, or removes it when it is gone. A failure is only logged, the
+// change itself is saved and the next Reindex catches the index up.
+func (s *SearchUsecase) SyncDocument(ctx context.Context, docType string, id int64) {
+	if !s.index.SyncRequired() {
+		return
+	}
+
+	doc, err := s.source.GetDocument(ctx, docType, id)
+	if err != nil {
+		log.Printf("cannot read %s %d for the search index: %s", docType, id, err)
+		return
+	}
+
+	if doc == nil {
+		s.RemoveDocument(ctx, docType, id)
+		return
+	}
+
+	if err := s.index.Index(ctx, doc); err != nil {
+		log.Printf("cannot index %s %d: %s", docType, id, err)
+	}
+}
+
+// RemoveDocument implements SearchUsecaseItf.
+func (s *SearchUsecase) RemoveDocument(ctx context.Context, docType string, id int64) {
+	if !s.index.SyncRequired() {
+		return
+	}
+
+	if err := s.index.Delete(ctx, docType, id); err != nil {
+		log.Printf("cannot remove %s %d from the search index: %s", docType, id, err)
+	}
+}
@@ -5,49 +5,122 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/federicodosantos/socialize/internal/model"
 	"github.com/federicodosantos/socialize/internal/repository"
 	customError "github.com/federicodosantos/socialize/pkg/custom-error"
 	"github.com/federicodosantos/socialize/pkg/jwt"
-	"github.com/federicodosantos/socialize/pkg/md5"
+	"github.com/federicodosantos/socialize/pkg/mailer"
+	"github.com/federicodosantos/socialize/pkg/oidc"
+	"github.com/federicodosantos/socialize/pkg/password"
+	"github.com/federicodosantos/socialize/pkg/rbac"
+	"github.com/federicodosantos/socialize/pkg/token"
+	"github.com/federicodosantos/socialize/pkg/totp"
 )
 
+// recoveryCodeCount is how many recovery codes are handed out when two
+// factor authentication is enabled.
+const recoveryCodeCount = 10
+
 type UserUsecaseItf interface {
 	Register(ctx context.Context, req *model.UserRegister) (*model.UserResponse, error)
-	Login(ctx context.Context, req *model.UserLogin) (string, error)
+	Login(ctx context.Context, req *model.UserLogin) (*model.LoginResponse, error)
+	LoginTwoFactor(ctx context.Context, req *model.TwoFactorLoginRequest) (*model.TokenResponse, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*model.TokenResponse, error)
+	Logout(ctx context.Context, jti string, expiresAt time.Time, refreshToken string, userId int64) error
+	StartTokenPurge(ctx context.Context, interval time.Duration)
+	VerifyEmail(ctx context.Context, verificationToken string) error
+	ResendVerificationEmail(ctx context.Context, email string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, req *model.ResetPasswordRequest) error
+	ChangePassword(ctx context.Context, req *model.ChangePasswordRequest, userId int64) (*model.TokenResponse, error)
+	ChangeEmail(ctx context.Context, req *model.ChangeEmailRequest, userId int64) (*model.UserResponse, error)
+	ConfirmEmailChange(ctx context.Context, confirmationToken string) (*model.UserResponse, error)
 	GetUserById(ctx context.Context, userId int64) (*model.UserResponse, error)
 	UpdateUserData(ctx context.Context, req *model.UserUpdateData, userId int64) (*model.UserResponse, error)
 	UpdateUserPhoto(ctx context.Context, req *model.UserUpdatePhoto, userId int64) (*model.UserResponse, error)
+	EnrollTwoFactor(ctx context.Context, req *model.EnrollTwoFactorRequest, userId int64) (*model.TwoFactorEnrollment, error)
+	ConfirmTwoFactor(ctx context.Context, req *model.ConfirmTwoFactorRequest, userId int64) (*model.RecoveryCodesResponse, error)
+	DisableTwoFactor(ctx context.Context, req *model.DisableTwoFactorRequest, userId int64) error
+	RegenerateRecoveryCodes(ctx context.Context, req *model.TwoFactorCodeRequest, userId int64) (*model.RecoveryCodesResponse, error)
+	StartOAuth(ctx context.Context, provider string) (*model.OAuthAuthorization, error)
+	OAuthCallback(ctx context.Context, req *model.OAuthCallbackRequest) (*model.LoginResponse, error)
+}
+
+type UserUsecaseConfig struct {
+	RefreshTokenTTL time.Duration
+
+	// RequireEmailVerification makes Login refuse accounts that have not
+	// verified their email address yet.
+	RequireEmailVerification bool
+	VerificationTokenTTL     time.Duration
+	MailResendInterval       time.Duration
+	PasswordResetTokenTTL    time.Duration
+	EmailChangeTokenTTL      time.Duration
+	// TwoFactorChallengeTTL is how long the challenge returned by Login for
+	// accounts with two-factor authentication can be exchanged.
+	TwoFactorChallengeTTL time.Duration
+	// TOTPIssuer is the account issuer shown in authenticator apps.
+	TOTPIssuer string
+	// OAuthStateTTL is how long a user has to log in at an OAuth provider.
+	OAuthStateTTL time.Duration
+	// AppURL is the base URL used to build links sent by email.
+	AppURL string
 }
 
 type UserUsecase struct {
-	userRepo repository.UserRepoItf
-	jwt      jwt.JWTItf
+	userRepo    repository.UserRepoItf
+	tokenRepo   repository.TokenRepoItf
+	jwt         jwt.JWTItf
+	hasher      password.Hasher
+	mailer      mailer.Mailer
+	tokenSigner *token.Signer
+	// oauthProviders are the OpenID Connect providers keyed by name.
+	oauthProviders map[string]*oidc.Provider
+	search         SearchUsecaseItf
+	config         UserUsecaseConfig
 }
 
-func NewUserUsecase(userRepo repository.UserRepoItf,
-	jwt jwt.JWTItf) UserUsecaseItf {
+func NewUserUsecase(userRepo repository.UserRepoItf, tokenRepo repository.TokenRepoItf,
+	jwt jwt.JWTItf, hasher password.Hasher, mailer mailer.Mailer, tokenSigner *token.Signer,
+	oauthProviders []*oidc.Provider, search SearchUsecaseItf, config UserUsecaseConfig) UserUsecaseItf {
+	providers := make(map[string]*oidc.Provider, len(oauthProviders))
+	for _, provider := range oauthProviders {
+		providers[provider.Name()] = provider
+	}
+
 	return &UserUsecase{
-		userRepo: userRepo,
-		jwt:      jwt,
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		jwt:            jwt,
+		hasher:         hasher,
+		mailer:         mailer,
+		tokenSigner:    tokenSigner,
+		oauthProviders: providers,
+		search:         search,
+		config:         config,
 	}
 }
 
 // Register implements UserUCItf.
 func (u *UserUsecase) Register(ctx context.Context, req *model.UserRegister) (*model.UserResponse, error) {
-	hashedPassword := md5.HashWithMd5(req.Password)
+	hashedPassword, err := u.hasher.Hash(req.Password)
+	if err != nil {
+		return nil, err
+	}
 
 	createdUser := &model.User{
 		Name:      req.Name,
 		Email:     req.Email,
 		Password:  hashedPassword,
+		Role:      string(rbac.RoleUser),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	err := u.userRepo.CreateUser(ctx, createdUser)
+	err = u.userRepo.CreateUser(ctx, createdUser)
 	if err != nil {
 		if errors.Is(err, customError.ErrEmailExist) {
 			return nil, fmt.Errorf("email : %s already exists: %w", createdUser.Email, err)
@@ -56,22 +129,781 @@ func (u *UserUsecase) Register(ctx context.Context, req *model.UserRegister) (*m
 		return nil, err
 	}
 
+	u.search.SyncDocument(ctx, model.SearchTypeUser, createdUser.ID)
+
+	// the account exists at this point, a failed mail can be retried through
+	// the resend endpoint
+	if err := u.sendVerificationEmail(ctx, createdUser); err != nil {
+		log.Printf("cannot send verification email to user %d: %s", createdUser.ID, err)
+	}
+
 	return convertToUserRespone(createdUser), nil
 }
 
-// Login implements UserUCItf.
-func (u *UserUsecase) Login(ctx context.Context, req *model.UserLogin) (string, error) {
-	user, err := u.userRepo.UserLogin(ctx, req.Email, md5.HashWithMd5(req.Password))
+// Login implements UserUCItf. Accounts with two-factor authentication get a
+// short lived challenge token instead of a token pair, to be exchanged
+// together with a code at LoginTwoFactor.
+func (u *UserUsecase) Login(ctx context.Context, req *model.UserLogin) (*model.LoginResponse, error) {
+	user, err := u.userRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := u.hasher.Verify(req.Password, user.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, customError.ErrIncorrectPassword
+	}
+
+	if u.config.RequireEmailVerification && !user.VerifiedAt.Valid {
+		return nil, customError.ErrNotVerified
+	}
+
+	// hashes from an older algorithm or with weaker parameters are upgraded
+	// while the plaintext password is at hand
+	if u.hasher.NeedsRehash(user.Password) {
+		u.rehashPassword(ctx, user.ID, req.Password)
+	}
+
+	return u.completeLogin(ctx, user)
+}
+
+// completeLogin is the end of every first factor login. Accounts with
+// two-factor authentication get a challenge instead of a token pair.
+func (u *UserUsecase) completeLogin(ctx context.Context, user *model.User) (*model.LoginResponse, error) {
+	if user.BannedAt.Valid {
+		return nil, customError.ErrUserBanned
+	}
+
+	if user.TOTPEnabledAt.Valid {
+		challengeToken, err := u.issueUserToken(ctx, user.ID, model.TokenPurposeTwoFactorLogin, u.config.TwoFactorChallengeTTL)
+		if err != nil {
+			return nil, err
+		}
+
+		return &model.LoginResponse{
+			TwoFactorRequired:  true,
+			ChallengeToken:     challengeToken,
+			ChallengeExpiresIn: int64(u.config.TwoFactorChallengeTTL.Seconds()),
+		}, nil
+	}
+
+	familyID, err := token.NewID()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := u.issueTokenPair(ctx, user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResponse{TokenResponse: tokens}, nil
+}
+
+// LoginTwoFactor implements UserUCItf. A challenge token allows a single
+// attempt, after a wrong code the user has to log in again, which keeps the
+// codes from being guessed.
+func (u *UserUsecase) LoginTwoFactor(ctx context.Context, req *model.TwoFactorLoginRequest) (*model.TokenResponse, error) {
+	userToken, err := u.consumeUserToken(ctx, model.TokenPurposeTwoFactorLogin, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.userRepo.GetUserById(ctx, userToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// two-factor was disabled after the challenge was issued
+	if !user.TOTPEnabledAt.Valid {
+		return nil, customError.ErrInvalidToken
+	}
+
+	if err := u.verifySecondFactor(ctx, user, &req.TwoFactorCodeRequest); err != nil {
+		return nil, err
+	}
+
+	familyID, err := token.NewID()
+	if err != nil {
+		return nil, err
+	}
+
+	return u.issueTokenPair(ctx, user, familyID)
+}
+
+// RefreshToken implements UserUCItf. Refresh tokens are single use: every
+// call rotates the presented token for a new one in the same family. Using a
+// token twice means it was stolen, so the whole family is revoked.
+func (u *UserUsecase) RefreshToken(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
+	if refreshToken == "" {
+		return nil, customError.ErrInvalidRefreshToken
+	}
+
+	current, err := u.tokenRepo.GetRefreshTokenByHash(ctx, token.Hash(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if current.UsedAt.Valid || current.RevokedAt.Valid {
+		if err := u.tokenRepo.RevokeRefreshTokenFamily(ctx, current.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, customError.ErrRefreshTokenReused
+	}
+
+	if now.After(current.ExpiresAt) {
+		return nil, customError.ErrInvalidRefreshToken
+	}
+
+	marked, err := u.tokenRepo.MarkRefreshTokenUsed(ctx, current.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// another request rotated this token first
+	if !marked {
+		if err := u.tokenRepo.RevokeRefreshTokenFamily(ctx, current.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, customError.ErrRefreshTokenReused
+	}
+
+	// the role is read again so a role change applies on the next refresh
+	user, err := u.userRepo.GetUserById(ctx, current.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.issueTokenPair(ctx, user, current.FamilyID)
+}
+
+// Logout implements UserUCItf. A refresh token of another user is ignored
+// like an unknown one, so it cannot be used to end their sessions.
+func (u *UserUsecase) Logout(ctx context.Context, jti string, expiresAt time.Time, refreshToken string, userId int64) error {
+	if err := u.tokenRepo.RevokeAccessToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	current, err := u.tokenRepo.GetRefreshTokenByHash(ctx, token.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, customError.ErrInvalidRefreshToken) {
+			return nil
+		}
+		return err
+	}
+
+	if current.UserID != userId {
+		return nil
+	}
+
+	return u.tokenRepo.RevokeRefreshTokenFamily(ctx, current.FamilyID, time.Now())
+}
+
+// StartTokenPurge implements UserUCItf. The revocations of expired access
+// tokens are deleted every interval until ctx is done.
+func (u *UserUsecase) StartTokenPurge(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := u.tokenRepo.DeleteExpiredRevokedTokens(ctx, time.Now()); err != nil {
+					log.Printf("cannot purge revoked tokens: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+// VerifyEmail implements UserUCItf.
+func (u *UserUsecase) VerifyEmail(ctx context.Context, verificationToken string) error {
+	userToken, err := u.consumeUserToken(ctx, model.TokenPurposeEmailVerification, verificationToken)
+	if err != nil {
+		return err
+	}
+
+	return u.userRepo.MarkUserVerified(ctx, userToken.UserID, time.Now())
+}
+
+// ResendVerificationEmail implements UserUCItf. Unknown or already verified
+// addresses, and addresses mailed less than MailResendInterval ago, are
+// silently ignored so the endpoint cannot be used to find out which emails
+// are registered.
+func (u *UserUsecase) ResendVerificationEmail(ctx context.Context, email string) error {
+	user, err := u.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, customError.ErrEmailNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.VerifiedAt.Valid {
+		return nil
+	}
+
+	latest, err := u.tokenRepo.GetLatestUserToken(ctx, user.ID, model.TokenPurposeEmailVerification)
+	if err != nil && !errors.Is(err, customError.ErrInvalidToken) {
+		return err
+	}
+
+	if latest != nil && time.Since(latest.CreatedAt) < u.config.MailResendInterval {
+		return nil
+	}
+
+	return u.sendVerificationEmail(ctx, user)
+}
+
+// ForgotPassword implements UserUCItf. It mails a reset link when the email
+// belongs to an account and otherwise does nothing, so callers always get the
+// same answer. Requests made shortly after a previous one are dropped to
+// avoid flooding the inbox. Failures once the account is found are only
+// logged, as they would tell which emails are registered.
+func (u *UserUsecase) ForgotPassword(ctx context.Context, email string) error {
+	user, err := u.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, customError.ErrEmailNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	latest, err := u.tokenRepo.GetLatestUserToken(ctx, user.ID, model.TokenPurposePasswordReset)
+	if err != nil && !errors.Is(err, customError.ErrInvalidToken) {
+		log.Printf("cannot check password reset tokens of user %d: %s", user.ID, err)
+		return nil
+	}
+
+	if latest != nil && time.Since(latest.CreatedAt) < u.config.MailResendInterval {
+		return nil
+	}
+
+	resetToken, err := u.issueUserToken(ctx, user.ID, model.TokenPurposePasswordReset, u.config.PasswordResetTokenTTL)
+	if err != nil {
+		log.Printf("cannot issue password reset token for user %d: %s", user.ID, err)
+		return nil
+	}
+
+	err = u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Socialize password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, open the link below to choose a new one:\n\n%s/auth/reset-password?token=%s\n\nThe link expires in %s. If you did not ask for it you can ignore this email.",
+			user.Name, u.config.AppURL, resetToken, u.config.PasswordResetTokenTTL),
+	})
+	if err != nil {
+		log.Printf("cannot send password reset email to user %d: %s", user.ID, err)
+	}
+
+	return nil
+}
+
+// ResetPassword implements UserUCItf. Every session of the user is ended once
+// the password has changed.
+func (u *UserUsecase) ResetPassword(ctx context.Context, req *model.ResetPasswordRequest) error {
+	if req.Password == "" || req.Password != req.ConfirmPassword {
+		return customError.ErrPasswordNotMatch
+	}
+
+	userToken, err := u.consumeUserToken(ctx, model.TokenPurposePasswordReset, req.Token)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := u.hasher.Hash(req.Password)
+	if err != nil {
+		return err
+	}
+
+	if err := u.userRepo.UpdateUserPassword(ctx, userToken.UserID, hashedPassword); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	// receiving the reset mail proves the user owns the address
+	if err := u.userRepo.MarkUserVerified(ctx, userToken.UserID, now); err != nil {
+		return err
+	}
+
+	return u.revokeSessions(ctx, userToken.UserID, now)
+}
+
+// ChangePassword implements UserUCItf. Every other session is ended and a
+// new token pair is returned so the caller stays logged in.
+func (u *UserUsecase) ChangePassword(ctx context.Context, req *model.ChangePasswordRequest, userId int64) (*model.TokenResponse, error) {
+	if req.NewPassword == "" || req.NewPassword != req.ConfirmPassword {
+		return nil, customError.ErrPasswordNotMatch
+	}
+
+	user, err := u.verifyCurrentPassword(ctx, userId, req.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := u.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.UpdateUserPassword(ctx, user.ID, hashedPassword); err != nil {
+		return nil, err
+	}
+
+	if err := u.revokeSessions(ctx, user.ID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your Socialize password was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe password of your account was just changed and every other session was logged out. If it was not you, reset your password right away.",
+			user.Name),
+	}); err != nil {
+		log.Printf("cannot send password change notice to user %d: %s", user.ID, err)
+	}
+
+	familyID, err := token.NewID()
+	if err != nil {
+		return nil, err
+	}
+
+	return u.issueTokenPair(ctx, user, familyID)
+}
+
+// ChangeEmail implements UserUCItf. The new address only replaces the current
+// one once it is confirmed from a link sent to it, and the current address is
+// told about the request.
+func (u *UserUsecase) ChangeEmail(ctx context.Context, req *model.ChangeEmailRequest, userId int64) (*model.UserResponse, error) {
+	user, err := u.verifyCurrentPassword(ctx, userId, req.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	exist, err := u.userRepo.CheckEmailExist(ctx, req.NewEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	if exist {
+		return nil, fmt.Errorf("email : %s already exists: %w", req.NewEmail, customError.ErrEmailExist)
+	}
+
+	if err := u.userRepo.SetPendingEmail(ctx, user.ID, req.NewEmail); err != nil {
+		return nil, err
+	}
+
+	user.PendingEmail = sql.NullString{String: req.NewEmail, Valid: true}
+
+	confirmationToken, err := u.issueUserToken(ctx, user.ID, model.TokenPurposeEmailChange, u.config.EmailChangeTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.mailer.Send(ctx, mailer.Message{
+		To:      req.NewEmail,
+		Subject: "Confirm your new Socialize email",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm this address as the new email of your account by opening the link below:\n\n%s/auth/confirm-email-change?token=%s\n\nThe link expires in %s.",
+			user.Name, u.config.AppURL, confirmationToken, u.config.EmailChangeTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Email change requested on your Socialize account",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email of your account to %s. The change only happens once it is confirmed from the new address. If it was not you, change your password right away.",
+			user.Name, req.NewEmail),
+	}); err != nil {
+		log.Printf("cannot send email change notice to user %d: %s", user.ID, err)
+	}
+
+	return convertToUserRespone(user), nil
+}
+
+// ConfirmEmailChange implements UserUCItf. Only the link from the latest
+// change request is honoured.
+func (u *UserUsecase) ConfirmEmailChange(ctx context.Context, confirmationToken string) (*model.UserResponse, error) {
+	userToken, err := u.consumeUserToken(ctx, model.TokenPurposeEmailChange, confirmationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := u.tokenRepo.GetLatestUserToken(ctx, userToken.UserID, model.TokenPurposeEmailChange)
+	if err != nil {
+		return nil, err
+	}
+
+	if latest.ID != userToken.ID {
+		return nil, customError.ErrInvalidToken
+	}
+
+	user, err := u.userRepo.GetUserById(ctx, userToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.PendingEmail.Valid {
+		return nil, customError.ErrInvalidToken
+	}
+
+	exist, err := u.userRepo.CheckEmailExist(ctx, user.PendingEmail.String)
+	if err != nil {
+		return nil, err
+	}
+
+	if exist {
+		return nil, fmt.Errorf("email : %s already exists: %w", user.PendingEmail.String, customError.ErrEmailExist)
+	}
+
+	now := time.Now()
+
+	if err := u.userRepo.ConfirmPendingEmail(ctx, user.ID, user.PendingEmail.String, now); err != nil {
+		return nil, err
+	}
+
+	user.Email = user.PendingEmail.String
+	user.PendingEmail = sql.NullString{}
+	user.VerifiedAt = sql.NullTime{Time: now, Valid: true}
+	user.UpdatedAt = now
+
+	return convertToUserRespone(user), nil
+}
+
+// EnrollTwoFactor implements UserUCItf. It generates a new secret that only
+// takes effect once ConfirmTwoFactor receives a code generated from it.
+func (u *UserUsecase) EnrollTwoFactor(ctx context.Context, req *model.EnrollTwoFactorRequest, userId int64) (*model.TwoFactorEnrollment, error) {
+	user, err := u.verifyCurrentPassword(ctx, userId, req.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabledAt.Valid {
+		return nil, customError.ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	uri := totp.URI(u.config.TOTPIssuer, user.Email, secret)
+
+	qrCode, err := totp.QRCode(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.SetTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCode:     qrCode,
+	}, nil
+}
+
+// ConfirmTwoFactor implements UserUCItf. The recovery codes are only ever
+// returned here and by RegenerateRecoveryCodes, they are stored hashed.
+func (u *UserUsecase) ConfirmTwoFactor(ctx context.Context, req *model.ConfirmTwoFactorRequest, userId int64) (*model.RecoveryCodesResponse, error) {
+	user, err := u.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabledAt.Valid {
+		return nil, customError.ErrTwoFactorAlreadyEnabled
+	}
+
+	if !user.TOTPSecret.Valid {
+		return nil, customError.ErrTwoFactorNotEnrolled
+	}
+
+	now := time.Now()
+
+	step, ok := totp.Validate(user.TOTPSecret.String, req.Code, now)
+	if !ok {
+		return nil, customError.ErrInvalidTwoFactorCode
+	}
+
+	if err := u.userRepo.EnableTOTP(ctx, user.ID, step, now); err != nil {
+		return nil, err
+	}
+
+	if err := u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Two-factor authentication enabled on your Socialize account",
+		Body: fmt.Sprintf("Hi %s,\n\nTwo-factor authentication was just enabled on your account. If it was not you, reset your password right away.",
+			user.Name),
+	}); err != nil {
+		log.Printf("cannot send two-factor notice to user %d: %s", user.ID, err)
+	}
+
+	return u.issueRecoveryCodes(ctx, user.ID, now)
+}
+
+// DisableTwoFactor implements UserUCItf. It asks for both the password and a
+// second factor so a stolen session alone cannot turn it off.
+func (u *UserUsecase) DisableTwoFactor(ctx context.Context, req *model.DisableTwoFactorRequest, userId int64) error {
+	user, err := u.verifyCurrentPassword(ctx, userId, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabledAt.Valid {
+		return customError.ErrTwoFactorNotEnabled
+	}
+
+	if err := u.verifySecondFactor(ctx, user, &req.TwoFactorCodeRequest); err != nil {
+		return err
+	}
+
+	if err := u.userRepo.DisableTOTP(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := u.tokenRepo.DeleteRecoveryCodes(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Two-factor authentication disabled on your Socialize account",
+		Body: fmt.Sprintf("Hi %s,\n\nTwo-factor authentication was just disabled on your account. If it was not you, reset your password right away.",
+			user.Name),
+	}); err != nil {
+		log.Printf("cannot send two-factor notice to user %d: %s", user.ID, err)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes implements UserUCItf. Codes issued before stop
+// working.
+func (u *UserUsecase) RegenerateRecoveryCodes(ctx context.Context, req *model.TwoFactorCodeRequest, userId int64) (*model.RecoveryCodesResponse, error) {
+	user, err := u.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TOTPEnabledAt.Valid {
+		return nil, customError.ErrTwoFactorNotEnabled
+	}
+
+	if err := u.verifySecondFactor(ctx, user, req); err != nil {
+		return nil, err
+	}
+
+	return u.issueRecoveryCodes(ctx, user.ID, time.Now())
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+// A TOTP code is refused if a code of the same time step was already
+// accepted, so an intercepted code cannot be replayed.
+func (u *UserUsecase) verifySecondFactor(ctx context.Context, user *model.User, req *model.TwoFactorCodeRequest) error {
+	now := time.Now()
+
+	if req.RecoveryCode != "" {
+		used, err := u.tokenRepo.UseRecoveryCode(ctx, user.ID, token.Hash(totp.NormalizeRecoveryCode(req.RecoveryCode)), now)
+		if err != nil {
+			return err
+		}
+
+		if !used {
+			return customError.ErrInvalidTwoFactorCode
+		}
+
+		return nil
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret.String, req.Code, now)
+	if !ok {
+		return customError.ErrInvalidTwoFactorCode
+	}
+
+	accepted, err := u.userRepo.UpdateTOTPLastStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+
+	if !accepted {
+		return customError.ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (u *UserUsecase) issueRecoveryCodes(ctx context.Context, userId int64, now time.Time) (*model.RecoveryCodesResponse, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := totp.RecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, token.Hash(totp.NormalizeRecoveryCode(code)))
+	}
+
+	if err := u.tokenRepo.ReplaceRecoveryCodes(ctx, userId, hashes, now); err != nil {
+		return nil, err
+	}
+
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (u *UserUsecase) verifyCurrentPassword(ctx context.Context, userId int64, currentPassword string) (*model.User, error) {
+	user, err := u.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := u.hasher.Verify(currentPassword, user.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, customError.ErrIncorrectPassword
+	}
+
+	return user, nil
+}
+
+// revokeSessions invalidates every refresh token of the user and every access
+// token issued before now.
+func (u *UserUsecase) revokeSessions(ctx context.Context, userId int64, now time.Time) error {
+	if err := u.tokenRepo.RevokeUserRefreshTokens(ctx, userId, now); err != nil {
+		return err
+	}
+
+	// token iat claims have a one second resolution
+	return u.userRepo.RevokeUserSessions(ctx, userId, now.Truncate(time.Second))
+}
+
+func (u *UserUsecase) sendVerificationEmail(ctx context.Context, user *model.User) error {
+	verificationToken, err := u.issueUserToken(ctx, user.ID, model.TokenPurposeEmailVerification, u.config.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Socialize account",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease verify your email address by opening the link below:\n\n%s/auth/verify-email?token=%s\n\nThe link expires in %s.",
+			user.Name, u.config.AppURL, verificationToken, u.config.VerificationTokenTTL),
+	})
+}
+
+// issueUserToken stores a new single use token for purpose and returns its
+// signed form, which is what gets mailed to the user.
+func (u *UserUsecase) issueUserToken(ctx context.Context, userId int64, purpose string, ttl time.Duration) (string, error) {
+	plain, hash, err := token.Generate()
 	if err != nil {
 		return "", err
 	}
 
-	token, err := u.jwt.CreateToken(user.ID)
+	now := time.Now()
+
+	err = u.tokenRepo.CreateUserToken(ctx, &model.UserToken{
+		UserID:    userId,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
 	if err != nil {
 		return "", err
 	}
 
-	return token, nil
+	return u.tokenSigner.Sign(purpose, plain), nil
+}
+
+// consumeUserToken checks the signature of a mailed token and marks it used.
+// Any invalid, expired or already used token yields ErrInvalidToken.
+func (u *UserUsecase) consumeUserToken(ctx context.Context, purpose string, signed string) (*model.UserToken, error) {
+	plain, ok := u.tokenSigner.Verify(purpose, signed)
+	if !ok {
+		return nil, customError.ErrInvalidToken
+	}
+
+	userToken, err := u.tokenRepo.GetUserToken(ctx, purpose, token.Hash(plain))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if userToken.UsedAt.Valid || now.After(userToken.ExpiresAt) {
+		return nil, customError.ErrInvalidToken
+	}
+
+	marked, err := u.tokenRepo.MarkUserTokenUsed(ctx, userToken.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if !marked {
+		return nil, customError.ErrInvalidToken
+	}
+
+	return userToken, nil
+}
+
+// issueTokenPair is the single place tokens are handed out, which makes it
+// the place banned users are stopped.
+func (u *UserUsecase) issueTokenPair(ctx context.Context, user *model.User, familyID string) (*model.TokenResponse, error) {
+	if user.BannedAt.Valid {
+		return nil, customError.ErrUserBanned
+	}
+
+	accessToken, err := u.jwt.CreateToken(user.ID, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshTokenHash, err := token.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	err = u.tokenRepo.CreateRefreshToken(ctx, &model.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: refreshTokenHash,
+		ExpiresAt: now.Add(u.config.RefreshTokenTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(u.jwt.ExpiresIn().Seconds()),
+	}, nil
 }
 
 // GetUserById implements UserUCItf.
@@ -91,20 +923,10 @@ func (u *UserUsecase) UpdateUserData(ctx context.Context, req *model.UserUpdateD
 		return nil, err
 	}
 
-	if req.Email != "" {
-		user.Email = req.Email
-	}
-
 	if req.Name != "" {
 		user.Name = req.Name
 	}
 
-	if req.Password != "" {
-		hashedPassword := md5.HashWithMd5(req.Password)
-
-		user.Password = hashedPassword
-	}
-
 	user.UpdatedAt = time.Now()
 
 	err = u.userRepo.UpdateUserData(ctx, user)
@@ -112,6 +934,8 @@ func (u *UserUsecase) UpdateUserData(ctx context.Context, req *model.UserUpdateD
 		return nil, err
 	}
 
+	u.search.SyncDocument(ctx, model.SearchTypeUser, user.ID)
+
 	return convertToUserRespone(user), nil
 }
 
@@ -138,13 +962,30 @@ func (u *UserUsecase) UpdateUserPhoto(ctx context.Context, req *model.UserUpdate
 	return convertToUserRespone(user), nil
 }
 
+func (u *UserUsecase) rehashPassword(ctx context.Context, userId int64, plain string) {
+	hashedPassword, err := u.hasher.Hash(plain)
+	if err != nil {
+		log.Printf("cannot rehash password for user %d: %s", userId, err)
+		return
+	}
+
+	if err := u.userRepo.UpdateUserPassword(ctx, userId, hashedPassword); err != nil {
+		log.Printf("cannot store rehashed password for user %d: %s", userId, err)
+	}
+}
+
 func convertToUserRespone(user *model.User) *model.UserResponse {
 	return &model.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		Photo:     user.Photo.String,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:           user.ID,
+		Name:         user.Name,
+		Email:        user.Email,
+		PendingEmail: user.PendingEmail.String,
+		Photo:        user.Photo.String,
+		Verified:     user.VerifiedAt.Valid,
+		TwoFactor:    user.TOTPEnabledAt.Valid,
+		Role:         user.Role,
+		Banned:       user.BannedAt.Valid,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
 	}
 }
@@ -0,0 +1,2 @@
+ALTER TABLE `users`
+MODIFY `password` varchar(155) NOT NULL;
@@ -0,0 +1,19 @@
+CREATE TABLE `refresh_tokens` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `family_id` varchar(64) NOT NULL,
+  `token_hash` char(64) UNIQUE NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  `used_at` timestamp NULL,
+  `revoked_at` timestamp NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_refresh_tokens_family` (`family_id`)
+);
+
+CREATE TABLE `revoked_tokens` (
+  `jti` varchar(64) PRIMARY KEY,
+  `expires_at` timestamp NOT NULL
+);
+
+ALTER TABLE `refresh_tokens`
+ADD FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+drop table if exists user_tokens;
+
+ALTER TABLE `users`
+DROP COLUMN `verified_at`;
@@ -0,0 +1,19 @@
+ALTER TABLE `users`
+ADD `verified_at` timestamp NULL;
+
+-- accounts created before verification existed are trusted as verified
+UPDATE `users` SET `verified_at` = `created_at`;
+
+CREATE TABLE `user_tokens` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `purpose` varchar(32) NOT NULL,
+  `token_hash` char(64) UNIQUE NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  `used_at` timestamp NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_user_tokens_user_purpose` (`user_id`, `purpose`, `created_at`)
+);
+
+ALTER TABLE `user_tokens`
+ADD FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
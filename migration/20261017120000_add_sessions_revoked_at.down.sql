@@ -0,0 +1,2 @@
+ALTER TABLE `users`
+DROP COLUMN `sessions_revoked_at`;
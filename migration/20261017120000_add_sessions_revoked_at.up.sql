@@ -0,0 +1,2 @@
+ALTER TABLE `users`
+ADD `sessions_revoked_at` timestamp NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE `users`
+ADD `pending_email` varchar(100) NULL;
@@ -0,0 +1,6 @@
+drop table if exists recovery_codes;
+
+ALTER TABLE `users`
+DROP COLUMN `totp_secret`,
+DROP COLUMN `totp_enabled_at`,
+DROP COLUMN `totp_last_step`;
@@ -0,0 +1,16 @@
+ALTER TABLE `users`
+ADD `totp_secret` varchar(64) NULL,
+ADD `totp_enabled_at` timestamp NULL,
+ADD `totp_last_step` bigint NULL;
+
+CREATE TABLE `recovery_codes` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `code_hash` char(64) NOT NULL,
+  `used_at` timestamp NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_recovery_codes_user_code` (`user_id`, `code_hash`)
+);
+
+ALTER TABLE `recovery_codes`
+ADD FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,12 @@
+CREATE TABLE `user_identities` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `provider` varchar(32) NOT NULL,
+  `subject` varchar(255) NOT NULL,
+  `email` varchar(100) NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_user_identities_provider_subject` (`provider`, `subject`)
+);
+
+ALTER TABLE `user_identities`
+ADD FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE;
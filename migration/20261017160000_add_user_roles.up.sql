@@ -0,0 +1,5 @@
+-- the first admin has to be promoted by hand, e.g.
+-- UPDATE `users` SET `role` = 'admin' WHERE `email` = 'admin@example.com';
+ALTER TABLE `users`
+ADD `role` varchar(20) NOT NULL DEFAULT 'user',
+ADD `banned_at` timestamp NULL;
@@ -0,0 +1,8 @@
+drop table if exists comment_revisions;
+drop table if exists post_revisions;
+
+ALTER TABLE `comments`
+DROP COLUMN `edited_at`;
+
+ALTER TABLE `posts`
+DROP COLUMN `edited_at`;
@@ -0,0 +1,33 @@
+ALTER TABLE `posts`
+ADD COLUMN `edited_at` timestamp NULL;
+
+ALTER TABLE `comments`
+ADD COLUMN `edited_at` timestamp NULL;
+
+-- every row is a version that was replaced by an edit, created_at is when that
+-- version was written and replaced_at when it stopped being the current one
+CREATE TABLE `post_revisions` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `post_id` int NOT NULL,
+  `title` varchar(255) NOT NULL,
+  `content` text,
+  `image` varchar(255),
+  `created_at` timestamp NULL,
+  `replaced_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_post_revisions_post_id` (`post_id`)
+);
+
+CREATE TABLE `comment_revisions` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `comment_id` int NOT NULL,
+  `comment` varchar(255) NOT NULL,
+  `created_at` timestamp NULL,
+  `replaced_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_comment_revisions_comment_id` (`comment_id`)
+);
+
+ALTER TABLE `post_revisions`
+ADD FOREIGN KEY (`post_id`) REFERENCES `posts` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `comment_revisions`
+ADD FOREIGN KEY (`comment_id`) REFERENCES `comments` (`id`) ON DELETE CASCADE;
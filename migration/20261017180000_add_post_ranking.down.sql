@@ -0,0 +1,8 @@
+ALTER TABLE `posts`
+DROP INDEX `idx_posts_score`,
+DROP INDEX `idx_posts_net_vote`,
+DROP COLUMN `score`,
+DROP COLUMN `comment_count`,
+DROP COLUMN `net_vote`,
+DROP COLUMN `down_vote`,
+DROP COLUMN `up_vote`;
@@ -0,0 +1,14 @@
+ALTER TABLE `posts`
+ADD COLUMN `up_vote` int NOT NULL DEFAULT 0,
+ADD COLUMN `down_vote` int NOT NULL DEFAULT 0,
+ADD COLUMN `net_vote` int GENERATED ALWAYS AS (`up_vote` - `down_vote`) STORED,
+ADD COLUMN `comment_count` int NOT NULL DEFAULT 0,
+ADD COLUMN `score` double NOT NULL DEFAULT 0,
+ADD INDEX `idx_posts_net_vote` (`net_vote`, `id`),
+ADD INDEX `idx_posts_score` (`score`, `id`);
+
+-- the scores are filled in by the recomputation job at startup
+UPDATE `posts` AS p SET
+  p.up_vote = (SELECT COUNT(*) FROM `votes` WHERE post_id = p.id AND vote = 1),
+  p.down_vote = (SELECT COUNT(*) FROM `votes` WHERE post_id = p.id AND vote = -1),
+  p.comment_count = (SELECT COUNT(*) FROM `comments` WHERE post_id = p.id);
@@ -0,0 +1,8 @@
+ALTER TABLE `users`
+DROP INDEX `ft_users_name`;
+
+ALTER TABLE `comments`
+DROP INDEX `ft_comments_comment`;
+
+ALTER TABLE `posts`
+DROP INDEX `ft_posts_title_content`;
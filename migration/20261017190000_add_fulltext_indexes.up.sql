@@ -0,0 +1,8 @@
+ALTER TABLE `posts`
+ADD FULLTEXT INDEX `ft_posts_title_content` (`title`, `content`);
+
+ALTER TABLE `comments`
+ADD FULLTEXT INDEX `ft_comments_comment` (`comment`);
+
+ALTER TABLE `users`
+ADD FULLTEXT INDEX `ft_users_name` (`name`);
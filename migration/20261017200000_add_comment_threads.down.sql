@@ -0,0 +1,14 @@
+-- replies and tombstones cannot be represented in a flat list
+UPDATE `comments` SET `parent_id` = NULL;
+
+DELETE FROM `comments` WHERE `deleted_at` IS NOT NULL;
+
+ALTER TABLE `comments`
+DROP FOREIGN KEY `fk_comments_parent`;
+
+ALTER TABLE `comments`
+DROP INDEX `idx_comments_parent`,
+DROP INDEX `idx_comments_thread`,
+DROP COLUMN `deleted_at`,
+DROP COLUMN `reply_count`,
+DROP COLUMN `parent_id`;
@@ -0,0 +1,11 @@
+-- a deleted comment that still has replies is kept as a tombstone with an
+-- empty text and deleted_at set, so the replies keep their place in the thread
+ALTER TABLE `comments`
+ADD COLUMN `parent_id` int NULL,
+ADD COLUMN `reply_count` int NOT NULL DEFAULT 0,
+ADD COLUMN `deleted_at` timestamp NULL,
+ADD INDEX `idx_comments_thread` (`post_id`, `parent_id`, `id`),
+ADD INDEX `idx_comments_parent` (`parent_id`, `id`);
+
+ALTER TABLE `comments`
+ADD CONSTRAINT `fk_comments_parent` FOREIGN KEY (`parent_id`) REFERENCES `comments` (`id`);
@@ -0,0 +1,7 @@
+drop table if exists reactions;
+
+ALTER TABLE `comments`
+DROP COLUMN `down_vote`,
+DROP COLUMN `up_vote`;
+
+drop table if exists comment_votes;
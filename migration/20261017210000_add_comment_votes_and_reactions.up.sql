@@ -0,0 +1,31 @@
+CREATE TABLE `comment_votes` (
+  `user_id` int,
+  `comment_id` int,
+  `vote` tinyint NOT NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`, `comment_id`),
+  INDEX `idx_comment_votes_comment_id` (`comment_id`)
+);
+
+ALTER TABLE `comment_votes`
+ADD FOREIGN KEY (`user_id`) REFERENCES `users` (`id`),
+ADD FOREIGN KEY (`comment_id`) REFERENCES `comments` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `comments`
+ADD COLUMN `up_vote` int NOT NULL DEFAULT 0,
+ADD COLUMN `down_vote` int NOT NULL DEFAULT 0;
+
+-- a user has at most one reaction on each post or comment, target_type tells
+-- which table target_id belongs to
+CREATE TABLE `reactions` (
+  `user_id` int,
+  `target_type` varchar(16) NOT NULL,
+  `target_id` int NOT NULL,
+  `reaction` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`, `target_type`, `target_id`),
+  INDEX `idx_reactions_target` (`target_type`, `target_id`, `reaction`)
+);
+
+ALTER TABLE `reactions`
+ADD FOREIGN KEY (`user_id`) REFERENCES `users` (`id`);
@@ -0,0 +1,14 @@
+-- follower_id follows followee_id, the id orders the follows for paging
+CREATE TABLE `follows` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `follower_id` int NOT NULL,
+  `followee_id` int NOT NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_follows_pair` (`follower_id`, `followee_id`),
+  INDEX `idx_follows_follower` (`follower_id`, `id`),
+  INDEX `idx_follows_followee` (`followee_id`, `id`)
+);
+
+ALTER TABLE `follows`
+ADD FOREIGN KEY (`follower_id`) REFERENCES `users` (`id`),
+ADD FOREIGN KEY (`followee_id`) REFERENCES `users` (`id`);
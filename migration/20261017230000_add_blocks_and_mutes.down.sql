@@ -0,0 +1,3 @@
+drop table if exists mutes;
+
+drop table if exists blocks;
@@ -0,0 +1,26 @@
+-- blocker_id and blocked_id cannot see nor interact with each other
+CREATE TABLE `blocks` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `blocker_id` int NOT NULL,
+  `blocked_id` int NOT NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_blocks_pair` (`blocker_id`, `blocked_id`),
+  INDEX `idx_blocks_blocked` (`blocked_id`, `blocker_id`)
+);
+
+ALTER TABLE `blocks`
+ADD FOREIGN KEY (`blocker_id`) REFERENCES `users` (`id`),
+ADD FOREIGN KEY (`blocked_id`) REFERENCES `users` (`id`);
+
+-- the posts of muted_id are left out of the feeds of muter_id
+CREATE TABLE `mutes` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `muter_id` int NOT NULL,
+  `muted_id` int NOT NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_mutes_pair` (`muter_id`, `muted_id`)
+);
+
+ALTER TABLE `mutes`
+ADD FOREIGN KEY (`muter_id`) REFERENCES `users` (`id`),
+ADD FOREIGN KEY (`muted_id`) REFERENCES `users` (`id`);
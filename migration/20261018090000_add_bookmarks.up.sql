@@ -0,0 +1,17 @@
+-- a user saves a post once, collection is the folder it is saved in and is
+-- empty when the post is not filed
+CREATE TABLE `bookmarks` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `post_id` int NOT NULL,
+  `collection` varchar(100) NOT NULL DEFAULT '',
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_bookmarks_user_post` (`user_id`, `post_id`),
+  INDEX `idx_bookmarks_user` (`user_id`, `id`),
+  INDEX `idx_bookmarks_collection` (`user_id`, `collection`, `id`),
+  INDEX `idx_bookmarks_post_id` (`post_id`)
+);
+
+ALTER TABLE `bookmarks`
+ADD FOREIGN KEY (`user_id`) REFERENCES `users` (`id`),
+ADD FOREIGN KEY (`post_id`) REFERENCES `posts` (`id`);
@@ -0,0 +1,5 @@
+drop table if exists notification_preferences;
+
+drop table if exists notification_actors;
+
+drop table if exists notifications;
@@ -0,0 +1,47 @@
+-- a notification gathers the events of one type on one target while it is
+-- unread, unread is NULL once it is read so the unique index only covers the
+-- unread ones and the next event opens a new notification
+CREATE TABLE `notifications` (
+  `id` int PRIMARY KEY AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `type` varchar(20) NOT NULL,
+  `target_type` varchar(20) NOT NULL,
+  `target_id` int NOT NULL,
+  `post_id` int,
+  `last_actor_id` int NOT NULL,
+  `actor_count` int NOT NULL DEFAULT 0,
+  `unread` tinyint(1) DEFAULT 1,
+  `read_at` timestamp NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_notifications_group` (`user_id`, `type`, `target_type`, `target_id`, `unread`),
+  INDEX `idx_notifications_user` (`user_id`, `updated_at`, `id`),
+  INDEX `idx_notifications_post_id` (`post_id`)
+);
+
+-- the distinct users behind the events of a notification
+CREATE TABLE `notification_actors` (
+  `notification_id` int NOT NULL,
+  `actor_id` int NOT NULL,
+  `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`notification_id`, `actor_id`)
+);
+
+-- a missing row means the type is enabled
+CREATE TABLE `notification_preferences` (
+  `user_id` int NOT NULL,
+  `type` varchar(20) NOT NULL,
+  `enabled` tinyint(1) NOT NULL,
+  PRIMARY KEY (`user_id`, `type`)
+);
+
+ALTER TABLE `notifications`
+ADD FOREIGN KEY (`user_id`) REFERENCES `users` (`id`),
+ADD FOREIGN KEY (`last_actor_id`) REFERENCES `users` (`id`);
+
+ALTER TABLE `notification_actors`
+ADD FOREIGN KEY (`notification_id`) REFERENCES `notifications` (`id`),
+ADD FOREIGN KEY (`actor_id`) REFERENCES `users` (`id`);
+
+ALTER TABLE `notification_preferences`
+ADD FOREIGN KEY (`user_id`) REFERENCES `users` (`id`);
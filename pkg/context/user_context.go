@@ -2,4 +2,9 @@ package context
 
 type ContextKey string
 
-const UserIDKey ContextKey = "userID"
\ No newline at end of file
+const (
+	UserIDKey         ContextKey = "userID"
+	UserRoleKey       ContextKey = "userRole"
+	TokenIDKey        ContextKey = "tokenID"
+	TokenExpiresAtKey ContextKey = "tokenExpiresAt"
+)
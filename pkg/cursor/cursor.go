@@ -0,0 +1,35 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode turns the position of the last item of a page into an opaque token
+// clients hand back to get the next page. Cursors are not signed, tampering
+// with one only moves the caller to another position in the same listing.
+func Encode(position any) (string, error) {
+	data, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decode reads a token made by Encode into position.
+func Decode(token string, position any) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, position); err != nil {
+		return ErrInvalidCursor
+	}
+
+	return nil
+}
@@ -8,7 +8,35 @@ var (
 	ErrEmailExist        = errors.New("email already exist")
 	ErrNotVerified       = errors.New("account has not been verified")
 	ErrIncorrectPassword = errors.New("incorrect password")
+	ErrPasswordNotMatch  = errors.New("password and confirm password do not match")
 	ErrDatabase          = errors.New("database error")
 	ErrRowsAffected      = errors.New("error due to there is no or more than 1 affected column")
 	ErrLastInsertId      = errors.New("error due to last insert id")
+
+	ErrInvalidRefreshToken     = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused      = errors.New("refresh token has already been used")
+	ErrTokenRevoked            = errors.New("token has been revoked")
+	ErrInvalidToken            = errors.New("invalid or expired token")
+	ErrUserBanned              = errors.New("account has been banned")
+	ErrPermissionDenied        = errors.New("you do not have permission to perform this action")
+	ErrNotFound                = errors.New("resource not found")
+	ErrInvalidRole             = errors.New("invalid role")
+	ErrEmptyComment            = errors.New("comment cannot be empty")
+	ErrInvalidSearchQuery      = errors.New("invalid search query")
+	ErrUnknownReaction         = errors.New("unknown reaction")
+	ErrFollowSelf              = errors.New("you cannot follow yourself")
+	ErrBlocked                 = errors.New("you cannot interact with this user")
+	ErrRestrictSelf            = errors.New("you cannot block or mute yourself")
+	ErrInvalidCollection       = errors.New("collection name is too long")
+	ErrUnknownNotificationType = errors.New("unknown notification type")
+
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment has not been started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+
+	ErrUnknownOAuthProvider  = errors.New("unknown oauth provider")
+	ErrInvalidOAuthState     = errors.New("invalid or expired oauth state")
+	ErrOAuthEmailNotVerified = errors.New("the identity provider did not return a verified email")
+	ErrOAuthDenied           = errors.New("the identity provider did not grant access")
 )
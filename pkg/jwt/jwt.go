@@ -1,55 +1,87 @@
 package jwt
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/federicodosantos/socialize/pkg/token"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type JWTItf interface {
-	CreateToken(userID int64) (string, error)
-	VerifyToken(tokenString string) (int64, error)
+	CreateToken(userID int64, role string) (string, error)
+	VerifyToken(tokenString string) (*UserClaim, error)
+	ExpiresIn() time.Duration
+	JWKS() JWKSet
 }
 
 type JWT struct {
-	SecretKey  string
+	Keys       *KeySet
 	ExpireTime time.Duration
+	Issuer     string
+	Audience   string
 }
 
-func NewJwt(SecretKey string, ExpireTime string) (JWTItf, error) {
+func NewJwt(keys *KeySet, ExpireTime string, Issuer string, Audience string) (JWTItf, error) {
 	exp, err := time.ParseDuration(ExpireTime)
 	if err != nil {
 		return nil, fmt.Errorf("invalid duration format for expireTime: %v", err)
 	}
 
+	if Issuer == "" || Audience == "" {
+		return nil, fmt.Errorf("jwt issuer and audience are required")
+	}
+
 	return &JWT{
-		SecretKey:  SecretKey,
+		Keys:       keys,
 		ExpireTime: exp,
+		Issuer:     Issuer,
+		Audience:   Audience,
 	}, nil
 }
 
 type UserClaim struct {
 	jwt.RegisteredClaims
-	UserID int64
+	// Role is the role of the user when the token was issued.
+	Role string `json:"role"`
+	// UserID is read from the subject claim.
+	UserID int64 `json:"-"`
 }
 
 // CreateToken implements JWTItf.
-func (j *JWT) CreateToken(userID int64) (string, error) {
+func (j *JWT) CreateToken(userID int64, role string) (string, error) {
 	if j.ExpireTime <= 0 {
 		return "", fmt.Errorf("jwt expire time must be greater than 0")
 	}
 
+	jti, err := token.NewID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
 	claims := &UserClaim{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpireTime)),
+			ID:        jti,
+			Issuer:    j.Issuer,
+			Audience:  jwt.ClaimStrings{j.Audience},
+			Subject:   strconv.FormatInt(userID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.ExpireTime)),
 		},
-		UserID: userID,
+		Role: role,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signer := j.Keys.Current()
 
-	signedToken, err := token.SignedString([]byte(j.SecretKey))
+	token := jwt.NewWithClaims(signer.Method(), claims)
+	token.Header["kid"] = signer.KeyID()
+
+	signedToken, err := token.SignedString(signer.SigningKey())
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %v", err)
 	}
@@ -58,19 +90,62 @@ func (j *JWT) CreateToken(userID int64) (string, error) {
 }
 
 // VerifyToken implements JWTItf.
-func (j *JWT) VerifyToken(tokenString string) (int64, error) {
+func (j *JWT) VerifyToken(tokenString string) (*UserClaim, error) {
 	var claims UserClaim
 
-	token, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(j.SecretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &claims, j.keyFunc,
+		jwt.WithValidMethods([]string{j.Keys.Algorithm()}),
+		jwt.WithIssuer(j.Issuer),
+		jwt.WithAudience(j.Audience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse token: %v", err)
+		return nil, fmt.Errorf("failed to parse token: %v", err)
 	}
 
 	if !token.Valid {
-		return 0, fmt.Errorf("invalid token")
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	if claims.NotBefore == nil {
+		return nil, fmt.Errorf("invalid token: missing nbf claim")
+	}
+
+	claims.UserID, err = strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token subject: %v", err)
 	}
 
-	return claims.UserID, nil
+	return &claims, nil
+}
+
+// keyFunc picks the verification key from the kid header and makes sure the
+// alg header matches the algorithm of that key.
+func (j *JWT) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, ok := t.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+
+	signer, ok := j.Keys.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if t.Method.Alg() != signer.Method().Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+
+	return signer.VerificationKey(), nil
+}
+
+// ExpiresIn implements JWTItf.
+func (j *JWT) ExpiresIn() time.Duration {
+	return j.ExpireTime
+}
+
+// JWKS implements JWTItf.
+func (j *JWT) JWKS() JWKSet {
+	return j.Keys.JWKS()
 }
@@ -0,0 +1,212 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/federicodosantos/socialize/pkg/token"
+)
+
+type keyEntry struct {
+	signer Signer
+	// verifyUntil is when the key stops being accepted for verification. It
+	// is zero for keys that stay valid until they are removed from config.
+	verifyUntil time.Time
+}
+
+// KeySet holds the key currently used for signing and the previous keys that
+// are still accepted when verifying tokens they signed.
+type KeySet struct {
+	mu        sync.RWMutex
+	algorithm string
+	current   *keyEntry
+	previous  []*keyEntry
+}
+
+func NewKeySet(current Signer, previous ...Signer) *KeySet {
+	ks := &KeySet{
+		algorithm: current.Method().Alg(),
+		current:   &keyEntry{signer: current},
+	}
+
+	for _, signer := range previous {
+		ks.previous = append(ks.previous, &keyEntry{signer: signer})
+	}
+
+	return ks
+}
+
+type KeyConfig struct {
+	Algorithm string
+	SecretKey string
+	// PrivateKeys is a comma separated list of kid=path entries pointing to
+	// PEM files. The first entry signs new tokens, the others only verify.
+	PrivateKeys string
+}
+
+func NewKeySetFromConfig(cfg KeyConfig) (*KeySet, error) {
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = AlgorithmHS256
+	}
+
+	if algorithm == AlgorithmHS256 {
+		secrets := strings.Split(cfg.SecretKey, ",")
+
+		var signers []Signer
+		for _, secret := range secrets {
+			secret = strings.TrimSpace(secret)
+
+			// the kid is derived from the secret so it stays the same when
+			// secrets are reordered during a rotation
+			signer, err := NewHMACSigner("hs256-"+token.Hash(secret)[:16], []byte(secret))
+			if err != nil {
+				return nil, err
+			}
+			signers = append(signers, signer)
+		}
+
+		return NewKeySet(signers[0], signers[1:]...), nil
+	}
+
+	if cfg.PrivateKeys == "" {
+		return nil, fmt.Errorf("no private keys configured for %s", algorithm)
+	}
+
+	var signers []Signer
+	for _, entry := range strings.Split(cfg.PrivateKeys, ",") {
+		kid, path, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || kid == "" || path == "" {
+			return nil, fmt.Errorf("invalid private key entry %q, expected kid=path", entry)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read key %s: %v", kid, err)
+		}
+
+		signer, err := ParsePrivateKey(algorithm, kid, data)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+
+	return NewKeySet(signers[0], signers[1:]...), nil
+}
+
+// Current returns the key used to sign new tokens.
+func (ks *KeySet) Current() Signer {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.current.signer
+}
+
+// Lookup returns the key identified by kid if it is still accepted.
+func (ks *KeySet) Lookup(kid string) (Signer, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if ks.current.signer.KeyID() == kid {
+		return ks.current.signer, true
+	}
+
+	now := time.Now()
+	for _, entry := range ks.previous {
+		if entry.signer.KeyID() != kid {
+			continue
+		}
+		if !entry.verifyUntil.IsZero() && now.After(entry.verifyUntil) {
+			return nil, false
+		}
+		return entry.signer, true
+	}
+
+	return nil, false
+}
+
+// Rotate makes next the signing key. The replaced key keeps verifying tokens
+// for gracePeriod, which should be at least the lifetime of a token.
+func (ks *KeySet) Rotate(next Signer, gracePeriod time.Duration) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := time.Now()
+
+	retired := ks.current
+	retired.verifyUntil = now.Add(gracePeriod)
+
+	var previous []*keyEntry
+	for _, entry := range append([]*keyEntry{retired}, ks.previous...) {
+		if entry.verifyUntil.IsZero() || now.Before(entry.verifyUntil) {
+			previous = append(previous, entry)
+		}
+	}
+
+	ks.current = &keyEntry{signer: next}
+	ks.previous = previous
+}
+
+// StartRotation generates a new key of the set's algorithm every interval
+// until ctx is done. Keys generated this way only live in memory, so it is
+// meant for single instance deployments; multiple replicas should share keys
+// through config instead.
+func (ks *KeySet) StartRotation(ctx context.Context, interval time.Duration, gracePeriod time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				kid, err := token.NewID()
+				if err != nil {
+					log.Printf("cannot rotate jwt key: %s", err)
+					continue
+				}
+
+				signer, err := GenerateSigner(ks.algorithm, kid)
+				if err != nil {
+					log.Printf("cannot rotate jwt key: %s", err)
+					continue
+				}
+
+				ks.Rotate(signer, gracePeriod)
+			}
+		}
+	}()
+}
+
+// Algorithm returns the signing algorithm shared by every key of the set.
+func (ks *KeySet) Algorithm() string {
+	return ks.algorithm
+}
+
+// JWKS returns the public keys that are currently accepted for verification.
+func (ks *KeySet) JWKS() JWKSet {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+
+	now := time.Now()
+	for _, entry := range append([]*keyEntry{ks.current}, ks.previous...) {
+		if !entry.verifyUntil.IsZero() && now.After(entry.verifyUntil) {
+			continue
+		}
+		if jwk, ok := entry.signer.PublicJWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	return set
+}
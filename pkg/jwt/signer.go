@@ -0,0 +1,182 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Signer is a single signing key identified by its kid.
+type Signer interface {
+	KeyID() string
+	Method() jwt.SigningMethod
+	SigningKey() any
+	VerificationKey() any
+	// PublicJWK returns the public half of the key. Symmetric keys have no
+	// public half and report false.
+	PublicJWK() (JWK, bool)
+}
+
+// JWK is the JSON Web Key representation of a public verification key.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type hmacSigner struct {
+	kid    string
+	secret []byte
+}
+
+func NewHMACSigner(kid string, secret []byte) (Signer, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("hmac secret must be at least 32 bytes")
+	}
+
+	return &hmacSigner{kid: kid, secret: secret}, nil
+}
+
+func (s *hmacSigner) KeyID() string             { return s.kid }
+func (s *hmacSigner) Method() jwt.SigningMethod { return jwt.SigningMethodHS256 }
+func (s *hmacSigner) SigningKey() any           { return s.secret }
+func (s *hmacSigner) VerificationKey() any      { return s.secret }
+func (s *hmacSigner) PublicJWK() (JWK, bool)    { return JWK{}, false }
+
+type rsaSigner struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func NewRSASigner(kid string, key *rsa.PrivateKey) (Signer, error) {
+	if key.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("rsa key must be at least 2048 bits")
+	}
+
+	return &rsaSigner{kid: kid, key: key}, nil
+}
+
+func (s *rsaSigner) KeyID() string             { return s.kid }
+func (s *rsaSigner) Method() jwt.SigningMethod { return jwt.SigningMethodRS256 }
+func (s *rsaSigner) SigningKey() any           { return s.key }
+func (s *rsaSigner) VerificationKey() any      { return &s.key.PublicKey }
+
+func (s *rsaSigner) PublicJWK() (JWK, bool) {
+	return JWK{
+		KeyType:   "RSA",
+		KeyID:     s.kid,
+		Algorithm: AlgorithmRS256,
+		Use:       "sig",
+		N:         base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+	}, true
+}
+
+type edDSASigner struct {
+	kid string
+	key ed25519.PrivateKey
+}
+
+func NewEdDSASigner(kid string, key ed25519.PrivateKey) Signer {
+	return &edDSASigner{kid: kid, key: key}
+}
+
+func (s *edDSASigner) KeyID() string             { return s.kid }
+func (s *edDSASigner) Method() jwt.SigningMethod { return jwt.SigningMethodEdDSA }
+func (s *edDSASigner) SigningKey() any           { return s.key }
+func (s *edDSASigner) VerificationKey() any      { return s.key.Public() }
+
+func (s *edDSASigner) PublicJWK() (JWK, bool) {
+	return JWK{
+		KeyType:   "OKP",
+		KeyID:     s.kid,
+		Algorithm: AlgorithmEdDSA,
+		Use:       "sig",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey)),
+	}, true
+}
+
+// ParsePrivateKey builds a signer for algorithm from a PEM encoded PKCS#8
+// (or PKCS#1 for RSA) private key.
+func ParsePrivateKey(algorithm string, kid string, data []byte) (Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s is not PEM encoded", kid)
+	}
+
+	var key any
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if rsaErr != nil {
+			return nil, fmt.Errorf("cannot parse key %s: %v", kid, err)
+		}
+		key = rsaKey
+	}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("key %s is not an RSA key", kid)
+		}
+		return NewRSASigner(kid, rsaKey)
+	case AlgorithmEdDSA:
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("key %s is not an Ed25519 key", kid)
+		}
+		return NewEdDSASigner(kid, edKey), nil
+	default:
+		return nil, fmt.Errorf("algorithm %s does not use private keys", algorithm)
+	}
+}
+
+// GenerateSigner creates a fresh random key for algorithm.
+func GenerateSigner(algorithm string, kid string) (Signer, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate hmac secret: %v", err)
+		}
+		return NewHMACSigner(kid, secret)
+	case AlgorithmRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate rsa key: %v", err)
+		}
+		return NewRSASigner(kid, key)
+	case AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ed25519 key: %v", err)
+		}
+		return NewEdDSASigner(kid, key), nil
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", algorithm)
+	}
+}
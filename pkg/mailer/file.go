@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/federicodosantos/socialize/pkg/token"
+)
+
+// FileMailer writes every message as an .eml file into a directory, which is
+// handy to inspect mails locally without an SMTP server.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("file mailer requires a drop directory")
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("cannot create mail drop directory: %v", err)
+	}
+
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	id, err := token.NewID()
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), id)
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), formatMessage(m.from, msg), 0o640); err != nil {
+		return fmt.Errorf("failed to write mail: %v", err)
+	}
+
+	return nil
+}
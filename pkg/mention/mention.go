@@ -0,0 +1,38 @@
+package mention
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// MaxMentions is how many users a single text can notify, the mentions after
+// it are left as plain text.
+const MaxMentions = 10
+
+// pattern matches the mention markup clients insert when a user is picked
+// from the autocomplete, "@[Name](id)". Names are not unique so the id is
+// what identifies the user.
+var pattern = regexp.MustCompile(`@\[[^\]\n]+\]\((\d+)\)`)
+
+// UserIDs returns the ids of the users mentioned in text, each once and in
+// the order they first appear.
+func UserIDs(text string) []int64 {
+	var ids []int64
+
+	seen := make(map[int64]bool)
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		id, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		ids = append(ids, id)
+
+		if len(ids) == MaxMentions {
+			break
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,156 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+var supportedAlgorithms = []string{"RS256", "ES256", "EdDSA"}
+
+// minRefreshInterval limits how often an unknown kid makes us refetch the
+// provider keys, so forged tokens cannot be used to hammer the provider.
+const minRefreshInterval = time.Minute
+
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+type publicKey struct {
+	algorithm string
+	key       any
+}
+
+// keySet caches the provider signing keys and refreshes them when a token
+// names a kid it does not know, which is how providers roll their keys.
+type keySet struct {
+	uri   string
+	fetch func(ctx context.Context, url string, v any) error
+
+	mu        sync.Mutex
+	keys      map[string]publicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(uri string, fetch func(ctx context.Context, url string, v any) error) *keySet {
+	return &keySet{uri: uri, fetch: fetch}
+}
+
+func (ks *keySet) lookup(ctx context.Context, kid string, algorithm string) (any, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, ok := ks.keys[kid]
+	if !ok && time.Since(ks.fetchedAt) >= minRefreshInterval {
+		if err := ks.refresh(ctx); err != nil {
+			return nil, err
+		}
+		key, ok = ks.keys[kid]
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if key.algorithm != algorithm {
+		return nil, fmt.Errorf("unexpected signing method %s", algorithm)
+	}
+
+	return key.key, nil
+}
+
+func (ks *keySet) refresh(ctx context.Context) error {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := ks.fetch(ctx, ks.uri, &set); err != nil {
+		return fmt.Errorf("cannot fetch provider keys: %v", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.parse()
+		if err != nil {
+			// keys of a type we do not support are skipped rather than
+			// failing the whole set
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+
+	return nil
+}
+
+func (k jwk) parse() (publicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return publicKey{}, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return publicKey{}, err
+		}
+
+		return publicKey{algorithm: "RS256", key: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return publicKey{}, fmt.Errorf("unsupported curve %s", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return publicKey{}, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return publicKey{}, err
+		}
+
+		return publicKey{algorithm: "ES256", key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return publicKey{}, fmt.Errorf("unsupported curve %s", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, fmt.Errorf("invalid ed25519 key")
+		}
+
+		return publicKey{algorithm: "EdDSA", key: ed25519.PublicKey(x)}, nil
+	default:
+		return publicKey{}, fmt.Errorf("unsupported key type %s", k.KeyType)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
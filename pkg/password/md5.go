@@ -0,0 +1,38 @@
+package password
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+)
+
+// LegacyMD5 verifies the unsalted hex MD5 digests that were stored before
+// passwords were hashed with a proper password hashing function. It never
+// produces new hashes.
+type LegacyMD5 struct{}
+
+func (LegacyMD5) Hash(password string) (string, error) {
+	return "", errors.New("md5 must not be used to hash new passwords")
+}
+
+func (LegacyMD5) Verify(password string, encoded string) (bool, error) {
+	digest := md5.Sum([]byte(password))
+	candidate := hex.EncodeToString(digest[:])
+
+	return subtle.ConstantTimeCompare([]byte(candidate), []byte(encoded)) == 1, nil
+}
+
+func (LegacyMD5) Supports(encoded string) bool {
+	if len(encoded) != hex.EncodedLen(md5.Size) {
+		return false
+	}
+
+	_, err := hex.DecodeString(encoded)
+
+	return err == nil
+}
+
+func (LegacyMD5) NeedsRehash(encoded string) bool {
+	return true
+}
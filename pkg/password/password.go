@@ -0,0 +1,123 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrUnsupportedHash = errors.New("unsupported password hash format")
+	ErrInvalidHash     = errors.New("invalid password hash")
+)
+
+// Hasher hashes passwords with one algorithm and verifies hashes it produced.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(password string, encoded string) (bool, error)
+	// Supports reports whether encoded was produced by this algorithm.
+	Supports(encoded string) bool
+	// NeedsRehash reports whether encoded was produced with weaker parameters
+	// than the ones the hasher is currently configured with.
+	NeedsRehash(encoded string) bool
+}
+
+type Config struct {
+	Algorithm         string
+	Argon2Memory      string
+	Argon2Iterations  string
+	Argon2Parallelism string
+	BcryptCost        string
+}
+
+// NewHasher builds the hasher used across the application. New hashes are
+// produced with the configured algorithm while hashes from the other
+// algorithms, including legacy MD5 digests, can still be verified so they
+// can be upgraded on the next successful login.
+func NewHasher(cfg Config) (Hasher, error) {
+	argon2id, err := newArgon2idFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	bcrypt, err := newBcryptFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Algorithm {
+	case "", AlgorithmArgon2id:
+		return NewMigratingHasher(argon2id, bcrypt, LegacyMD5{}), nil
+	case AlgorithmBcrypt:
+		return NewMigratingHasher(bcrypt, argon2id, LegacyMD5{}), nil
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", cfg.Algorithm)
+	}
+}
+
+// MigratingHasher hashes with a preferred algorithm and verifies with any of
+// the known ones. Hashes that are not in the preferred format are reported as
+// needing a rehash.
+type MigratingHasher struct {
+	preferred Hasher
+	fallbacks []Hasher
+}
+
+func NewMigratingHasher(preferred Hasher, fallbacks ...Hasher) *MigratingHasher {
+	return &MigratingHasher{
+		preferred: preferred,
+		fallbacks: fallbacks,
+	}
+}
+
+func (m *MigratingHasher) Hash(password string) (string, error) {
+	return m.preferred.Hash(password)
+}
+
+func (m *MigratingHasher) Verify(password string, encoded string) (bool, error) {
+	hasher := m.find(encoded)
+	if hasher == nil {
+		return false, ErrUnsupportedHash
+	}
+
+	return hasher.Verify(password, encoded)
+}
+
+func (m *MigratingHasher) Supports(encoded string) bool {
+	return m.find(encoded) != nil
+}
+
+func (m *MigratingHasher) NeedsRehash(encoded string) bool {
+	if !m.preferred.Supports(encoded) {
+		return true
+	}
+
+	return m.preferred.NeedsRehash(encoded)
+}
+
+func (m *MigratingHasher) find(encoded string) Hasher {
+	if m.preferred.Supports(encoded) {
+		return m.preferred
+	}
+
+	for _, hasher := range m.fallbacks {
+		if hasher.Supports(encoded) {
+			return hasher
+		}
+	}
+
+	return nil
+}
+
+func parseUint(value string, fallback uint64, bitSize int) (uint64, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	return strconv.ParseUint(value, 10, bitSize)
+}
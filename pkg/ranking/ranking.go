@@ -0,0 +1,125 @@
+package ranking
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	AlgorithmHot        = "hot"
+	AlgorithmWilson     = "wilson"
+	AlgorithmHackerNews = "hn"
+)
+
+// Stats are the inputs every scorer works from.
+type Stats struct {
+	UpVote       int64
+	DownVote     int64
+	CommentCount int64
+	CreatedAt    time.Time
+}
+
+// Scorer turns the stats of a post into the score posts are sorted by, the
+// higher the better.
+type Scorer interface {
+	Score(stats Stats, now time.Time) float64
+	// TimeDependent reports whether the score of a post changes as time
+	// passes, such scores have to be recomputed periodically.
+	TimeDependent() bool
+}
+
+// SQLScorer is a time dependent Scorer the database can compute as of a given
+// time. Listings sorted by such a score rank every page as of the time of
+// their first page, the stored scores move as they are recomputed and would
+// shift posts across pages.
+type SQLScorer interface {
+	Scorer
+	// ScoreSQL returns the score as an SQL expression of the net votes and
+	// creation time columns, as of the time bound to its only placeholder.
+	ScoreSQL(netVote string, createdAt string) string
+}
+
+// NewScorer returns the scorer of the named algorithm.
+func NewScorer(algorithm string) (Scorer, error) {
+	switch algorithm {
+	case "", AlgorithmHot:
+		return RedditHot{}, nil
+	case AlgorithmWilson:
+		return Wilson{Z: 1.96}, nil
+	case AlgorithmHackerNews:
+		return HackerNews{Gravity: 1.8}, nil
+	default:
+		return nil, fmt.Errorf("unknown ranking algorithm %q", algorithm)
+	}
+}
+
+// redditEpoch is the epoch of the original Reddit hot formula, kept so scores
+// stay small.
+const redditEpoch = 1134028003
+
+// RedditHot ranks by the order of magnitude of the net votes plus a term
+// growing by one every 12.5 hours, so a post needs ten times the net votes to
+// outrank one posted that much later. It only depends on the post itself.
+type RedditHot struct{}
+
+func (RedditHot) Score(stats Stats, _ time.Time) float64 {
+	net := float64(stats.UpVote - stats.DownVote)
+
+	order := math.Log10(math.Max(math.Abs(net), 1))
+
+	sign := 0.0
+	switch {
+	case net > 0:
+		sign = 1
+	case net < 0:
+		sign = -1
+	}
+
+	return sign*order + float64(stats.CreatedAt.Unix()-redditEpoch)/45000
+}
+
+func (RedditHot) TimeDependent() bool { return false }
+
+// Wilson ranks by the lower bound of the Wilson score interval of the share
+// of up votes, so a post with few votes is not ranked above one with many
+// votes and a slightly lower ratio. Z is the quantile of the confidence
+// level, 1.96 for 95%.
+type Wilson struct {
+	Z float64
+}
+
+func (w Wilson) Score(stats Stats, _ time.Time) float64 {
+	n := float64(stats.UpVote + stats.DownVote)
+	if n == 0 {
+		return 0
+	}
+
+	p := float64(stats.UpVote) / n
+	z2 := w.Z * w.Z
+
+	return (p + z2/(2*n) - w.Z*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
+
+func (Wilson) TimeDependent() bool { return false }
+
+// HackerNews divides the net votes by a power of the age in hours, so every
+// post sinks as it gets older whatever its votes.
+type HackerNews struct {
+	Gravity float64
+}
+
+func (h HackerNews) Score(stats Stats, now time.Time) float64 {
+	age := math.Max(now.Sub(stats.CreatedAt).Hours(), 0)
+	points := float64(stats.UpVote - stats.DownVote)
+
+	return points / math.Pow(age+2, h.Gravity)
+}
+
+func (HackerNews) TimeDependent() bool { return true }
+
+func (h HackerNews) ScoreSQL(netVote string, createdAt string) string {
+	return fmt.Sprintf("(%s / POW(GREATEST(TIMESTAMPDIFF(SECOND, %s, ?) / 3600, 0) + 2, %s))",
+		netVote, createdAt, strconv.FormatFloat(h.Gravity, 'f', -1, 64))
+}
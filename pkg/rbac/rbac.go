@@ -0,0 +1,69 @@
+package rbac
+
+import "fmt"
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+type Permission string
+
+const (
+	// PermRemoveAnyPost allows deleting posts written by someone else.
+	PermRemoveAnyPost Permission = "post:remove_any"
+	// PermRemoveAnyComment allows deleting comments written by someone else.
+	PermRemoveAnyComment Permission = "comment:remove_any"
+	// PermManageUsers allows listing users, changing their role and banning
+	// them.
+	PermManageUsers Permission = "user:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser:      {},
+	RoleModerator: {PermRemoveAnyPost, PermRemoveAnyComment},
+	RoleAdmin:     {PermRemoveAnyPost, PermRemoveAnyComment, PermManageUsers},
+}
+
+// ParseRole returns the role named s. Unknown roles are refused instead of
+// being downgraded so a typo cannot silently change what a user can do.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+
+	return role, nil
+}
+
+// Can reports whether the role grants every one of perms.
+func (r Role) Can(perms ...Permission) bool {
+	granted, ok := rolePermissions[r]
+	if !ok {
+		return false
+	}
+
+	for _, perm := range perms {
+		found := false
+		for _, g := range granted {
+			if g == perm {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Permissions returns the permissions granted to the role.
+func (r Role) Permissions() []Permission {
+	return append([]Permission(nil), rolePermissions[r]...)
+}
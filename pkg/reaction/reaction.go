@@ -0,0 +1,70 @@
+package reaction
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default is the reaction set used when none is configured.
+var Default = []string{"👍", "❤️", "😂", "😮", "😢", "😡"}
+
+// maxLength matches the width of the reaction column.
+const maxLength = 32
+
+// Set is the list of reactions users can leave on posts and comments, in the
+// order clients should show them.
+type Set struct {
+	reactions []string
+	allowed   map[string]bool
+}
+
+// NewSet builds a set from reactions, refusing empty, too long or duplicated
+// entries.
+func NewSet(reactions []string) (*Set, error) {
+	if len(reactions) == 0 {
+		return nil, fmt.Errorf("reaction set is empty")
+	}
+
+	set := &Set{allowed: make(map[string]bool, len(reactions))}
+
+	for _, r := range reactions {
+		if r == "" || utf8.RuneCountInString(r) > maxLength {
+			return nil, fmt.Errorf("invalid reaction %q", r)
+		}
+
+		if set.allowed[r] {
+			return nil, fmt.Errorf("duplicated reaction %q", r)
+		}
+
+		set.allowed[r] = true
+		set.reactions = append(set.reactions, r)
+	}
+
+	return set, nil
+}
+
+// Parse builds a set from a comma separated list, an empty list gives the
+// Default set.
+func Parse(list string) (*Set, error) {
+	if strings.TrimSpace(list) == "" {
+		return NewSet(Default)
+	}
+
+	var reactions []string
+	for _, r := range strings.Split(list, ",") {
+		reactions = append(reactions, strings.TrimSpace(r))
+	}
+
+	return NewSet(reactions)
+}
+
+// Contains reports whether r belongs to the set.
+func (s *Set) Contains(r string) bool {
+	return s.allowed[r]
+}
+
+// List returns the reactions of the set.
+func (s *Set) List() []string {
+	return append([]string(nil), s.reactions...)
+}
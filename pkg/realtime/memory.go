@@ -0,0 +1,186 @@
+package realtime
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryHub is a Hub living in the process, it only reaches the clients
+// connected to this replica.
+type MemoryHub struct {
+	mu sync.Mutex
+	// epoch tells the events of this hub apart from the ones of a hub that
+	// ran before a restart, which cannot be replayed
+	epoch string
+	seq   uint64
+
+	// history is a ring of the most recent events, next is where the next
+	// event goes and size how many it holds
+	history []historyEntry
+	next    int
+	size    int
+
+	bufferSize int
+	channels   map[string]map[*Subscription]bool
+	subs       map[*Subscription][]string
+	closed     bool
+}
+
+type historyEntry struct {
+	seq   uint64
+	event Event
+}
+
+func NewMemoryHub(historySize int, bufferSize int) *MemoryHub {
+	return &MemoryHub{
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		history:    make([]historyEntry, historySize),
+		bufferSize: bufferSize,
+		channels:   make(map[string]map[*Subscription]bool),
+		subs:       make(map[*Subscription][]string),
+	}
+}
+
+func (h *MemoryHub) eventID(seq uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// Publish implements Hub. A subscriber whose buffer is full is dropped
+// rather than slowing down the publisher and the other subscribers.
+func (h *MemoryHub) Publish(ctx context.Context, event Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrHubClosed
+	}
+
+	h.seq++
+	event.ID = h.eventID(h.seq)
+
+	h.history[h.next] = historyEntry{seq: h.seq, event: event}
+	h.next = (h.next + 1) % len(h.history)
+	h.size = min(h.size+1, len(h.history))
+
+	for sub := range h.channels[event.Channel] {
+		if !sub.deliver(event) {
+			h.removeLocked(sub)
+			sub.finish(ErrSlowConsumer)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe implements Hub. The replayed events are queued before the
+// subscription is registered, under the same lock, so no event falls
+// between the replay and the live ones.
+func (h *MemoryHub) Subscribe(ctx context.Context, channels []string, lastEventID string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	var sub *Subscription
+	sub = newSubscription(h.bufferSize, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.removeLocked(sub)
+	})
+
+	if lastEventID != "" {
+		replay, ok := h.replayLocked(channels, lastEventID)
+		if !ok || len(replay) > h.bufferSize {
+			// the reset carries the current id so the next resume starts
+			// from here
+			replay = []Event{{ID: h.eventID(h.seq), Type: EventReset}}
+		}
+
+		for _, event := range replay {
+			sub.deliver(event)
+		}
+	}
+
+	for _, channel := range channels {
+		if h.channels[channel] == nil {
+			h.channels[channel] = make(map[*Subscription]bool)
+		}
+
+		h.channels[channel][sub] = true
+	}
+
+	h.subs[sub] = channels
+
+	return sub, nil
+}
+
+// replayLocked returns the events of channels published after lastEventID.
+// It reports false when some of them are no longer in the history or the id
+// comes from another hub.
+func (h *MemoryHub) replayLocked(channels []string, lastEventID string) ([]Event, bool) {
+	epoch, seqStr, found := strings.Cut(lastEventID, "-")
+	if !found || epoch != h.epoch {
+		return nil, false
+	}
+
+	lastSeq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil || lastSeq > h.seq {
+		return nil, false
+	}
+
+	// the events right after lastSeq must still be in the history
+	oldest := h.seq - uint64(h.size) + 1
+	if lastSeq+1 < oldest {
+		return nil, false
+	}
+
+	wanted := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		wanted[channel] = true
+	}
+
+	var replay []Event
+
+	start := (h.next - h.size + len(h.history)) % len(h.history)
+	for i := 0; i < h.size; i++ {
+		entry := h.history[(start+i)%len(h.history)]
+		if entry.seq > lastSeq && wanted[entry.event.Channel] {
+			replay = append(replay, entry.event)
+		}
+	}
+
+	return replay, true
+}
+
+func (h *MemoryHub) removeLocked(sub *Subscription) {
+	for _, channel := range h.subs[sub] {
+		delete(h.channels[channel], sub)
+
+		if len(h.channels[channel]) == 0 {
+			delete(h.channels, channel)
+		}
+	}
+
+	delete(h.subs, sub)
+}
+
+// Close implements Hub. It ends every subscription with ErrHubClosed.
+func (h *MemoryHub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+
+	for sub := range h.subs {
+		h.removeLocked(sub)
+		sub.finish(ErrHubClosed)
+	}
+
+	return nil
+}
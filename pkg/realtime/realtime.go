@@ -0,0 +1,165 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+var (
+	// ErrSlowConsumer closes a subscription that did not keep up with its
+	// events, the client reconnects with the id of the last event it got and
+	// the missed events are replayed.
+	ErrSlowConsumer = errors.New("subscription dropped, events were not read fast enough")
+	// ErrHubClosed closes the subscriptions of a hub that is shutting down.
+	ErrHubClosed = errors.New("realtime hub closed")
+)
+
+// Event types. EventReset tells a resumed subscription that some of its
+// events could not be replayed, the client should reload what it shows.
+const (
+	EventComment      = "comment"
+	EventVote         = "vote"
+	EventNotification = "notification"
+	EventReset        = "reset"
+)
+
+// PostChannel is the channel of the events of a post.
+func PostChannel(postID int64) string {
+	return "post:" + strconv.FormatInt(postID, 10)
+}
+
+// UserChannel is the channel of the events of a user, only that user can
+// subscribe to it.
+func UserChannel(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
+
+type Event struct {
+	// ID is set by the hub when the event is published, it is opaque to the
+	// clients which send it back to resume a subscription.
+	ID      string `json:"id"`
+	Channel string `json:"channel"`
+	Type    string `json:"type"`
+	// ActorID is the user behind the event, gateways use it to leave out the
+	// events of users the subscriber blocked or was blocked by.
+	ActorID int64           `json:"actor_id,omitempty"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Hub delivers published events to the subscribers of their channel. The
+// hub keeps a window of recent events so a client that reconnects with the
+// id of the last event it got does not miss the events published meanwhile.
+//
+// A hub shared by several replicas, such as one backed by Redis pub/sub and
+// a stream for the replay window, only has to implement this interface.
+type Hub interface {
+	Publish(ctx context.Context, event Event) error
+	// Subscribe starts a subscription to channels. When lastEventID is set
+	// the events published after it are delivered first, or a single
+	// EventReset when they are no longer all known.
+	Subscribe(ctx context.Context, channels []string, lastEventID string) (*Subscription, error)
+	Close() error
+}
+
+type Config struct {
+	Driver string
+	// HistorySize is how many recent events are kept for replay
+	HistorySize int
+	// BufferSize is how many events a subscriber can lag behind before it
+	// is dropped
+	BufferSize int
+}
+
+const (
+	DefaultHistorySize = 1000
+	DefaultBufferSize  = 64
+)
+
+// NewHub returns the hub selected by cfg.Driver, only memory for now.
+func NewHub(cfg Config) (Hub, error) {
+	if cfg.HistorySize < 1 {
+		cfg.HistorySize = DefaultHistorySize
+	}
+
+	if cfg.BufferSize < 1 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+
+	switch cfg.Driver {
+	case "", "memory":
+		return NewMemoryHub(cfg.HistorySize, cfg.BufferSize), nil
+	default:
+		return nil, fmt.Errorf("unknown realtime driver %q", cfg.Driver)
+	}
+}
+
+// Subscription receives the events of the channels it was opened for until
+// Done is closed, Err then tells why.
+type Subscription struct {
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+	err    error
+	// cancel removes the subscription from its hub, it may be called more
+	// than once
+	cancel func()
+}
+
+func newSubscription(bufferSize int, cancel func()) *Subscription {
+	return &Subscription{
+		events: make(chan Event, bufferSize),
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+}
+
+// Events returns the events of the subscription. The channel is never
+// closed, readers also wait on Done.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the subscription ended, it is nil while it is open and
+// after Close.
+func (s *Subscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.cancel()
+	s.finish(nil)
+}
+
+// deliver queues the event without blocking, it reports false when the
+// buffer is full.
+func (s *Subscription) deliver(event Event) bool {
+	select {
+	case s.events <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// finish closes Done with err, the hub calls it once it removed the
+// subscription.
+func (s *Subscription) finish(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
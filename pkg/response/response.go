@@ -6,9 +6,10 @@ import (
 )
 
 type HttpResponse struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	Data    any    `json:"obj,omitempty"`
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	Data       any    `json:"obj,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func SuccessResponse(w http.ResponseWriter, status int, message string, data any) {
@@ -24,6 +25,22 @@ func SuccessResponse(w http.ResponseWriter, status int, message string, data any
 	json.NewEncoder(w).Encode(response)
 }
 
+// PaginatedResponse writes one page of a listing. nextCursor is empty on the
+// last page.
+func PaginatedResponse(w http.ResponseWriter, status int, message string, data any, nextCursor string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := HttpResponse{
+		Status:     status,
+		Message:    message,
+		Data:       data,
+		NextCursor: nextCursor,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
 func FailedResponse(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,86 @@
+package snippet
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	OpenTag  = "<mark>"
+	CloseTag = "</mark>"
+)
+
+// Terms splits a search text into the words to highlight.
+func Terms(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Highlight returns an excerpt of about length bytes of text around the first
+// of terms it contains, with every term wrapped in OpenTag and CloseTag. The
+// rest of the excerpt is HTML escaped so it can be rendered as is.
+func Highlight(text string, terms []string, length int) string {
+	if len(terms) == 0 {
+		return html.EscapeString(excerpt(text, 0, length))
+	}
+
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, regexp.QuoteMeta(term))
+	}
+
+	pattern := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+
+	start := 0
+	if loc := pattern.FindStringIndex(text); loc != nil {
+		// keep a bit of context before the first match
+		start = max(loc[0]-length/4, 0)
+	}
+
+	window := excerpt(text, start, length)
+
+	var sb strings.Builder
+
+	last := 0
+	for _, loc := range pattern.FindAllStringIndex(window, -1) {
+		sb.WriteString(html.EscapeString(window[last:loc[0]]))
+		sb.WriteString(OpenTag)
+		sb.WriteString(html.EscapeString(window[loc[0]:loc[1]]))
+		sb.WriteString(CloseTag)
+		last = loc[1]
+	}
+	sb.WriteString(html.EscapeString(window[last:]))
+
+	return sb.String()
+}
+
+// excerpt cuts about length bytes out of text from start, moving both ends to
+// rune boundaries and marking cut ends with an ellipsis.
+func excerpt(text string, start, length int) string {
+	if start >= len(text) {
+		return ""
+	}
+
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+
+	end := min(start+length, len(text))
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	result := text[start:end]
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(text) {
+		result += "…"
+	}
+
+	return result
+}
@@ -0,0 +1,88 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Generate returns a random opaque token to hand out to the client together
+// with the hash that should be persisted in its place.
+func Generate() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %v", err)
+	}
+
+	plain := base64.RawURLEncoding.EncodeToString(b)
+
+	return plain, Hash(plain), nil
+}
+
+// Hash returns the hex encoded SHA-256 of a token. Tokens are high entropy
+// random values so a fast hash is enough to keep them useless if leaked from
+// the database.
+func Hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// NewID returns a random identifier suitable for a jti claim or a token
+// family.
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %v", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// Signer appends an HMAC signature to tokens so forged or mistyped tokens are
+// rejected before they are looked up.
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret string) (*Signer, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("token signing secret must be at least 32 bytes")
+	}
+
+	return &Signer{secret: []byte(secret)}, nil
+}
+
+// Sign returns plain with a signature bound to purpose, so a token issued for
+// one flow cannot be replayed against another.
+func (s *Signer) Sign(purpose string, plain string) string {
+	return plain + "." + s.signature(purpose, plain)
+}
+
+// Verify returns the plain token if signed carries a valid signature for
+// purpose.
+func (s *Signer) Verify(purpose string, signed string) (string, bool) {
+	plain, signature, found := strings.Cut(signed, ".")
+	if !found {
+		return "", false
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.signature(purpose, plain))) {
+		return "", false
+	}
+
+	return plain, true
+}
+
+func (s *Signer) signature(purpose string, plain string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(plain))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -4,11 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/federicodosantos/socialize/internal/model"
 	customContext "github.com/federicodosantos/socialize/pkg/context"
+	"github.com/federicodosantos/socialize/pkg/cursor"
 	customError "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/rbac"
 	response "github.com/federicodosantos/socialize/pkg/response"
 	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
@@ -42,6 +46,39 @@ func GetUserIdFromContext(w http.ResponseWriter, r *http.Request) (int64, error)
 	return intUserID, nil
 }
 
+// GetActorFromContext returns the authenticated user and their role, writing
+// the failure response when they are missing.
+func GetActorFromContext(w http.ResponseWriter, r *http.Request) (model.Actor, error) {
+	userID, err := GetUserIdFromContext(w, r)
+	if err != nil {
+		return model.Actor{}, err
+	}
+
+	role, ok := r.Context().Value(customContext.UserRoleKey).(rbac.Role)
+	if !ok {
+		response.FailedResponse(w, http.StatusUnauthorized, "role not found in context")
+		return model.Actor{}, errors.New("role not found in context")
+	}
+
+	return model.Actor{UserID: userID, Role: role}, nil
+}
+
+// GetTokenFromContext returns the jti and expiry of the access token that
+// authenticated the request.
+func GetTokenFromContext(r *http.Request) (string, time.Time, error) {
+	jti, ok := r.Context().Value(customContext.TokenIDKey).(string)
+	if !ok || jti == "" {
+		return "", time.Time{}, errors.New("token id not found in context")
+	}
+
+	expiresAt, ok := r.Context().Value(customContext.TokenExpiresAtKey).(time.Time)
+	if !ok {
+		return "", time.Time{}, errors.New("token expiry not found in context")
+	}
+
+	return jti, expiresAt, nil
+}
+
 func HealthCheck(router *chi.Mux, db *sqlx.DB) {
 	type HealthStatus struct {
 		Status   string `json:"status"`
@@ -68,11 +105,368 @@ func HealthCheck(router *chi.Mux, db *sqlx.DB) {
 	})
 }
 
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+
+	// DefaultCommentDepth is how many levels of a comment thread are loaded
+	// when the depth query parameter is missing.
+	DefaultCommentDepth = 3
+	MaxCommentDepth     = 10
+	// DefaultReplyLimit is how many replies are loaded below each comment of
+	// a thread.
+	DefaultReplyLimit = 5
+	MaxReplyLimit     = 50
+	// MaxRealtimePosts is how many posts a realtime connection can follow.
+	MaxRealtimePosts = 50
+)
+
+// postWindows maps the window query parameter of the top sort to its length,
+// a zero length means all time.
+var postWindows = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+	"all":   0,
+}
+
+// ParseLimit reads the limit query parameter, falling back to
+// DefaultPageLimit when it is missing.
+func ParseLimit(r *http.Request) (int, error) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return DefaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > MaxPageLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", MaxPageLimit)
+	}
+
+	return limit, nil
+}
+
 func ParsePostFilter(r *http.Request, filter *model.PostFilter) error {
-	if keyword := r.URL.Query().Get("keyword"); keyword != "" {
+	query := r.URL.Query()
+
+	if keyword := query.Get("keyword"); keyword != "" {
 		filter.Keyword = keyword
 	}
 
+	filter.Sort = model.PostSortNew
+	if sort := query.Get("sort"); sort != "" {
+		switch sort {
+		case model.PostSortNew, model.PostSortTop, model.PostSortHot:
+			filter.Sort = sort
+		default:
+			return fmt.Errorf("unknown sort %q", sort)
+		}
+	}
+
+	if window := query.Get("window"); window != "" {
+		length, ok := postWindows[window]
+		if !ok {
+			return fmt.Errorf("unknown window %q", window)
+		}
+
+		// windows only make sense when ranking by votes
+		if filter.Sort == model.PostSortTop {
+			filter.Window = length
+		}
+	}
+
+	limit, err := ParseLimit(r)
+	if err != nil {
+		return err
+	}
+	filter.Limit = limit
+
+	if token := query.Get("cursor"); token != "" {
+		var position model.PostCursor
+		if err := cursor.Decode(token, &position); err != nil {
+			return err
+		}
+
+		// a cursor is a position in one ordering only
+		if position.Sort != filter.Sort {
+			return cursor.ErrInvalidCursor
+		}
+
+		filter.Cursor = &position
+	}
+
+	return nil
+}
+
+// ParseCommentFilter reads the limit and cursor of a page of comments.
+func ParseCommentFilter(r *http.Request, filter *model.CommentFilter) error {
+	limit, err := ParseLimit(r)
+	if err != nil {
+		return err
+	}
+	filter.Limit = limit
+
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		var position model.CommentCursor
+		if err := cursor.Decode(token, &position); err != nil {
+			return err
+		}
+
+		filter.Cursor = &position
+	}
+
+	return nil
+}
+
+// ParseFollowFilter reads the limit and cursor of a page of followers or
+// followings.
+func ParseFollowFilter(r *http.Request, filter *model.FollowFilter) error {
+	limit, err := ParseLimit(r)
+	if err != nil {
+		return err
+	}
+	filter.Limit = limit
+
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		var position model.FollowCursor
+		if err := cursor.Decode(token, &position); err != nil {
+			return err
+		}
+
+		filter.Cursor = &position
+	}
+
+	return nil
+}
+
+// ParseRestrictionFilter reads the limit and cursor of a page of blocked or
+// muted users.
+func ParseRestrictionFilter(r *http.Request, filter *model.RestrictionFilter) error {
+	limit, err := ParseLimit(r)
+	if err != nil {
+		return err
+	}
+	filter.Limit = limit
+
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		var position model.RestrictionCursor
+		if err := cursor.Decode(token, &position); err != nil {
+			return err
+		}
+
+		filter.Cursor = &position
+	}
+
+	return nil
+}
+
+// ParseBookmarkFilter reads the limit and cursor of a page of bookmarks and
+// the collection it is limited to.
+func ParseBookmarkFilter(r *http.Request, filter *model.BookmarkFilter) error {
+	limit, err := ParseLimit(r)
+	if err != nil {
+		return err
+	}
+	filter.Limit = limit
+
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		var position model.BookmarkCursor
+		if err := cursor.Decode(token, &position); err != nil {
+			return err
+		}
+
+		filter.Cursor = &position
+	}
+
+	filter.Collection = r.URL.Query().Get("collection")
+
+	return nil
+}
+
+// ParseNotificationFilter reads the limit and cursor of a page of
+// notifications and whether it only holds the unread ones.
+func ParseNotificationFilter(r *http.Request, filter *model.NotificationFilter) error {
+	limit, err := ParseLimit(r)
+	if err != nil {
+		return err
+	}
+	filter.Limit = limit
+
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		var position model.NotificationCursor
+		if err := cursor.Decode(token, &position); err != nil {
+			return err
+		}
+
+		filter.Cursor = &position
+	}
+
+	if unread := r.URL.Query().Get("unread"); unread != "" {
+		filter.UnreadOnly, err = strconv.ParseBool(unread)
+		if err != nil {
+			return fmt.Errorf("invalid unread %q", unread)
+		}
+	}
+
 	return nil
+}
+
+// ParseRealtimeFilter reads the posts a realtime connection follows and the
+// id of the last event the client got, from the Last-Event-ID header that
+// EventSource sends on reconnect or else from the last_event_id parameter.
+func ParseRealtimeFilter(r *http.Request, filter *model.RealtimeFilter) error {
+	query := r.URL.Query()
+
+	if posts := query.Get("posts"); posts != "" {
+		for _, part := range strings.Split(posts, ",") {
+			postID, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil || postID < 1 {
+				return fmt.Errorf("invalid post id %q", part)
+			}
+
+			filter.PostIDs = append(filter.PostIDs, postID)
+		}
+
+		if len(filter.PostIDs) > MaxRealtimePosts {
+			return fmt.Errorf("posts must list at most %d ids", MaxRealtimePosts)
+		}
+	}
+
+	filter.LastEventID = r.Header.Get("Last-Event-ID")
+	if filter.LastEventID == "" {
+		filter.LastEventID = query.Get("last_event_id")
+	}
+
+	return nil
+}
+
+// ParseCommentTreeFilter reads the limit and cursor of a page of a comment
+// thread, and the depth and replies parameters telling how much of the
+// replies below the page is loaded.
+func ParseCommentTreeFilter(r *http.Request, filter *model.CommentTreeFilter) error {
+	query := r.URL.Query()
+
+	limit, err := ParseLimit(r)
+	if err != nil {
+		return err
+	}
+	filter.Limit = limit
+
+	filter.Depth = DefaultCommentDepth
+	if depthStr := query.Get("depth"); depthStr != "" {
+		depth, err := strconv.Atoi(depthStr)
+		if err != nil || depth < 1 || depth > MaxCommentDepth {
+			return fmt.Errorf("depth must be between 1 and %d", MaxCommentDepth)
+		}
+		filter.Depth = depth
+	}
+
+	filter.ReplyLimit = DefaultReplyLimit
+	if repliesStr := query.Get("replies"); repliesStr != "" {
+		replies, err := strconv.Atoi(repliesStr)
+		if err != nil || replies < 1 || replies > MaxReplyLimit {
+			return fmt.Errorf("replies must be between 1 and %d", MaxReplyLimit)
+		}
+		filter.ReplyLimit = replies
+	}
+
+	if token := query.Get("cursor"); token != "" {
+		var position model.CommentCursor
+		if err := cursor.Decode(token, &position); err != nil {
+			return err
+		}
+
+		filter.Cursor = &position
+	}
+
+	return nil
+}
+
+// ParseSearchQuery reads the text, type and filters of a search. Dates are
+// accepted as RFC 3339 timestamps or as plain YYYY-MM-DD days.
+func ParseSearchQuery(r *http.Request, searchQuery *model.SearchQuery) error {
+	query := r.URL.Query()
+
+	searchQuery.Text = strings.TrimSpace(query.Get("q"))
+	if searchQuery.Text == "" {
+		return fmt.Errorf("%w: q is required", customError.ErrInvalidSearchQuery)
+	}
+
+	searchQuery.Type = model.SearchTypePost
+	if searchType := query.Get("type"); searchType != "" {
+		switch searchType {
+		case model.SearchTypePost, model.SearchTypeComment, model.SearchTypeUser:
+			searchQuery.Type = searchType
+		default:
+			return fmt.Errorf("%w: unknown type %q", customError.ErrInvalidSearchQuery, searchType)
+		}
+	}
+
+	if author := query.Get("author"); author != "" {
+		authorID, err := strconv.ParseInt(author, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid author", customError.ErrInvalidSearchQuery)
+		}
+		searchQuery.AuthorID = authorID
+	}
+
+	var err error
+
+	if searchQuery.From, err = parseSearchDate(query.Get("from")); err != nil {
+		return fmt.Errorf("%w: invalid from", customError.ErrInvalidSearchQuery)
+	}
+
+	// a bare to date includes the whole day
+	to := query.Get("to")
+	if searchQuery.To, err = parseSearchDate(to); err != nil {
+		return fmt.Errorf("%w: invalid to", customError.ErrInvalidSearchQuery)
+	}
+
+	if len(to) == len(time.DateOnly) {
+		searchQuery.To = searchQuery.To.AddDate(0, 0, 1)
+	}
+
+	if hasImage := query.Get("has_image"); hasImage != "" {
+		value, err := strconv.ParseBool(hasImage)
+		if err != nil {
+			return fmt.Errorf("%w: invalid has_image", customError.ErrInvalidSearchQuery)
+		}
+		searchQuery.HasImage = &value
+	}
+
+	if minScore := query.Get("min_score"); minScore != "" {
+		value, err := strconv.ParseInt(minScore, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid min_score", customError.ErrInvalidSearchQuery)
+		}
+		searchQuery.MinScore = &value
+	}
+
+	if searchQuery.Limit, err = ParseLimit(r); err != nil {
+		return err
+	}
+
+	if token := query.Get("cursor"); token != "" {
+		var position model.SearchCursor
+		if err := cursor.Decode(token, &position); err != nil || position.Offset < 0 {
+			return cursor.ErrInvalidCursor
+		}
+		searchQuery.Offset = position.Offset
+	}
+
+	return nil
+}
+
+// parseSearchDate returns the zero time for an empty value.
+func parseSearchDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		return day, nil
+	}
 
+	return time.Parse(time.RFC3339, value)
 }
@@ -0,0 +1,107 @@
+// Package websocket pushes events to browsers over websockets. It wraps
+// github.com/coder/websocket with the settings of this server: any origin
+// is accepted, like the CORS policy, because the connections authenticate
+// with a token rather than cookies, and clients may only send small
+// messages.
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Close codes.
+const (
+	CloseNormal        = websocket.StatusNormalClosure
+	CloseGoingAway     = websocket.StatusGoingAway
+	CloseTryAgainLater = websocket.StatusTryAgainLater
+)
+
+// DefaultReadLimit is the largest message a client may send.
+const DefaultReadLimit = 4096
+
+// maxCloseReason is how long the reason of a close may be, control frames
+// carry at most 125 bytes.
+const maxCloseReason = 123
+
+// Conn is an upgraded connection. Writes may be called from any goroutine.
+type Conn struct {
+	conn *websocket.Conn
+}
+
+// IsUpgrade reports whether r asks for a websocket.
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Upgrade answers the handshake of r and takes the connection over. Nothing
+// must have been written to w before. On failure an error response is
+// written.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	// the server deadlines do not apply to the upgraded connection
+	rc := http.NewResponseController(w)
+	for _, clear := range []func(time.Time) error{rc.SetReadDeadline, rc.SetWriteDeadline} {
+		if err := clear(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			http.Error(w, "websocket upgrade not supported", http.StatusInternalServerError)
+			return nil, err
+		}
+	}
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadLimit(DefaultReadLimit)
+
+	return &Conn{conn: conn}, nil
+}
+
+// CloseRead reads the connection in the background for the client that
+// sends nothing but pongs and the close. The returned context is done once
+// the connection is closed, a message from the client closes it.
+func (c *Conn) CloseRead(ctx context.Context) context.Context {
+	return c.conn.CloseRead(ctx)
+}
+
+// WriteText sends data as a text message.
+func (c *Conn) WriteText(ctx context.Context, data []byte) error {
+	return c.conn.Write(ctx, websocket.MessageText, data)
+}
+
+// Ping sends a ping and waits for the pong of the client. The connection
+// must be read meanwhile, see CloseRead.
+func (c *Conn) Ping(ctx context.Context) error {
+	return c.conn.Ping(ctx)
+}
+
+// Close goes through the closing handshake then closes the connection.
+func (c *Conn) Close(code websocket.StatusCode, reason string) error {
+	if len(reason) > maxCloseReason {
+		reason = reason[:maxCloseReason]
+	}
+
+	return c.conn.Close(code, reason)
+}
+
+// CloseNow closes the connection without the closing handshake.
+func (c *Conn) CloseNow() error {
+	return c.conn.CloseNow()
+}
@@ -0,0 +1,66 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockEndsFollows(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT IGNORE INTO blocks (blocker_id, blocked_id) VALUES (?, ?)`)).
+		WithArgs(int64(2), int64(5)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM follows`)).
+		WithArgs(int64(2), int64(5), int64(5), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	r := repository.NewBlockRepo(db)
+
+	assert.NoError(t, r.Block(context.Background(), 2, 5))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestIsBlockedBothWays(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)`)).
+		WithArgs(int64(5), int64(2), int64(2), int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"blocked"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`UNION`)).
+		WithArgs(int64(5), int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(9))
+
+	r := repository.NewBlockRepo(db)
+	ctx := context.Background()
+
+	blocked, err := r.IsBlocked(ctx, 5, 2)
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+
+	ids, err := r.GetBlockedUserIDs(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 9}, ids)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
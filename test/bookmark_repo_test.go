@@ -0,0 +1,70 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetBookmarkMovesCollection(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`ON DUPLICATE KEY UPDATE collection = VALUES(collection)`)).
+		WithArgs(int64(2), int64(7), "recipes").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`ON DUPLICATE KEY UPDATE collection = VALUES(collection)`)).
+		WithArgs(int64(2), int64(7), "travel").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+
+	r := repository.NewBookmarkRepo(db)
+	ctx := context.Background()
+
+	assert.NoError(t, r.SetBookmark(ctx, 2, 7, "recipes"))
+	assert.NoError(t, r.SetBookmark(ctx, 2, 7, "travel"))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetBookmarksKeysetCursor(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	savedAt := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE b.user_id = ? AND b.collection = ? AND b.id < ?`)).
+		WithArgs(int64(2), int64(2), int64(2), int64(2), "recipes", int64(30), int64(2), int64(2), 21).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bookmarked", "bookmark_id", "collection", "bookmarked_at"}).
+			AddRow(7, 4, 1, 29, "recipes", savedAt))
+
+	r := repository.NewBookmarkRepo(db)
+
+	posts, err := r.GetBookmarks(context.Background(), 2, model.BookmarkFilter{
+		Limit:      21,
+		Cursor:     &model.BookmarkCursor{ID: 30},
+		Collection: "recipes",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	assert.Equal(t, int64(7), posts[0].ID)
+	assert.Equal(t, int64(29), posts[0].BookmarkID)
+	assert.True(t, posts[0].Bookmarked)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
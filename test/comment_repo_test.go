@@ -0,0 +1,316 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	customerror "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentRepoInjectionPayloads(t *testing.T) {
+	for _, payload := range injectionPayloads {
+		t.Run(payload, func(t *testing.T) {
+			db, mock, err := setup()
+			if err != nil {
+				t.Fatalf("error to create sql mock and db due to %s", err.Error())
+			}
+			defer db.Close()
+
+			comment := &model.Comment{
+				PostID:    1,
+				UserID:    1,
+				Comment:   payload,
+				CreatedAt: time.Now(),
+			}
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO comments(user_id, post_id, parent_id, comment, created_at)
+				VALUES(?, ?, ?, ?, ?)`)).
+				WithArgs(comment.UserID, comment.PostID, nil, payload, comment.CreatedAt).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE posts SET comment_count`)).
+				WithArgs(comment.PostID, comment.PostID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			r := repository.NewCommentRepo(db)
+
+			assert.NoError(t, r.CreateComment(context.Background(), comment))
+			assert.Equal(t, int64(1), comment.ID)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetCommentByIDNotFound(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE c.id = ?`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	r := repository.NewCommentRepo(db)
+
+	comment, err := r.GetCommentByID(context.Background(), 7)
+	assert.ErrorIs(t, err, customerror.ErrNotFound)
+	assert.Nil(t, comment)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteCommentNotFound(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM comments WHERE id = ? FOR UPDATE`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id"}))
+	mock.ExpectRollback()
+
+	r := repository.NewCommentRepo(db)
+
+	assert.ErrorIs(t, r.DeleteComment(context.Background(), 7), customerror.ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetCommentsByUserIDKeysetCursor(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE c.user_id = ? AND c.deleted_at IS NULL AND c.id < ? ORDER BY c.id DESC LIMIT ?`)).
+		WithArgs(int64(3), int64(40), 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(39, 3))
+
+	r := repository.NewCommentRepo(db)
+
+	comments, err := r.GetCommentsByUserID(context.Background(), 3, model.CommentFilter{
+		Limit:  11,
+		Cursor: &model.CommentCursor{ID: 40},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteCommentWithRepliesLeavesTombstone(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM comments WHERE id = ? FOR UPDATE`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "parent_id", "reply_count", "deleted_at"}).
+			AddRow(7, 1, nil, 2, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE comments SET comment = '', deleted_at = ? WHERE id = ?`)).
+		WithArgs(sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM comment_revisions WHERE comment_id = ?`)).
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM reactions WHERE target_type = ? AND target_id = ?`)).
+		WithArgs(model.ReactionTargetComment, int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE a FROM notification_actors AS a`)).
+		WithArgs(model.NotificationTargetComment, int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notifications WHERE target_type = ? AND target_id = ?`)).
+		WithArgs(model.NotificationTargetComment, int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE posts SET comment_count`)).
+		WithArgs(int64(1), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := repository.NewCommentRepo(db)
+
+	assert.NoError(t, r.DeleteComment(context.Background(), 7))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteLastReplyRemovesTombstone(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	columns := []string{"id", "post_id", "parent_id", "reply_count", "deleted_at"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM comments WHERE id = ? FOR UPDATE`)).
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(9, 1, 7, 0, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM reactions WHERE target_type = ? AND target_id = ?`)).
+		WithArgs(model.ReactionTargetComment, int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE a FROM notification_actors AS a`)).
+		WithArgs(model.NotificationTargetComment, int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notifications WHERE target_type = ? AND target_id = ?`)).
+		WithArgs(model.NotificationTargetComment, int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM comments WHERE id = ?`)).
+		WithArgs(int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`SET c.reply_count = r.replies`)).
+		WithArgs(int64(7), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// the parent is a tombstone left without replies, it goes too
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM comments WHERE id = ? FOR UPDATE`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(7, 1, 5, 0, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM reactions WHERE target_type = ? AND target_id = ?`)).
+		WithArgs(model.ReactionTargetComment, int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE a FROM notification_actors AS a`)).
+		WithArgs(model.NotificationTargetComment, int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notifications WHERE target_type = ? AND target_id = ?`)).
+		WithArgs(model.NotificationTargetComment, int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM comments WHERE id = ?`)).
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`SET c.reply_count = r.replies`)).
+		WithArgs(int64(5), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// the grandparent still has a reply and stops the walk
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM comments WHERE id = ? FOR UPDATE`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, 1, nil, 1, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE posts SET comment_count`)).
+		WithArgs(int64(1), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := repository.NewCommentRepo(db)
+
+	assert.NoError(t, r.DeleteComment(context.Background(), 9))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetRepliesBatch(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`WHERE c\.parent_id IN \(\?, \?\)\s+AND \(c\.deleted_at IS NOT NULL OR NOT EXISTS \(SELECT 1 FROM blocks .+\) AS replies\s+WHERE position <= \?`).
+		WithArgs(int64(3), int64(4), int64(2), int64(2), 6).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "position"}).
+			AddRow(10, 3, 1).
+			AddRow(11, 4, 1))
+
+	r := repository.NewCommentRepo(db)
+
+	replies, err := r.GetRepliesBatch(context.Background(), []int64{3, 4}, 6, 2)
+	assert.NoError(t, err)
+	assert.Len(t, replies, 2)
+	assert.Equal(t, int64(4), replies[1].ParentID.Int64)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateCommentVoteUpserts(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM comments WHERE id = ? AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec(regexp.QuoteMeta(`ON DUPLICATE KEY UPDATE vote = VALUES(vote)`)).
+		WithArgs(int64(4), int64(2), int64(-1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE comments SET`)).
+		WithArgs(int64(4), int64(4), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT up_vote, down_vote FROM comments WHERE id = ?`)).
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"up_vote", "down_vote"}).AddRow(0, 1))
+	mock.ExpectCommit()
+
+	r := repository.NewCommentRepo(db)
+
+	count, err := r.SetVote(context.Background(), 4, 2, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, &model.VoteCount{UpVote: 0, DownVote: 1}, count)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetCommentRevisions(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM comment_revisions
+	WHERE comment_id = ?
+	ORDER BY id DESC`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "comment_id", "comment", "created_at", "replaced_at"}).
+			AddRow(2, 7, "second", now.Add(-time.Hour), now).
+			AddRow(1, 7, "first", now.Add(-2*time.Hour), now.Add(-time.Hour)))
+
+	r := repository.NewCommentRepo(db)
+
+	revisions, err := r.GetCommentRevisions(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "second", revisions[0].Comment)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFollowIsIdempotent(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT IGNORE INTO follows (follower_id, followee_id) VALUES (?, ?)`)).
+		WithArgs(int64(2), int64(5)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT IGNORE INTO follows (follower_id, followee_id) VALUES (?, ?)`)).
+		WithArgs(int64(2), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`AS is_following`)).
+		WithArgs(int64(2), int64(5), int64(5), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"is_following", "follows_you"}).AddRow(1, 1))
+
+	r := repository.NewFollowRepo(db)
+	ctx := context.Background()
+
+	assert.NoError(t, r.Follow(ctx, 2, 5))
+	assert.NoError(t, r.Follow(ctx, 2, 5))
+
+	status, err := r.GetFollowStatus(ctx, 2, 5)
+	assert.NoError(t, err)
+	assert.True(t, status.IsFollowing)
+	assert.True(t, status.FollowsYou)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetFollowersKeysetCursor(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	followedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN users AS u ON u.id = f.follower_id WHERE f.followee_id = ? AND f.id < ? AND NOT EXISTS (SELECT 1 FROM blocks`)).
+		WithArgs(int64(2), int64(2), int64(5), int64(30), int64(2), int64(2), 21).
+		WillReturnRows(sqlmock.NewRows([]string{"follow_id", "id", "name", "photo", "followed_at", "is_following", "follows_you"}).
+			AddRow(29, 7, "rico", nil, followedAt, 1, 0))
+
+	r := repository.NewFollowRepo(db)
+
+	users, err := r.GetFollowers(context.Background(), 5, model.FollowFilter{
+		Limit:    21,
+		Cursor:   &model.FollowCursor{ID: 30},
+		ViewerID: 2,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, int64(29), users[0].FollowID)
+	assert.True(t, users[0].IsFollowing)
+	assert.False(t, users[0].FollowsYou)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetFeedReadsFollowedAccounts(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE p.user_id IN (SELECT followee_id FROM follows WHERE follower_id = ?) AND (0 < ? OR (0 = ? AND p.id < ?)) AND NOT EXISTS (SELECT 1 FROM blocks`)).
+		WithArgs(int64(2), int64(2), int64(2), int64(2), float64(0), float64(0), int64(40), int64(2), int64(2), int64(2), 21).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "sort_key"}).
+			AddRow(39, 5, 0))
+
+	feed := repository.NewFanOutOnReadFeed(db)
+
+	posts, err := feed.GetFeed(context.Background(), 2, model.PostFilter{
+		Sort:         model.PostSortNew,
+		Limit:        21,
+		Cursor:       &model.PostCursor{Sort: model.PostSortNew, ID: 40},
+		ViewerID:     2,
+		ExcludeMuted: true,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
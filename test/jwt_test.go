@@ -0,0 +1,110 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/federicodosantos/socialize/pkg/jwt"
+	jwtLib "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newJwtService(t *testing.T, keys *jwt.KeySet) jwt.JWTItf {
+	service, err := jwt.NewJwt(keys, "15m", "socialize", "socialize")
+	if err != nil {
+		t.Fatalf("cannot create jwt service: %s", err)
+	}
+
+	return service
+}
+
+func TestJwtSignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{jwt.AlgorithmHS256, jwt.AlgorithmRS256, jwt.AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			signer, err := jwt.GenerateSigner(algorithm, "key-1")
+			assert.NoError(t, err)
+
+			service := newJwtService(t, jwt.NewKeySet(signer))
+
+			token, err := service.CreateToken(42, "moderator")
+			assert.NoError(t, err)
+
+			claims, err := service.VerifyToken(token)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(42), claims.UserID)
+			assert.Equal(t, "moderator", claims.Role)
+			assert.Equal(t, "42", claims.Subject)
+			assert.Equal(t, "socialize", claims.Issuer)
+			assert.NotEmpty(t, claims.ID)
+			assert.NotNil(t, claims.NotBefore)
+		})
+	}
+}
+
+func TestJwtRejectsAlgorithmConfusion(t *testing.T) {
+	signer, err := jwt.GenerateSigner(jwt.AlgorithmRS256, "key-1")
+	assert.NoError(t, err)
+
+	service := newJwtService(t, jwt.NewKeySet(signer))
+
+	// a token signed with HS256 using the public key material as secret must
+	// not be accepted by an RS256 key set
+	forged := jwtLib.NewWithClaims(jwtLib.SigningMethodHS256, jwtLib.RegisteredClaims{
+		Subject:   "1",
+		Issuer:    "socialize",
+		Audience:  jwtLib.ClaimStrings{"socialize"},
+		IssuedAt:  jwtLib.NewNumericDate(time.Now()),
+		NotBefore: jwtLib.NewNumericDate(time.Now()),
+		ExpiresAt: jwtLib.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	forged.Header["kid"] = "key-1"
+
+	forgedToken, err := forged.SignedString([]byte("public key material"))
+	assert.NoError(t, err)
+
+	_, err = service.VerifyToken(forgedToken)
+	assert.Error(t, err)
+}
+
+func TestJwtKeyRotation(t *testing.T) {
+	oldSigner, err := jwt.GenerateSigner(jwt.AlgorithmEdDSA, "old")
+	assert.NoError(t, err)
+
+	keys := jwt.NewKeySet(oldSigner)
+	service := newJwtService(t, keys)
+
+	oldToken, err := service.CreateToken(1, "user")
+	assert.NoError(t, err)
+
+	newSigner, err := jwt.GenerateSigner(jwt.AlgorithmEdDSA, "new")
+	assert.NoError(t, err)
+
+	keys.Rotate(newSigner, time.Hour)
+
+	_, err = service.VerifyToken(oldToken)
+	assert.NoError(t, err, "tokens signed by a retired key stay valid during the grace period")
+
+	jwks := service.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "new", jwks.Keys[0].KeyID)
+
+	newerSigner, err := jwt.GenerateSigner(jwt.AlgorithmEdDSA, "newer")
+	assert.NoError(t, err)
+
+	keys.Rotate(newerSigner, 0)
+
+	_, ok := keys.Lookup("new")
+	assert.False(t, ok, "keys retired without a grace period are dropped")
+
+	_, ok = keys.Lookup("old")
+	assert.True(t, ok)
+}
+
+func TestJwksOmitsSymmetricKeys(t *testing.T) {
+	signer, err := jwt.GenerateSigner(jwt.AlgorithmHS256, "secret")
+	assert.NoError(t, err)
+
+	service := newJwtService(t, jwt.NewKeySet(signer))
+
+	assert.Empty(t, service.JWKS().Keys)
+}
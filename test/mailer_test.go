@@ -0,0 +1,52 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/federicodosantos/socialize/pkg/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	m := mailer.NewMemoryMailer()
+
+	assert.NoError(t, m.Send(context.Background(), mailer.Message{To: "a@mail.com", Subject: "first"}))
+	assert.NoError(t, m.Send(context.Background(), mailer.Message{To: "b@mail.com", Subject: "second"}))
+	assert.NoError(t, m.Send(context.Background(), mailer.Message{To: "a@mail.com", Subject: "third"}))
+
+	assert.Len(t, m.Messages(), 3)
+
+	last, ok := m.Last("a@mail.com")
+	assert.True(t, ok)
+	assert.Equal(t, "third", last.Subject)
+
+	_, ok = m.Last("c@mail.com")
+	assert.False(t, ok)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := mailer.NewFileMailer(dir, "no-reply@socialize.local")
+	assert.NoError(t, err)
+
+	err = m.Send(context.Background(), mailer.Message{
+		To:      "jamalunyu@gmail.com",
+		Subject: "Verify your Socialize account",
+		Body:    "hello",
+	})
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	content, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(string(content), "To: jamalunyu@gmail.com"))
+	assert.True(t, strings.Contains(string(content), "hello"))
+}
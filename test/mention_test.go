@@ -0,0 +1,39 @@
+package repository_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/federicodosantos/socialize/pkg/mention"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentionUserIDs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected []int64
+	}{
+		{name: "none", text: "hello @everyone", expected: nil},
+		{name: "one", text: "thanks @[Ada Lovelace](12)!", expected: []int64{12}},
+		{name: "repeated", text: "@[Ada](12) and @[Bob](7) and @[Ada](12)", expected: []int64{12, 7}},
+		{name: "malformed", text: "@[Ada](x12) @[](3) @Ada(4)", expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, mention.UserIDs(tc.text))
+		})
+	}
+}
+
+func TestMentionUserIDsLimit(t *testing.T) {
+	var sb strings.Builder
+	for i := 1; i <= mention.MaxMentions+5; i++ {
+		sb.WriteString("@[user](")
+		sb.WriteString(strings.Repeat("1", i))
+		sb.WriteString(") ")
+	}
+
+	assert.Len(t, mention.UserIDs(sb.String()), mention.MaxMentions)
+}
@@ -0,0 +1,166 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	customerror "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddEventGathersActors(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	event := &model.NotificationEvent{
+		UserID:     4,
+		ActorID:    9,
+		Type:       model.NotificationVote,
+		TargetType: model.NotificationTargetPost,
+		TargetID:   7,
+		PostID:     7,
+	}
+
+	// a new actor joins the unread notification and is counted
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`id = LAST_INSERT_ID(id)`)).
+		WithArgs(int64(4), model.NotificationVote, model.NotificationTargetPost, int64(7), int64(7), int64(9)).
+		WillReturnResult(sqlmock.NewResult(12, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT IGNORE INTO notification_actors (notification_id, actor_id) VALUES (?, ?)`)).
+		WithArgs(int64(12), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notifications SET actor_count = actor_count + 1 WHERE id = ?`)).
+		WithArgs(int64(12)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// the same actor again is not counted twice
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`id = LAST_INSERT_ID(id)`)).
+		WithArgs(int64(4), model.NotificationVote, model.NotificationTargetPost, int64(7), int64(7), int64(9)).
+		WillReturnResult(sqlmock.NewResult(12, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT IGNORE INTO notification_actors (notification_id, actor_id) VALUES (?, ?)`)).
+		WithArgs(int64(12), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	r := repository.NewNotificationRepo(db)
+	ctx := context.Background()
+
+	assert.NoError(t, r.AddEvent(ctx, event))
+	assert.NoError(t, r.AddEvent(ctx, event))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAddFollowEventHasNoPost(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`id = LAST_INSERT_ID(id)`)).
+		WithArgs(int64(4), model.NotificationFollow, model.NotificationTargetUser, int64(4), nil, int64(9)).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT IGNORE INTO notification_actors`)).
+		WithArgs(int64(3), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE notifications SET actor_count = actor_count + 1 WHERE id = ?`)).
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := repository.NewNotificationRepo(db)
+
+	err = r.AddEvent(context.Background(), &model.NotificationEvent{
+		UserID:     4,
+		ActorID:    9,
+		Type:       model.NotificationFollow,
+		TargetType: model.NotificationTargetUser,
+		TargetID:   4,
+	})
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	testCases := []struct {
+		name        string
+		rowsChanged int64
+		exists      bool
+		expectedErr error
+	}{
+		{name: "unread", rowsChanged: 1},
+		{name: "already read", rowsChanged: 0, exists: true},
+		{name: "of another user", rowsChanged: 0, exists: false, expectedErr: customerror.ErrNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := setup()
+			if err != nil {
+				t.Fatalf("error to create sql mock and db due to %s", err.Error())
+			}
+			defer db.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta(`WHERE id = ? AND user_id = ? AND unread = 1`)).
+				WithArgs(int64(12), int64(4)).
+				WillReturnResult(sqlmock.NewResult(0, tc.rowsChanged))
+			if tc.rowsChanged == 0 {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM notifications WHERE id = ? AND user_id = ?)`)).
+					WithArgs(int64(12), int64(4)).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tc.exists))
+			}
+
+			r := repository.NewNotificationRepo(db)
+
+			err = r.MarkRead(context.Background(), 4, 12)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetNotificationsLeavesOutBlockedActors(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE n.user_id = ? AND NOT EXISTS (SELECT 1 FROM blocks`)).
+		WithArgs(int64(4), int64(4), int64(4), 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "target_type", "target_id", "actor_count", "actor_id", "actor_name"}).
+			AddRow(12, model.NotificationVote, model.NotificationTargetPost, 3, 2, 9, "actor"))
+
+	r := repository.NewNotificationRepo(db)
+
+	notifications, err := r.GetNotifications(context.Background(), 4, model.NotificationFilter{Limit: 20})
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 1)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
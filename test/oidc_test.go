@@ -0,0 +1,218 @@
+package repository_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/federicodosantos/socialize/pkg/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockOIDCServer is a minimal OpenID Connect provider. It hands out a single
+// authorization code bound to the PKCE challenge and nonce of the last
+// authorization request.
+type mockOIDCServer struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	// audience overrides the aud claim of issued ID tokens when set
+	audience      string
+	codeChallenge string
+	nonce         string
+}
+
+func newMockOIDCServer(t *testing.T, clientID string) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate rsa key: %s", err)
+	}
+
+	m := &mockOIDCServer{key: key, clientID: clientID}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock-key",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil ||
+			r.PostForm.Get("code") != "mock-code" ||
+			oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != m.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		audience := m.audience
+		if audience == "" {
+			audience = m.clientID
+		}
+
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            m.URL,
+			"sub":            "mock-subject",
+			"aud":            audience,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          m.nonce,
+			"email":          "johndoe@gmail.com",
+			"email_verified": true,
+			"name":           "john doe",
+		})
+		idToken.Header["kid"] = "mock-key"
+
+		signed, err := idToken.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"id_token":     signed,
+		})
+	})
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+// authorize plays the part of the user logging in at the provider.
+func (m *mockOIDCServer) authorize(t *testing.T, authURL string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid auth url: %s", err)
+	}
+
+	query := u.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, m.clientID, query.Get("client_id"))
+
+	m.codeChallenge = query.Get("code_challenge")
+	m.nonce = query.Get("nonce")
+}
+
+func newMockProvider(t *testing.T, m *mockOIDCServer, issuer string) *oidc.Provider {
+	provider, err := oidc.NewProvider(oidc.Config{
+		Name:        "mock",
+		Issuer:      issuer,
+		ClientID:    m.clientID,
+		RedirectURL: "http://localhost:8061/auth/oauth/mock/callback",
+	}, m.Client())
+	if err != nil {
+		t.Fatalf("cannot create provider: %s", err)
+	}
+
+	return provider
+}
+
+func TestOIDCLogin(t *testing.T) {
+	type testCase struct {
+		name string
+		// tamper changes the flow before the code is exchanged
+		tamper      func(m *mockOIDCServer, verifier *string, nonce *string)
+		expectedErr error
+		expectErr   bool
+	}
+
+	testCases := []testCase{
+		{
+			name:   "Success",
+			tamper: func(m *mockOIDCServer, verifier *string, nonce *string) {},
+		},
+		{
+			name: "Wrong code verifier",
+			tamper: func(m *mockOIDCServer, verifier *string, nonce *string) {
+				*verifier = "not-the-verifier"
+			},
+			expectErr: true,
+		},
+		{
+			name: "Nonce mismatch",
+			tamper: func(m *mockOIDCServer, verifier *string, nonce *string) {
+				*nonce = "another-nonce"
+			},
+			expectedErr: oidc.ErrNonceMismatch,
+		},
+		{
+			name: "Token issued to another client",
+			tamper: func(m *mockOIDCServer, verifier *string, nonce *string) {
+				m.audience = "another-client"
+			},
+			expectedErr: oidc.ErrInvalidIDToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newMockOIDCServer(t, "socialize")
+			provider := newMockProvider(t, m, m.URL)
+			ctx := context.Background()
+
+			verifier, challenge, err := oidc.NewPKCE()
+			assert.NoError(t, err)
+
+			authURL, err := provider.AuthCodeURL(ctx, "mock-state", "mock-nonce", challenge)
+			assert.NoError(t, err)
+
+			m.authorize(t, authURL)
+
+			nonce := "mock-nonce"
+			tc.tamper(m, &verifier, &nonce)
+
+			claims, err := provider.Exchange(ctx, "mock-code", verifier, nonce)
+
+			switch {
+			case tc.expectedErr != nil:
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, claims)
+			case tc.expectErr:
+				assert.Error(t, err)
+				assert.Nil(t, claims)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, "mock-subject", claims.Subject)
+				assert.Equal(t, "johndoe@gmail.com", claims.Email)
+				assert.True(t, claims.EmailVerified)
+			}
+		})
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	m := newMockOIDCServer(t, "socialize")
+	provider := newMockProvider(t, m, m.URL+"/")
+
+	_, err := provider.Discover(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package repository_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/federicodosantos/socialize/pkg/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	type testCase struct {
+		name   string
+		hasher password.Hasher
+		prefix string
+	}
+
+	testCases := []testCase{
+		{
+			name:   "argon2id",
+			hasher: password.NewArgon2id(1024, 1, 1),
+			prefix: "$argon2id$v=19$m=1024,t=1,p=1$",
+		},
+		{
+			name:   "bcrypt",
+			hasher: password.NewBcrypt(4),
+			prefix: "$2a$04$",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded, err := tc.hasher.Hash("rahasia123")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(encoded, tc.prefix))
+			assert.True(t, tc.hasher.Supports(encoded))
+			assert.False(t, tc.hasher.NeedsRehash(encoded))
+
+			valid, err := tc.hasher.Verify("rahasia123", encoded)
+			assert.NoError(t, err)
+			assert.True(t, valid)
+
+			valid, err = tc.hasher.Verify("rahasia124", encoded)
+			assert.NoError(t, err)
+			assert.False(t, valid)
+		})
+	}
+}
+
+func TestMigratingHasherUpgradesLegacyHashes(t *testing.T) {
+	argon2id := password.NewArgon2id(1024, 1, 1)
+	hasher := password.NewMigratingHasher(argon2id, password.NewBcrypt(4), password.LegacyMD5{})
+
+	digest := md5.Sum([]byte("rahasia123"))
+	legacy := hex.EncodeToString(digest[:])
+
+	valid, err := hasher.Verify("rahasia123", legacy)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	assert.True(t, hasher.NeedsRehash(legacy))
+
+	valid, err = hasher.Verify("wrong", legacy)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	weaker, err := password.NewArgon2id(512, 1, 1).Hash("rahasia123")
+	assert.NoError(t, err)
+	assert.True(t, hasher.NeedsRehash(weaker))
+
+	upgraded, err := hasher.Hash("rahasia123")
+	assert.NoError(t, err)
+	assert.False(t, hasher.NeedsRehash(upgraded))
+
+	_, err = hasher.Verify("rahasia123", "plaintext")
+	assert.ErrorIs(t, err, password.ErrUnsupportedHash)
+}
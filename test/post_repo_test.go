@@ -0,0 +1,365 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	customerror "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/federicodosantos/socialize/pkg/ranking"
+	"github.com/stretchr/testify/assert"
+)
+
+func createPost(payload string) *model.Post {
+	now := time.Now()
+
+	return &model.Post{
+		Title:     payload,
+		Content:   payload,
+		UserID:    1,
+		Image:     sql.NullString{String: payload, Valid: true},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestPostRepoInjectionPayloads(t *testing.T) {
+	for _, payload := range injectionPayloads {
+		t.Run(payload, func(t *testing.T) {
+			db, mock, err := setup()
+			if err != nil {
+				t.Fatalf("error to create sql mock and db due to %s", err.Error())
+			}
+			defer db.Close()
+
+			post := createPost(payload)
+
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO posts (
+					user_id, title, content, image, created_at, updated_at
+				) VALUES (
+					?, ?, ?, ?, ?, ?
+				)`)).
+				WithArgs(post.UserID, payload, payload, payload, post.CreatedAt, post.UpdatedAt).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			mock.ExpectQuery(regexp.QuoteMeta(`WHERE p.content LIKE ?`)).
+				WithArgs(int64(0), int64(0), int64(0), "%"+payload+"%").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content"}))
+
+			mock.ExpectQuery(regexp.QuoteMeta(`WHERE p.user_id = ? AND p.content LIKE ?`)).
+				WithArgs(int64(0), int64(0), int64(0), post.UserID, "%"+payload+"%").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content"}))
+
+			r := repository.NewPostRepo(db, ranking.RedditHot{})
+			ctx := context.Background()
+
+			assert.NoError(t, r.CreatePost(ctx, post))
+			assert.Equal(t, int64(1), post.ID)
+
+			_, err = r.GetAllPost(ctx, model.PostFilter{Keyword: payload})
+			assert.NoError(t, err)
+
+			_, err = r.GetAllPostByUserID(ctx, model.PostFilter{Keyword: payload}, post.UserID)
+			assert.NoError(t, err)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetAllPostEscapesLikeWildcards(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE p.content LIKE ?`)).
+		WithArgs(int64(0), int64(0), int64(0), `%100\%\_off%`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content"}))
+
+	r := repository.NewPostRepo(db, ranking.RedditHot{})
+
+	_, err = r.GetAllPost(context.Background(), model.PostFilter{Keyword: "100%_off"})
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	testCases := []struct {
+		name         string
+		rowsAffected int64
+		expectedErr  error
+	}{
+		{name: "deleted", rowsAffected: 1},
+		{name: "not found", rowsAffected: 0, expectedErr: customerror.ErrNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := setup()
+			if err != nil {
+				t.Fatalf("error to create sql mock and db due to %s", err.Error())
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM votes WHERE post_id = ?`)).
+				WithArgs(int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM bookmarks WHERE post_id = ?`)).
+				WithArgs(int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`JOIN notifications AS n ON n.id = a.notification_id`)).
+				WithArgs(int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 4))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notifications WHERE post_id = ?`)).
+				WithArgs(int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectExec(regexp.QuoteMeta(`JOIN comments AS c ON c.id = r.target_id`)).
+				WithArgs(model.ReactionTargetComment, int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM reactions WHERE target_type = ? AND target_id = ?`)).
+				WithArgs(model.ReactionTargetPost, int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE comments SET parent_id = NULL WHERE post_id = ?`)).
+				WithArgs(int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM comments WHERE post_id = ?`)).
+				WithArgs(int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM posts WHERE id = ?`)).
+				WithArgs(int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, tc.rowsAffected))
+			if tc.expectedErr != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectCommit()
+			}
+
+			r := repository.NewPostRepo(db, ranking.RedditHot{})
+
+			err = r.DeletePost(context.Background(), 3)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestUpdatePostKeepsRevision(t *testing.T) {
+	testCases := []struct {
+		name        string
+		snapshotted int64
+		expectedErr error
+	}{
+		{name: "updated", snapshotted: 1},
+		{name: "not found", snapshotted: 0, expectedErr: customerror.ErrNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := setup()
+			if err != nil {
+				t.Fatalf("error to create sql mock and db due to %s", err.Error())
+			}
+			defer db.Close()
+
+			post := createPost("edited")
+			post.ID = 3
+			post.EditedAt = sql.NullTime{Time: post.UpdatedAt, Valid: true}
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO post_revisions`)).
+				WithArgs(post.EditedAt.Time, post.ID).
+				WillReturnResult(sqlmock.NewResult(1, tc.snapshotted))
+			if tc.expectedErr != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE posts SET`)).
+					WithArgs(post.Title, post.Content, post.Image, post.UpdatedAt, post.EditedAt, post.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			r := repository.NewPostRepo(db, ranking.RedditHot{})
+
+			err = r.UpdatePost(context.Background(), post)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetAllPostKeysetCursor(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	since := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE p.created_at >= ? AND (p.net_vote < ? OR (p.net_vote = ? AND p.id < ?)) AND NOT EXISTS (SELECT 1 FROM blocks`)).
+		WithArgs(int64(9), int64(9), int64(9), since, float64(5), float64(5), int64(42), int64(9), int64(9), 21).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "my_vote", "is_owner", "sort_key"}).
+			AddRow(41, "title", "content", 1, 1, 5).
+			AddRow(17, "title", "content", 0, 0, 4))
+
+	r := repository.NewPostRepo(db, ranking.RedditHot{})
+
+	posts, err := r.GetAllPost(context.Background(), model.PostFilter{
+		Sort:     model.PostSortTop,
+		Since:    since,
+		Limit:    21,
+		Cursor:   &model.PostCursor{Sort: model.PostSortTop, Score: 5, ID: 42},
+		ViewerID: 9,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, posts, 2)
+	assert.Equal(t, float64(4), posts[1].SortKey)
+	assert.Equal(t, int64(1), posts[0].MyVote)
+	assert.True(t, posts[0].IsOwner)
+	assert.False(t, posts[1].IsOwner)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetAllPostTimeDependentHotIsRankedAsOfFirstPage(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	rankedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`(p.net_vote / POW(GREATEST(TIMESTAMPDIFF(SECOND, p.created_at, ?) / 3600, 0) + 2, 1.8)) AS sort_key`)).
+		WithArgs(int64(9), int64(9), rankedAt, int64(9), rankedAt, 0.5, rankedAt, 0.5, int64(42), int64(9), int64(9), 21).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sort_key"}).
+			AddRow(41, 0.5).
+			AddRow(17, 0.25))
+
+	r := repository.NewPostRepo(db, ranking.HackerNews{Gravity: 1.8})
+
+	posts, err := r.GetAllPost(context.Background(), model.PostFilter{
+		Sort:     model.PostSortHot,
+		Limit:    21,
+		Cursor:   &model.PostCursor{Sort: model.PostSortHot, Score: 0.5, ID: 42},
+		ViewerID: 9,
+		RankedAt: rankedAt,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, posts, 2)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetPostByIDViewerFields(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`LEFT JOIN votes AS v ON v.post_id = p.id AND v.user_id = ? WHERE p.id = ?`)).
+		WithArgs(int64(9), int64(9), int64(9), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "comment_count", "my_vote", "is_owner", "bookmarked"}).
+			AddRow(3, 4, 12, -1, 0, 1))
+
+	r := repository.NewPostRepo(db, ranking.RedditHot{})
+
+	post, err := r.GetPostByID(context.Background(), 3, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), post.CommentCount)
+	assert.Equal(t, int64(-1), post.MyVote)
+	assert.False(t, post.IsOwner)
+	assert.True(t, post.Bookmarked)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSetVoteUpsertsAndReturnsCounts(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM posts WHERE id = ? FOR UPDATE`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(regexp.QuoteMeta(`ON DUPLICATE KEY UPDATE vote = VALUES(vote)`)).
+		WithArgs(int64(7), int64(3), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE posts SET`)).
+		WithArgs(int64(7), int64(7), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT up_vote, down_vote FROM posts WHERE id = ?`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"up_vote", "down_vote"}).AddRow(1, 0))
+	mock.ExpectCommit()
+
+	r := repository.NewPostRepo(db, ranking.RedditHot{})
+
+	count, err := r.SetVote(context.Background(), 7, 3, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, &model.VoteCount{UpVote: 1, DownVote: 0}, count)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteVoteOnMissingPost(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM posts WHERE id = ? FOR UPDATE`)).
+		WithArgs(int64(7)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	r := repository.NewPostRepo(db, ranking.RedditHot{})
+
+	_, err = r.DeleteVote(context.Background(), 7, 3)
+	assert.ErrorIs(t, err, customerror.ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
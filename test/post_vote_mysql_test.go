@@ -0,0 +1,136 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/ranking"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSetVoteConcurrentMySQL votes on one post from many users at once, which
+// only a real database can check. It needs TEST_MYSQL_DSN to point at a
+// migrated schema, with parseTime=true, and is skipped otherwise.
+func TestSetVoteConcurrentMySQL(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+
+	db, err := sqlx.Connect("mysql", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	run := time.Now().UnixNano()
+
+	const voters = 50
+
+	createUser := func(i int) int64 {
+		res, err := db.ExecContext(ctx, `INSERT INTO users (name, email, password) VALUES (?, ?, ?)`,
+			fmt.Sprintf("voter %d", i), fmt.Sprintf("voter-%d-%d@example.com", run, i), "unused")
+		require.NoError(t, err)
+
+		id, err := res.LastInsertId()
+		require.NoError(t, err)
+
+		return id
+	}
+
+	userIDs := []int64{createUser(0)}
+
+	res, err := db.ExecContext(ctx, `INSERT INTO posts (title, content, user_id) VALUES (?, ?, ?)`,
+		"concurrent votes", "content", userIDs[0])
+	require.NoError(t, err)
+
+	postID, err := res.LastInsertId()
+	require.NoError(t, err)
+
+	for i := 1; i <= voters; i++ {
+		userIDs = append(userIDs, createUser(i))
+	}
+
+	t.Cleanup(func() {
+		db.ExecContext(ctx, `DELETE FROM votes WHERE post_id = ?`, postID)
+		db.ExecContext(ctx, `DELETE FROM posts WHERE id = ?`, postID)
+
+		query, args, _ := sqlx.In(`DELETE FROM users WHERE id IN (?)`, userIDs)
+		db.ExecContext(ctx, query, args...)
+	})
+
+	r := repository.NewPostRepo(db, ranking.RedditHot{})
+
+	// every voter up votes, the even ones then switch to a down vote and one
+	// in three retracts their vote, all at the same time
+	var expectedUp, expectedDown int64
+
+	var wg sync.WaitGroup
+	errs := make(chan error, voters)
+	for i, userID := range userIDs[1:] {
+		switch {
+		case i%3 == 0:
+		case i%2 == 0:
+			expectedDown++
+		default:
+			expectedUp++
+		}
+
+		wg.Add(1)
+		go func(i int, userID int64) {
+			defer wg.Done()
+
+			if _, err := r.SetVote(ctx, postID, userID, 1); err != nil {
+				errs <- err
+				return
+			}
+
+			if i%2 == 0 {
+				if _, err := r.SetVote(ctx, postID, userID, -1); err != nil {
+					errs <- err
+					return
+				}
+			}
+
+			if i%3 == 0 {
+				if _, err := r.DeleteVote(ctx, postID, userID); err != nil {
+					errs <- err
+				}
+			}
+		}(i, userID)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("vote failed: %s", err)
+	}
+
+	var counters struct {
+		UpVote   int64 `db:"up_vote"`
+		DownVote int64 `db:"down_vote"`
+	}
+	require.NoError(t, db.GetContext(ctx, &counters, `SELECT up_vote, down_vote FROM posts WHERE id = ?`, postID))
+
+	assert.Equal(t, expectedUp, counters.UpVote)
+	assert.Equal(t, expectedDown, counters.DownVote)
+
+	// the counters on the post match the votes themselves
+	var rows struct {
+		UpVote   int64 `db:"up_vote"`
+		DownVote int64 `db:"down_vote"`
+	}
+	require.NoError(t, db.GetContext(ctx, &rows, `
+	SELECT COALESCE(SUM(vote = 1), 0) AS up_vote, COALESCE(SUM(vote = -1), 0) AS down_vote
+	FROM votes WHERE post_id = ?`, postID))
+
+	assert.Equal(t, counters.UpVote, rows.UpVote)
+	assert.Equal(t, counters.DownVote, rows.DownVote)
+}
@@ -0,0 +1,55 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/federicodosantos/socialize/pkg/ranking"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedditHot(t *testing.T) {
+	scorer, err := ranking.NewScorer(ranking.AlgorithmHot)
+	assert.NoError(t, err)
+	assert.False(t, scorer.TimeDependent())
+
+	now := time.Now()
+	older := ranking.Stats{UpVote: 10, CreatedAt: now.Add(-12*time.Hour - 30*time.Minute)}
+	newer := ranking.Stats{UpVote: 1, CreatedAt: now}
+
+	// ten times the net votes make up for 12.5 hours
+	assert.InDelta(t, scorer.Score(older, now), scorer.Score(newer, now), 1e-6)
+
+	downVoted := ranking.Stats{DownVote: 10, CreatedAt: now}
+	assert.Less(t, scorer.Score(downVoted, now), scorer.Score(newer, now))
+}
+
+func TestWilson(t *testing.T) {
+	scorer, err := ranking.NewScorer(ranking.AlgorithmWilson)
+	assert.NoError(t, err)
+
+	now := time.Now()
+
+	assert.Equal(t, 0.0, scorer.Score(ranking.Stats{}, now))
+
+	few := scorer.Score(ranking.Stats{UpVote: 2}, now)
+	many := scorer.Score(ranking.Stats{UpVote: 95, DownVote: 5}, now)
+	assert.Greater(t, many, few, "a high ratio over many votes beats a perfect ratio over a few")
+	assert.InDelta(t, 0.8882, many, 1e-4)
+}
+
+func TestHackerNews(t *testing.T) {
+	scorer, err := ranking.NewScorer(ranking.AlgorithmHackerNews)
+	assert.NoError(t, err)
+	assert.True(t, scorer.TimeDependent())
+
+	now := time.Now()
+	stats := ranking.Stats{UpVote: 50, CreatedAt: now.Add(-time.Hour)}
+
+	assert.Greater(t, scorer.Score(stats, now), scorer.Score(stats, now.Add(24*time.Hour)))
+}
+
+func TestUnknownRankingAlgorithm(t *testing.T) {
+	_, err := ranking.NewScorer("random")
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/federicodosantos/socialize/pkg/rbac"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolePermissions(t *testing.T) {
+	type testCase struct {
+		role     rbac.Role
+		perms    []rbac.Permission
+		expected bool
+	}
+
+	testCases := []testCase{
+		{role: rbac.RoleUser, perms: []rbac.Permission{rbac.PermRemoveAnyPost}, expected: false},
+		{role: rbac.RoleModerator, perms: []rbac.Permission{rbac.PermRemoveAnyPost, rbac.PermRemoveAnyComment}, expected: true},
+		{role: rbac.RoleModerator, perms: []rbac.Permission{rbac.PermManageUsers}, expected: false},
+		{role: rbac.RoleAdmin, perms: []rbac.Permission{rbac.PermRemoveAnyPost, rbac.PermManageUsers}, expected: true},
+		{role: rbac.Role("superuser"), perms: []rbac.Permission{rbac.PermRemoveAnyPost}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.role), func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.role.Can(tc.perms...))
+		})
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := rbac.ParseRole("moderator")
+	assert.NoError(t, err)
+	assert.Equal(t, rbac.RoleModerator, role)
+
+	_, err = rbac.ParseRole("Admin")
+	assert.Error(t, err)
+}
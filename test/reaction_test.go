@@ -0,0 +1,71 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/reaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReactionSet(t *testing.T) {
+	testCases := []struct {
+		name      string
+		list      string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "default", list: "", expected: reaction.Default},
+		{name: "custom", list: "🔥, 👀 ,🎉", expected: []string{"🔥", "👀", "🎉"}},
+		{name: "empty entry", list: "🔥,,🎉", expectErr: true},
+		{name: "duplicated", list: "🔥,🔥", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			set, err := reaction.Parse(tc.list)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, set.List())
+
+			for _, r := range tc.expected {
+				assert.True(t, set.Contains(r))
+			}
+			assert.False(t, set.Contains("not a reaction"))
+		})
+	}
+}
+
+func TestGetReactionCounts(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE target_type = ? AND target_id IN (?, ?)
+	GROUP BY target_id, reaction`)).
+		WithArgs(model.ReactionTargetPost, int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"target_id", "reaction", "count"}).
+			AddRow(1, "👍", 3).
+			AddRow(2, "😂", 1))
+
+	r := repository.NewReactionRepo(db)
+
+	counts, err := r.GetReactionCounts(context.Background(), model.ReactionTargetPost, []int64{1, 2})
+	assert.NoError(t, err)
+	assert.Len(t, counts, 2)
+	assert.Equal(t, int64(3), counts[0].Count)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,178 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/federicodosantos/socialize/pkg/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveEvent(t *testing.T, sub *realtime.Subscription) realtime.Event {
+	t.Helper()
+
+	select {
+	case event := <-sub.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return realtime.Event{}
+	}
+}
+
+func assertNoEvent(t *testing.T, sub *realtime.Subscription) {
+	t.Helper()
+
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestRealtimeHubPublish(t *testing.T) {
+	hub := realtime.NewMemoryHub(10, 10)
+	defer hub.Close()
+
+	ctx := context.Background()
+
+	sub, err := hub.Subscribe(ctx, []string{realtime.PostChannel(1)}, "")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	require.NoError(t, hub.Publish(ctx, realtime.Event{Channel: realtime.PostChannel(2), Type: realtime.EventVote}))
+	require.NoError(t, hub.Publish(ctx, realtime.Event{Channel: realtime.PostChannel(1), Type: realtime.EventComment}))
+
+	event := receiveEvent(t, sub)
+	assert.Equal(t, realtime.PostChannel(1), event.Channel)
+	assert.Equal(t, realtime.EventComment, event.Type)
+	assert.NotEmpty(t, event.ID)
+	assertNoEvent(t, sub)
+}
+
+func TestRealtimeHubReplay(t *testing.T) {
+	hub := realtime.NewMemoryHub(10, 10)
+	defer hub.Close()
+
+	ctx := context.Background()
+	channel := realtime.UserChannel(7)
+
+	sub, err := hub.Subscribe(ctx, []string{channel}, "")
+	require.NoError(t, err)
+
+	require.NoError(t, hub.Publish(ctx, realtime.Event{Channel: channel, Type: realtime.EventNotification}))
+	last := receiveEvent(t, sub)
+	sub.Close()
+
+	// published while the client was away
+	require.NoError(t, hub.Publish(ctx, realtime.Event{Channel: channel, Type: realtime.EventNotification}))
+	require.NoError(t, hub.Publish(ctx, realtime.Event{Channel: realtime.UserChannel(8), Type: realtime.EventNotification}))
+
+	resumed, err := hub.Subscribe(ctx, []string{channel}, last.ID)
+	require.NoError(t, err)
+	defer resumed.Close()
+
+	event := receiveEvent(t, resumed)
+	assert.Equal(t, channel, event.Channel)
+	assert.NotEqual(t, last.ID, event.ID)
+	assertNoEvent(t, resumed)
+}
+
+func TestRealtimeHubReplayReset(t *testing.T) {
+	testCases := []struct {
+		name        string
+		lastEventID func(first realtime.Event) string
+	}{
+		{name: "unknown hub", lastEventID: func(realtime.Event) string { return "otherhub-1" }},
+		{name: "out of history", lastEventID: func(first realtime.Event) string { return first.ID }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := realtime.NewMemoryHub(2, 10)
+			defer hub.Close()
+
+			ctx := context.Background()
+			channel := realtime.PostChannel(1)
+
+			sub, err := hub.Subscribe(ctx, []string{channel}, "")
+			require.NoError(t, err)
+
+			// the history only keeps the two last of the four events
+			for i := 0; i < 4; i++ {
+				require.NoError(t, hub.Publish(ctx, realtime.Event{Channel: channel, Type: realtime.EventVote}))
+			}
+
+			first := receiveEvent(t, sub)
+			sub.Close()
+
+			resumed, err := hub.Subscribe(ctx, []string{channel}, tc.lastEventID(first))
+			require.NoError(t, err)
+			defer resumed.Close()
+
+			event := receiveEvent(t, resumed)
+			assert.Equal(t, realtime.EventReset, event.Type)
+			assert.NotEmpty(t, event.ID)
+			assertNoEvent(t, resumed)
+		})
+	}
+}
+
+func TestRealtimeHubSlowConsumer(t *testing.T) {
+	hub := realtime.NewMemoryHub(10, 2)
+	defer hub.Close()
+
+	ctx := context.Background()
+	channel := realtime.PostChannel(1)
+
+	slow, err := hub.Subscribe(ctx, []string{channel}, "")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, hub.Publish(ctx, realtime.Event{Channel: channel, Type: realtime.EventVote}))
+	}
+
+	select {
+	case <-slow.Done():
+	case <-time.After(time.Second):
+		t.Fatal("slow subscription was not dropped")
+	}
+
+	assert.ErrorIs(t, slow.Err(), realtime.ErrSlowConsumer)
+
+	// the other subscribers are not held back
+	fast, err := hub.Subscribe(ctx, []string{channel}, "")
+	require.NoError(t, err)
+	defer fast.Close()
+
+	require.NoError(t, hub.Publish(ctx, realtime.Event{Channel: channel, Type: realtime.EventVote}))
+	receiveEvent(t, fast)
+}
+
+func TestRealtimeHubClose(t *testing.T) {
+	hub := realtime.NewMemoryHub(10, 10)
+	ctx := context.Background()
+
+	sub, err := hub.Subscribe(ctx, []string{realtime.UserChannel(1)}, "")
+	require.NoError(t, err)
+
+	require.NoError(t, hub.Close())
+
+	<-sub.Done()
+	assert.ErrorIs(t, sub.Err(), realtime.ErrHubClosed)
+
+	_, err = hub.Subscribe(ctx, []string{realtime.UserChannel(1)}, "")
+	assert.ErrorIs(t, err, realtime.ErrHubClosed)
+	assert.ErrorIs(t, hub.Publish(ctx, realtime.Event{Channel: realtime.UserChannel(1)}), realtime.ErrHubClosed)
+}
+
+func TestRealtimeNewHub(t *testing.T) {
+	hub, err := realtime.NewHub(realtime.Config{})
+	require.NoError(t, err)
+	assert.NoError(t, hub.Close())
+
+	_, err = realtime.NewHub(realtime.Config{Driver: "carrier-pigeon"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package repository_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/federicodosantos/socialize/internal/model"
+	"github.com/federicodosantos/socialize/internal/repository"
+	"github.com/federicodosantos/socialize/pkg/snippet"
+	"github.com/federicodosantos/socialize/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBleveSearchIndex(t *testing.T) {
+	index, err := repository.NewBleveSearchIndex(filepath.Join(t.TempDir(), "search.bleve"))
+	if err != nil {
+		t.Fatalf("cannot create search index: %s", err)
+	}
+	defer index.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	err = index.Index(ctx,
+		&model.SearchDocument{Type: model.SearchTypePost, ID: 1, PostID: 1, AuthorID: 7, AuthorName: "Ana",
+			Title: "Gardening tips", Body: "Tomatoes need a lot of sun", HasImage: true, Score: 12, CreatedAt: now},
+		&model.SearchDocument{Type: model.SearchTypePost, ID: 2, PostID: 2, AuthorID: 8, AuthorName: "Budi",
+			Title: "Cooking", Body: "A tomato soup recipe", Score: 1, CreatedAt: now.Add(-48 * time.Hour)},
+		&model.SearchDocument{Type: model.SearchTypeComment, ID: 5, PostID: 1, AuthorID: 8, AuthorName: "Budi",
+			Body: "My tomatoes love the sun too", CreatedAt: now},
+	)
+	assert.NoError(t, err)
+
+	hits, err := index.Search(ctx, model.SearchQuery{Text: "tomatoes", Type: model.SearchTypePost, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 2, "the analyzer matches the singular form too")
+
+	var gardening *model.SearchDocument
+	for _, hit := range hits {
+		if hit.Document.ID == 1 {
+			gardening = hit.Document
+		}
+	}
+	if assert.NotNil(t, gardening) {
+		assert.Equal(t, "Ana", gardening.AuthorName)
+		assert.Equal(t, int64(7), gardening.AuthorID)
+		assert.True(t, gardening.HasImage)
+		assert.True(t, now.Equal(gardening.CreatedAt))
+	}
+
+	minScore := int64(5)
+	hits, err = index.Search(ctx, model.SearchQuery{Text: "tomato", Type: model.SearchTypePost, MinScore: &minScore, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	hits, err = index.Search(ctx, model.SearchQuery{Text: "tomato", Type: model.SearchTypePost,
+		From: now.Add(-time.Hour), Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, int64(1), hits[0].Document.ID)
+
+	hits, err = index.Search(ctx, model.SearchQuery{Text: "tomato", Type: model.SearchTypePost, AuthorID: 8, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, int64(2), hits[0].Document.ID)
+
+	hits, err = index.Search(ctx, model.SearchQuery{Text: "tomato", Type: model.SearchTypePost,
+		ExcludeAuthorIDs: []int64{7}, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, int64(2), hits[0].Document.ID)
+
+	// deleting a post removes its comments
+	assert.NoError(t, index.Delete(ctx, model.SearchTypePost, 1))
+
+	hits, err = index.Search(ctx, model.SearchQuery{Text: "sun", Type: model.SearchTypeComment, Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+}
+
+func TestMySQLSearchIndexFilters(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	hasImage := true
+	minScore := int64(3)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE MATCH(p.title, p.content) AGAINST (? IN NATURAL LANGUAGE MODE) AND p.user_id = ? AND COALESCE(p.image, '') <> '' AND p.net_vote >= ? ORDER BY relevance DESC, p.id DESC LIMIT ? OFFSET ?`)).
+		WithArgs("' OR 1=1 --", "' OR 1=1 --", int64(4), minScore, 20, 40).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "title", "relevance"}).
+			AddRow("post", 9, "title", 1.5))
+
+	index := repository.NewMySQLSearchIndex(db)
+
+	hits, err := index.Search(context.Background(), model.SearchQuery{
+		Text:     "' OR 1=1 --",
+		Type:     model.SearchTypePost,
+		AuthorID: 4,
+		HasImage: &hasImage,
+		MinScore: &minScore,
+		Limit:    20,
+		Offset:   40,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, int64(9), hits[0].Document.ID)
+	assert.Equal(t, 1.5, hits[0].Relevance)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSnippetHighlight(t *testing.T) {
+	terms := snippet.Terms("Tomato <soup>")
+	assert.Equal(t, []string{"Tomato", "soup"}, terms)
+
+	assert.Equal(t, "A <mark>tomato</mark> &amp; <mark>soup</mark> &lt;b&gt;",
+		snippet.Highlight("A tomato & soup <b>", terms, 100))
+
+	long := "Lorem ipsum dolor sit amet, consectetur adipiscing elit. The tomato is here. Sed do eiusmod tempor."
+	assert.Equal(t, "…. The <mark>tomato</mark> is here. Se…", snippet.Highlight(long, terms, 24))
+}
+
+func TestMySQLSearchIndexExcludesAuthors(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`AND c.user_id NOT IN (?, ?) ORDER BY relevance DESC`)).
+		WithArgs("sun", "sun", int64(3), int64(8), 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "relevance"}))
+
+	index := repository.NewMySQLSearchIndex(db)
+
+	_, err = index.Search(context.Background(), model.SearchQuery{
+		Text:             "sun",
+		Type:             model.SearchTypeComment,
+		ExcludeAuthorIDs: []int64{3, 8},
+		Limit:            10,
+	})
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestParseSearchQueryToIsInclusive(t *testing.T) {
+	testCases := []struct {
+		name     string
+		to       string
+		expected time.Time
+	}{
+		{name: "date", to: "2026-10-17", expected: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{name: "timestamp", to: "2026-10-17T12:00:00Z", expected: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/search?q=go&to="+tc.to, nil)
+
+			var query model.SearchQuery
+			assert.NoError(t, util.ParseSearchQuery(r, &query))
+			assert.True(t, tc.expected.Equal(query.To))
+		})
+	}
+}
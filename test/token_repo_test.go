@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/federicodosantos/socialize/internal/repository"
+	customerror "github.com/federicodosantos/socialize/pkg/custom-error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkRefreshTokenUsed(t *testing.T) {
+	type testCase struct {
+		name         string
+		rowsAffected int64
+		expected     bool
+	}
+
+	testCases := []testCase{
+		{
+			name:         "Success - first use",
+			rowsAffected: 1,
+			expected:     true,
+		},
+		{
+			name:         "Already used or revoked",
+			rowsAffected: 0,
+			expected:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := setup()
+			if err != nil {
+				t.Fatalf("error to create sql mock and db due to %s", err.Error())
+			}
+			defer db.Close()
+
+			usedAt := time.Now()
+
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE refresh_tokens SET used_at = ?
+				WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL`)).
+				WithArgs(usedAt, 7).
+				WillReturnResult(sqlmock.NewResult(0, tc.rowsAffected))
+
+			r := repository.NewTokenRepo(db)
+
+			marked, err := r.MarkRefreshTokenUsed(context.Background(), 7, usedAt)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, marked)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetRefreshTokenByHashNotFound(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM refresh_tokens WHERE token_hash = ?`)).
+		WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	r := repository.NewTokenRepo(db)
+
+	token, err := r.GetRefreshTokenByHash(context.Background(), "unknown")
+	assert.ErrorIs(t, err, customerror.ErrInvalidRefreshToken)
+	assert.Nil(t, token)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	now := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM revoked_tokens WHERE expires_at <= ?`)).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	r := repository.NewTokenRepo(db)
+
+	deleted, err := r.DeleteExpiredRevokedTokens(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,32 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/federicodosantos/socialize/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenSigner(t *testing.T) {
+	signer, err := token.NewSigner("0123456789abcdef0123456789abcdef")
+	assert.NoError(t, err)
+
+	plain, hash, err := token.Generate()
+	assert.NoError(t, err)
+	assert.Equal(t, token.Hash(plain), hash)
+
+	signed := signer.Sign("email_verification", plain)
+
+	got, ok := signer.Verify("email_verification", signed)
+	assert.True(t, ok)
+	assert.Equal(t, plain, got)
+
+	_, ok = signer.Verify("password_reset", signed)
+	assert.False(t, ok, "signature is bound to the purpose")
+
+	_, ok = signer.Verify("email_verification", plain+".forged")
+	assert.False(t, ok)
+
+	_, err = token.NewSigner("short")
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package repository_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/federicodosantos/socialize/pkg/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the RFC 6238 SHA1 test key "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	type testCase struct {
+		unix     int64
+		expected string
+	}
+
+	// the RFC vectors use 8 digits, these are their last 6
+	testCases := []testCase{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			code, err := totp.Code(rfcSecret, totp.Step(time.Unix(tc.unix, 0)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, code)
+		})
+	}
+}
+
+func TestTOTPValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	current := totp.Step(now)
+
+	step, ok := totp.Validate(rfcSecret, "081804", now)
+	assert.True(t, ok)
+	assert.Equal(t, current, step)
+
+	// a code from the previous period is still accepted to tolerate drift
+	step, ok = totp.Validate(rfcSecret, "081804", now.Add(totp.Period))
+	assert.True(t, ok)
+	assert.Equal(t, current, step)
+
+	_, ok = totp.Validate(rfcSecret, "081804", now.Add(3*totp.Period))
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(rfcSecret, "000000", now)
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(rfcSecret, "81804", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	uri := totp.URI("Socialize", "johndoe@gmail.com", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Socialize:johndoe@gmail.com?"))
+	assert.Contains(t, uri, "secret="+secret)
+
+	qrCode, err := totp.QRCode(uri)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(qrCode, "data:image/png;base64,"))
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := totp.RecoveryCode()
+	assert.NoError(t, err)
+	assert.Len(t, code, 17)
+	assert.Equal(t, "-", code[8:9])
+
+	assert.Equal(t, strings.ReplaceAll(code, "-", ""), totp.NormalizeRecoveryCode(" "+strings.ToUpper(code)+" "))
+}
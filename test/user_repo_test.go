@@ -3,7 +3,6 @@ package repository_test
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"regexp"
 	"testing"
 	"time"
@@ -12,7 +11,6 @@ import (
 	"github.com/federicodosantos/socialize/internal/model"
 	"github.com/federicodosantos/socialize/internal/repository"
 	customerror "github.com/federicodosantos/socialize/pkg/custom-error"
-	"github.com/federicodosantos/socialize/pkg/util"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 )
@@ -28,6 +26,15 @@ func setup() (*sqlx.DB, sqlmock.Sqlmock, error) {
 	return db, mock, nil
 }
 
+// injectionPayloads are values that used to break or rewrite queries when they
+// were interpolated into the SQL text.
+var injectionPayloads = []string{
+	"O'Brien",
+	"' OR 1=1 --",
+	"'; DROP TABLE users; --",
+	"admin'--",
+}
+
 func createUser() *model.User {
 	now := time.Now()
 
@@ -56,15 +63,16 @@ func TestCreateUser(t *testing.T) {
 		{
 			name: "Success - CreateUser",
 			setupMock: func(mock sqlmock.Sqlmock, user *model.User) {
-				mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`
-								SELECT COUNT(*) FROM users WHERE email = '%s'
-								`, user.Email))).
+				mock.ExpectQuery(regexp.QuoteMeta(`
+								SELECT COUNT(*) FROM users WHERE email = ?
+								`)).
+					WithArgs(user.Email).
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 
-				mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`
+				mock.ExpectExec(regexp.QuoteMeta(`
 								INSERT INTO users(name, email, password, created_at, updated_at)
-  								VALUES ('%s', '%s', '%s', '%s', '%s')`,
-					user.Name, user.Email, user.Password, util.ConvertTimeToString(user.CreatedAt), util.ConvertTimeToString(user.UpdatedAt)))).
+  								VALUES (?, ?, ?, ?, ?)`)).
+					WithArgs(user.Name, user.Email, user.Password, user.CreatedAt, user.UpdatedAt).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 			},
@@ -74,9 +82,10 @@ func TestCreateUser(t *testing.T) {
 		{
 			name: "Error email already exists - CreateUser",
 			setupMock: func(mock sqlmock.Sqlmock, user *model.User) {
-				mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`
-								SELECT COUNT(*) FROM users WHERE email = '%s'
-								`, user.Email))).
+				mock.ExpectQuery(regexp.QuoteMeta(`
+								SELECT COUNT(*) FROM users WHERE email = ?
+								`)).
+					WithArgs(user.Email).
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 			},
 			user:          createUser(),
@@ -128,7 +137,8 @@ func TestGetUserByEmail(t *testing.T) {
 			name:  "Success get user by email",
 			email: user.Email,
 			setupMock: func(mock sqlmock.Sqlmock, email string) {
-				mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf("SELECT * FROM users WHERE email = '%s'", email))).
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM users WHERE email = ?")).
+					WithArgs(email).
 					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"}).
 						AddRow(user.ID, user.Name, user.Email, user.Password, user.CreatedAt, user.UpdatedAt))
 			},
@@ -139,7 +149,8 @@ func TestGetUserByEmail(t *testing.T) {
 			name:  "Error user not found",
 			email: user.Email,
 			setupMock: func(mock sqlmock.Sqlmock, email string) {
-				mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf("SELECT * FROM users WHERE email = '%s'", email))).
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM users WHERE email = ?")).
+					WithArgs(email).
 					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"}))
 			},
 			expectedUser:  nil,
@@ -182,3 +193,116 @@ func TestGetUserByEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestUserRepoInjectionPayloads(t *testing.T) {
+	for _, payload := range injectionPayloads {
+		t.Run(payload, func(t *testing.T) {
+			db, mock, err := setup()
+			if err != nil {
+				t.Fatalf("error to create sql mock and db due to %s", err.Error())
+			}
+			defer db.Close()
+
+			user := createUser()
+			user.ID = 1
+			user.Name = payload
+			user.Email = payload
+			user.Password = payload
+			user.Photo = sql.NullString{String: payload, Valid: true}
+
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE email = ?`)).
+				WithArgs(payload).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users(name, email, password, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?)`)).
+				WithArgs(payload, payload, payload, user.CreatedAt, user.UpdatedAt).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM users WHERE email = ?")).
+				WithArgs(payload).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"}).
+					AddRow(user.ID, user.Name, user.Email, user.Password, user.CreatedAt, user.UpdatedAt))
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE users 
+				SET name = ?, updated_at = ?
+				WHERE id = ?`)).
+				WithArgs(payload, user.UpdatedAt, user.ID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE users 
+				SET photo = ?, updated_at = ?
+				WHERE id = ?`)).
+				WithArgs(payload, user.UpdatedAt, user.ID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET password = ? WHERE id = ?`)).
+				WithArgs(payload, user.ID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET pending_email = ? WHERE id = ?`)).
+				WithArgs(payload, user.ID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			u := repository.NewUserRepo(db)
+			ctx := context.Background()
+
+			assert.NoError(t, u.CreateUser(ctx, user))
+
+			found, err := u.GetUserByEmail(ctx, payload)
+			assert.NoError(t, err)
+			assert.Equal(t, payload, found.Email)
+
+			assert.NoError(t, u.UpdateUserData(ctx, user))
+			assert.NoError(t, u.UpdateUserPhoto(ctx, user))
+
+			assert.NoError(t, u.UpdateUserPassword(ctx, user.ID, payload))
+			assert.NoError(t, u.SetPendingEmail(ctx, user.ID, payload))
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetUserProfile(t *testing.T) {
+	db, mock, err := setup()
+	if err != nil {
+		t.Fatalf("error to create sql mock and db due to %s", err.Error())
+	}
+	defer db.Close()
+
+	joinedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users AS u
+	WHERE u.id = ?`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "photo", "role", "banned_at", "created_at", "post_count", "comment_count", "karma", "follower_count", "following_count"}).
+			AddRow(5, "rico", nil, "user", nil, joinedAt, 3, 8, -2, 4, 1))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE u.id = ?`)).
+		WithArgs(int64(6)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	r := repository.NewUserRepo(db)
+
+	profile, err := r.GetUserProfile(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), profile.PostCount)
+	assert.Equal(t, int64(8), profile.CommentCount)
+	assert.Equal(t, int64(-2), profile.Karma)
+	assert.Equal(t, int64(4), profile.FollowerCount)
+	assert.Equal(t, int64(1), profile.FollowingCount)
+	assert.Equal(t, joinedAt, profile.CreatedAt)
+
+	_, err = r.GetUserProfile(context.Background(), 6)
+	assert.ErrorIs(t, err, customerror.ErrUserNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,60 @@
+package repository_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	coderws "github.com/coder/websocket"
+	"github.com/federicodosantos/socialize/pkg/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketUpgrade(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+
+		ctx := conn.CloseRead(r.Context())
+		conn.WriteText(ctx, []byte("hello"))
+		conn.Close(websocket.CloseGoingAway, strings.Repeat("x", 200))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// another origin is accepted like by the CORS policy
+	conn, _, err := coderws.Dial(ctx, server.URL, &coderws.DialOptions{
+		HTTPHeader: http.Header{"Origin": []string{"https://example.com"}},
+	})
+	require.NoError(t, err)
+	defer conn.CloseNow()
+
+	typ, data, err := conn.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, coderws.MessageText, typ)
+	assert.Equal(t, "hello", string(data))
+
+	// a reason too long for a close frame is cut rather than refused
+	_, _, err = conn.Read(ctx)
+	assert.Equal(t, websocket.CloseGoingAway, coderws.CloseStatus(err))
+}
+
+func TestWebSocketUpgradeRefused(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	assert.False(t, websocket.IsUpgrade(req))
+
+	_, err := websocket.Upgrade(rec, req)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUpgradeRequired, rec.Code)
+}